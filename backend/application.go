@@ -10,15 +10,11 @@ package backend
 import (
 	"errors"
 	"net/http"
-	"path/filepath"
 	"strings"
-	"sync"
-	"time"
 
 	"github.com/Janusec/janusec/data"
 	"github.com/Janusec/janusec/firewall"
 	"github.com/Janusec/janusec/models"
-	"github.com/Janusec/janusec/utils"
 )
 
 var (
@@ -43,51 +39,76 @@ func SelectDestination(app *models.Application) string {
 */
 
 // SelectBackendRoute will replace SelectDestination
-func SelectBackendRoute(app *models.Application, r *http.Request) *models.Destination {
-	routePath := utils.GetRoutePath(r.URL.Path)
-	var dests []*models.Destination
-	hit := false
-	if routePath != "/" {
-		// First check /abc/
-		valueI, ok := app.Route.Load(routePath)
-		if ok {
-			hit = true
-			dests = valueI.([]*models.Destination)
-		}
+func SelectBackendRoute(w http.ResponseWriter, app *models.Application, r *http.Request) *models.Destination {
+	entry, loc := MatchRouteEntry(app, r)
+	if entry == nil {
+		// lack of route /
+		return nil
 	}
-
-	if !hit {
-		// Second check .php
-		ext := filepath.Ext(r.URL.Path)
-		valueI, ok := app.Route.Load(ext)
-		// Third check /
-		if !ok {
-			valueI, ok = app.Route.Load("/")
+	dests := entry.Destinations
+	if split := GetTrafficSplit(app, entry.RequestRoute); split != nil {
+		group := SelectBackendGroup(w, r, split)
+		if groupDests := filterDestinationsByGroup(dests, group); len(groupDests) > 0 {
+			dests = groupDests
 		}
-		if !ok {
-			// lack of route /
-			return nil
-		}
-		dests = valueI.([]*models.Destination)
 	}
-
-	destLen := len(dests)
+	dests = filterHealthyDestinations(dests)
 	var dest *models.Destination
-	if destLen == 1 {
+	if len(dests) == 1 {
 		dest = dests[0]
-	} else if destLen > 1 {
-		ns := time.Now().Nanosecond()
-		destIndex := ns % destLen
-		dest = dests[destIndex]
+	} else {
+		dest = selectStickyDestination(w, r, app, dests)
 	}
 	if dest.RouteType == models.ReverseProxyRoute {
-		if dest.RequestRoute != dest.BackendRoute {
-			r.URL.Path = strings.Replace(r.URL.Path, dest.RequestRoute, dest.BackendRoute, 1)
+		rewriteBackendPath(r, entry, loc, dest)
+	}
+	return dest
+}
+
+// SelectRetryDestination select another healthy destination of the same route and backend group
+// for retrying the request, r is the request with the original path, return nil if not available
+func SelectRetryDestination(app *models.Application, r *http.Request, triedDests []*models.Destination) *models.Destination {
+	entry, loc := MatchRouteEntry(app, r)
+	if entry == nil || len(triedDests) == 0 {
+		return nil
+	}
+	var dests []*models.Destination
+	for _, dest := range entry.Destinations {
+		if dest.RouteType != models.ReverseProxyRoute || dest.Group != triedDests[0].Group || !IsDestinationHealthy(dest) {
+			continue
+		}
+		isTried := false
+		for _, triedDest := range triedDests {
+			if dest.ID == triedDest.ID {
+				isTried = true
+				break
+			}
+		}
+		if !isTried {
+			dests = append(dests, dest)
 		}
 	}
+	if len(dests) == 0 {
+		return nil
+	}
+	dest := selectRandomDestination(dests)
+	rewriteBackendPath(r, entry, loc, dest)
 	return dest
 }
 
+// rewriteBackendPath replace the request route with the backend route of destination
+func rewriteBackendPath(r *http.Request, entry *models.RouteEntry, loc []int, dest *models.Destination) {
+	if entry.MatchMode == models.RouteMatch_Regex {
+		// replace the matched part, capture groups such as $1 are supported
+		if len(dest.BackendRoute) > 0 && dest.RequestRoute != dest.BackendRoute {
+			backendRoute := entry.Regex.ExpandString(nil, dest.BackendRoute, r.URL.Path, loc)
+			r.URL.Path = r.URL.Path[:loc[0]] + string(backendRoute) + r.URL.Path[loc[1]:]
+		}
+	} else if dest.RequestRoute != dest.BackendRoute {
+		r.URL.Path = strings.Replace(r.URL.Path, dest.RequestRoute, dest.BackendRoute, 1)
+	}
+}
+
 func GetApplicationByID(appID int64) (*models.Application, error) {
 	for _, app := range Apps {
 		if app.ID == appID {
@@ -127,18 +148,26 @@ func LoadApps() {
 		dbApps := data.DAL.SelectApplications()
 		for _, dbApp := range dbApps {
 			app := &models.Application{ID: dbApp.ID,
-				Name:           dbApp.Name,
-				InternalScheme: dbApp.InternalScheme,
-				RedirectHTTPS:  dbApp.RedirectHTTPS,
-				HSTSEnabled:    dbApp.HSTSEnabled,
-				WAFEnabled:     dbApp.WAFEnabled,
-				ClientIPMethod: dbApp.ClientIPMethod,
-				Description:    dbApp.Description,
-				Destinations:   []*models.Destination{},
-				Route:          sync.Map{},
-				OAuthRequired:  dbApp.OAuthRequired,
-				SessionSeconds: dbApp.SessionSeconds,
-				Owner:          dbApp.Owner}
+				Name:                dbApp.Name,
+				InternalScheme:      dbApp.InternalScheme,
+				RedirectHTTPS:       dbApp.RedirectHTTPS,
+				HSTSEnabled:         dbApp.HSTSEnabled,
+				WAFEnabled:          dbApp.WAFEnabled,
+				ClientIPMethod:      dbApp.ClientIPMethod,
+				Description:         dbApp.Description,
+				Destinations:        []*models.Destination{},
+				OAuthRequired:       dbApp.OAuthRequired,
+				SessionSeconds:      dbApp.SessionSeconds,
+				Owner:               dbApp.Owner,
+				MaxRequestBodySize:  dbApp.MaxRequestBodySize,
+				MaxResponseBodySize: dbApp.MaxResponseBodySize,
+				OversizeAction:      dbApp.OversizeAction,
+				HSTSMaxAge:          dbApp.HSTSMaxAge,
+				HSTSPreload:         dbApp.HSTSPreload,
+				StickyMode:          dbApp.StickyMode,
+				ConnectTimeout:      dbApp.ConnectTimeout,
+				ResponseTimeout:     dbApp.ResponseTimeout,
+				RetryTimes:          dbApp.RetryTimes}
 			Apps = append(Apps, app)
 		}
 	} else {
@@ -153,29 +182,14 @@ func LoadApps() {
 func LoadDestinations() {
 	for _, app := range Apps {
 		app.Destinations = data.DAL.SelectDestinationsByAppID(app.ID)
-		for _, dest := range app.Destinations {
-			routeI, ok := app.Route.Load(dest.RequestRoute)
-			var route []*models.Destination
-			if ok {
-				route = routeI.([]*models.Destination)
-			}
-			route = append(route, dest)
-			app.Route.Store(dest.RequestRoute, route)
-		}
 	}
+	LoadRoute()
 }
 
+// LoadRoute rebuild the route table of all applications
 func LoadRoute() {
 	for _, app := range Apps {
-		for _, dest := range app.Destinations {
-			routeI, ok := app.Route.Load(dest.RequestRoute)
-			var route []*models.Destination
-			if ok {
-				route = routeI.([]*models.Destination)
-			}
-			route = append(route, dest)
-			app.Route.Store(dest.RequestRoute, route)
-		}
+		BuildRouteTable(app)
 	}
 }
 
@@ -218,57 +232,71 @@ func GetApplications(authUser *models.AuthUser) ([]*models.Application, error) {
 	return myApps, nil
 }
 
-func UpdateDestinations(app *models.Application, destinations []interface{}) {
-	//fmt.Println("ToDo UpdateDestinations")
+// parseDestinations parse and check the destinations, nothing is saved
+func parseDestinations(destinations []interface{}) ([]*models.Destination, error) {
+	var newDestinations []*models.Destination
+	for _, destinationInterface := range destinations {
+		destMap, ok := destinationInterface.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid destination")
+		}
+		dest := &models.Destination{
+			ID:           int64(destMap["id"].(float64)),
+			RouteType:    models.RouteType(destMap["route_type"].(float64)),
+			RequestRoute: strings.TrimSpace(destMap["request_route"].(string)),
+			BackendRoute: strings.TrimSpace(destMap["backend_route"].(string)),
+			Destination:  strings.TrimSpace(destMap["destination"].(string)),
+			NodeID:       int64(destMap["node_id"].(float64))}
+		if value, ok := destMap["match_mode"].(float64); ok {
+			dest.MatchMode = models.RouteMatchMode(value)
+		}
+		if value, ok := destMap["priority"].(float64); ok {
+			dest.Priority = int64(value)
+		}
+		dest.Host, _ = destMap["host"].(string)
+		dest.Methods, _ = destMap["methods"].(string)
+		dest.HeaderName, _ = destMap["header_name"].(string)
+		dest.HeaderValue, _ = destMap["header_value"].(string)
+		dest.Group, _ = destMap["group"].(string)
+		dest.Group = strings.TrimSpace(dest.Group)
+		if err := checkRoute(dest); err != nil {
+			return nil, err
+		}
+		newDestinations = append(newDestinations, dest)
+	}
+	return newDestinations, nil
+}
+
+// UpdateDestinations replace the destinations of application with the parsed ones
+func UpdateDestinations(app *models.Application, newDestinations []*models.Destination) {
 	for _, dest := range app.Destinations {
 		// delete outdated destinations from DB
-		if !InterfaceContainsDestinationID(destinations, dest.ID) {
-			app.Route.Delete(dest.RequestRoute)
+		if !containsDestinationID(newDestinations, dest.ID) {
 			data.DAL.DeleteDestinationByID(dest.ID)
 		}
 	}
-	var newDestinations []*models.Destination
-	for _, destinationInterface := range destinations {
+	for _, dest := range newDestinations {
 		// add new destinations to DB and app
-		destMap := destinationInterface.(map[string]interface{})
-		destID := int64(destMap["id"].(float64))
-		routeType := int64(destMap["route_type"].(float64))
-		requestRoute := strings.TrimSpace(destMap["request_route"].(string))
-		backendRoute := strings.TrimSpace(destMap["backend_route"].(string))
-		destDest := strings.TrimSpace(destMap["destination"].(string))
-		appID := app.ID //int64(destMap["appID"].(float64))
-		nodeID := int64(destMap["node_id"].(float64))
-		if destID == 0 {
-			destID, _ = data.DAL.InsertDestination(routeType, requestRoute, backendRoute, destDest, appID, nodeID)
+		dest.AppID = app.ID
+		if dest.ID == 0 {
+			dest.ID, _ = data.DAL.InsertDestination(dest)
 		} else {
-			data.DAL.UpdateDestinationNode(routeType, requestRoute, backendRoute, destDest, appID, nodeID, destID)
+			data.DAL.UpdateDestinationNode(dest)
 		}
-		dest := &models.Destination{
-			ID:           destID,
-			RouteType:    models.RouteType(routeType),
-			RequestRoute: requestRoute,
-			BackendRoute: backendRoute,
-			Destination:  destDest,
-			AppID:        appID,
-			NodeID:       nodeID}
-		newDestinations = append(newDestinations, dest)
 	}
 	app.Destinations = newDestinations
 
-	// Update Route Map
-	app.Route.Range(func(key, value interface{}) bool {
-		app.Route.Delete(key)
-		return true
-	})
-	for _, dest := range app.Destinations {
-		routeI, ok := app.Route.Load(dest.RequestRoute)
-		var route []*models.Destination
-		if ok {
-			route = routeI.([]*models.Destination)
+	// Update Route Table
+	BuildRouteTable(app)
+}
+
+func containsDestinationID(destinations []*models.Destination, id int64) bool {
+	for _, dest := range destinations {
+		if dest.ID == id {
+			return true
 		}
-		route = append(route, dest)
-		app.Route.Store(dest.RequestRoute, route)
 	}
+	return false
 }
 
 func UpdateAppDomains(app *models.Application, appDomains []interface{}) {
@@ -305,29 +333,88 @@ func UpdateApplication(param map[string]interface{}) (*models.Application, error
 	oauthRequired := application["oauth_required"].(bool)
 	sessionSeconds := int64(application["session_seconds"].(float64))
 	owner := application["owner"].(string)
+	var maxReqBodySize, maxRespBodySize int64
+	if value, ok := application["max_req_body_size"].(float64); ok {
+		maxReqBodySize = int64(value)
+	}
+	if value, ok := application["max_resp_body_size"].(float64); ok {
+		maxRespBodySize = int64(value)
+	}
+	oversizeAction := models.Oversize_Pass
+	if value, ok := application["oversize_action"].(float64); ok {
+		oversizeAction = models.OversizeAction(value)
+	}
+	var hstsMaxAge int64
+	if value, ok := application["hsts_max_age"].(float64); ok {
+		hstsMaxAge = int64(value)
+	}
+	hstsPreload, _ := application["hsts_preload"].(bool)
+	stickyMode := models.Sticky_None
+	if value, ok := application["sticky_mode"].(float64); ok {
+		stickyMode = models.StickyMode(value)
+	}
+	var connectTimeout, responseTimeout int64
+	if value, ok := application["connect_timeout"].(float64); ok {
+		connectTimeout = int64(value)
+	}
+	if value, ok := application["response_timeout"].(float64); ok {
+		responseTimeout = int64(value)
+	}
+	var retryTimes int64 = 1
+	if value, ok := application["retry_times"].(float64); ok {
+		retryTimes = int64(value)
+	}
+	// all sub objects are checked before the application is saved, so an invalid one changes nothing
+	newDestinations, err := parseDestinations(application["destinations"].([]interface{}))
+	if err != nil {
+		return nil, err
+	}
+	headerPolicies, hasHeaderPolicies := application["header_policies"].([]interface{})
+	newHeaderPolicies, err := parseHeaderPolicies(headerPolicies)
+	if err != nil {
+		return nil, err
+	}
+	cookiePolicies, hasCookiePolicies := application["cookie_policies"].([]interface{})
+	newCookiePolicies, err := parseCookiePolicies(cookiePolicies)
+	if err != nil {
+		return nil, err
+	}
+	rewriteRules, hasRewriteRules := application["rewrite_rules"].([]interface{})
+	newRewriteRules, err := parseRewriteRules(rewriteRules)
+	if err != nil {
+		return nil, err
+	}
 	var app *models.Application
 	if appID == 0 {
 		// new application
-		newID := data.DAL.InsertApplication(appName, internalScheme, redirectHttps, hstsEnabled, wafEnabled, ipMethod, description, oauthRequired, sessionSeconds, owner)
+		newID := data.DAL.InsertApplication(appName, internalScheme, redirectHttps, hstsEnabled, wafEnabled, ipMethod, description, oauthRequired, sessionSeconds, owner, maxReqBodySize, maxRespBodySize, oversizeAction, hstsMaxAge, hstsPreload, stickyMode, connectTimeout, responseTimeout, retryTimes)
 		app = &models.Application{
 			ID: newID, Name: appName,
 			InternalScheme: internalScheme,
 			//Destinations:   []*models.Destination{},
-			Route:          sync.Map{},
-			Domains:        []*models.Domain{},
-			RedirectHTTPS:  redirectHttps,
-			HSTSEnabled:    hstsEnabled,
-			WAFEnabled:     wafEnabled,
-			ClientIPMethod: ipMethod,
-			Description:    description,
-			OAuthRequired:  oauthRequired,
-			SessionSeconds: sessionSeconds,
-			Owner:          owner}
+			Domains:             []*models.Domain{},
+			RedirectHTTPS:       redirectHttps,
+			HSTSEnabled:         hstsEnabled,
+			WAFEnabled:          wafEnabled,
+			ClientIPMethod:      ipMethod,
+			Description:         description,
+			OAuthRequired:       oauthRequired,
+			SessionSeconds:      sessionSeconds,
+			Owner:               owner,
+			MaxRequestBodySize:  maxReqBodySize,
+			MaxResponseBodySize: maxRespBodySize,
+			OversizeAction:      oversizeAction,
+			HSTSMaxAge:          hstsMaxAge,
+			HSTSPreload:         hstsPreload,
+			StickyMode:          stickyMode,
+			ConnectTimeout:      connectTimeout,
+			ResponseTimeout:     responseTimeout,
+			RetryTimes:          retryTimes}
 		Apps = append(Apps, app)
 	} else {
 		app, _ = GetApplicationByID(appID)
 		if app != nil {
-			data.DAL.UpdateApplication(appName, internalScheme, redirectHttps, hstsEnabled, wafEnabled, ipMethod, description, oauthRequired, sessionSeconds, owner, appID)
+			data.DAL.UpdateApplication(appName, internalScheme, redirectHttps, hstsEnabled, wafEnabled, ipMethod, description, oauthRequired, sessionSeconds, owner, maxReqBodySize, maxRespBodySize, oversizeAction, hstsMaxAge, hstsPreload, stickyMode, connectTimeout, responseTimeout, retryTimes, appID)
 			app.Name = appName
 			app.InternalScheme = internalScheme
 			app.RedirectHTTPS = redirectHttps
@@ -338,14 +425,31 @@ func UpdateApplication(param map[string]interface{}) (*models.Application, error
 			app.OAuthRequired = oauthRequired
 			app.SessionSeconds = sessionSeconds
 			app.Owner = owner
+			app.MaxRequestBodySize = maxReqBodySize
+			app.MaxResponseBodySize = maxRespBodySize
+			app.OversizeAction = oversizeAction
+			app.HSTSMaxAge = hstsMaxAge
+			app.HSTSPreload = hstsPreload
+			app.StickyMode = stickyMode
+			app.ConnectTimeout = connectTimeout
+			app.ResponseTimeout = responseTimeout
+			app.RetryTimes = retryTimes
 		} else {
 			return nil, errors.New("Application not found.")
 		}
 	}
-	destinations := application["destinations"].([]interface{})
-	UpdateDestinations(app, destinations)
+	UpdateDestinations(app, newDestinations)
 	appDomains := application["domains"].([]interface{})
 	UpdateAppDomains(app, appDomains)
+	if hasHeaderPolicies {
+		UpdateHeaderPolicies(app, newHeaderPolicies)
+	}
+	if hasCookiePolicies {
+		UpdateCookiePolicies(app, newCookiePolicies)
+	}
+	if hasRewriteRules {
+		UpdateRewriteRules(app, newRewriteRules)
+	}
 	data.UpdateBackendLastModified()
 	return app, nil
 }
@@ -370,6 +474,10 @@ func DeleteApplicationByID(appID int64) error {
 	}
 	DeleteDomainsByApp(app)
 	DeleteDestinationsByApp(appID)
+	DeleteHeaderPoliciesByApp(appID)
+	DeleteCookiePoliciesByApp(appID)
+	DeleteRewriteRulesByApp(appID)
+	DeleteTrafficSplitsByApp(appID)
 	firewall.DeleteCCPolicyByAppID(appID)
 	err = data.DAL.DeleteApplication(appID)
 	if err != nil {
@@ -0,0 +1,136 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 00:28:15
+ * @Last Modified: U2, 2026-10-20 00:28:15
+ */
+
+package backend
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+)
+
+// LoadCookiePolicies load cookie policies of all applications
+func LoadCookiePolicies() {
+	for _, app := range Apps {
+		app.CookiePolicies = data.DAL.SelectCookiePoliciesByAppID(app.ID)
+	}
+}
+
+func parseCookiePolicy(cookiePolicyMap map[string]interface{}) (*models.CookiePolicy, error) {
+	cookiePolicy := &models.CookiePolicy{}
+	if id, ok := cookiePolicyMap["id"].(float64); ok {
+		cookiePolicy.ID = int64(id)
+	}
+	name, _ := cookiePolicyMap["name"].(string)
+	cookiePolicy.Name = strings.TrimSpace(name)
+	if len(cookiePolicy.Name) == 0 || strings.ContainsAny(cookiePolicy.Name, " \t\r\n;,=\"") {
+		return nil, errors.New("invalid cookie name: " + name)
+	}
+	cookiePolicy.Secure, _ = cookiePolicyMap["secure"].(bool)
+	cookiePolicy.HttpOnly, _ = cookiePolicyMap["http_only"].(bool)
+	if sameSite, ok := cookiePolicyMap["same_site"].(float64); ok {
+		cookiePolicy.SameSite = models.CookieSameSite(sameSite)
+	}
+	if cookiePolicy.SameSite < models.SameSite_Keep || cookiePolicy.SameSite > models.SameSite_None {
+		return nil, errors.New("same_site should be 0 (keep), 1 (lax), 2 (strict) or 3 (none)")
+	}
+	cookiePolicy.Encrypt, _ = cookiePolicyMap["encrypt"].(bool)
+	if cookiePolicy.Encrypt && cookiePolicy.Name == "*" {
+		return nil, errors.New("cookie encryption requires the cookie name")
+	}
+	cookiePolicy.Description, _ = cookiePolicyMap["description"].(string)
+	return cookiePolicy, nil
+}
+
+// parseCookiePolicies parse and check the cookie policies, nothing is saved
+func parseCookiePolicies(cookiePolicies []interface{}) ([]*models.CookiePolicy, error) {
+	var newCookiePolicies []*models.CookiePolicy
+	for _, cookiePolicyInterface := range cookiePolicies {
+		cookiePolicyMap, ok := cookiePolicyInterface.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid cookie policy")
+		}
+		cookiePolicy, err := parseCookiePolicy(cookiePolicyMap)
+		if err != nil {
+			return nil, err
+		}
+		newCookiePolicies = append(newCookiePolicies, cookiePolicy)
+	}
+	return newCookiePolicies, nil
+}
+
+// UpdateCookiePolicies replace the cookie policies of application with the parsed ones
+func UpdateCookiePolicies(app *models.Application, newCookiePolicies []*models.CookiePolicy) {
+	for _, oldCookiePolicy := range app.CookiePolicies {
+		if !containsCookiePolicyID(newCookiePolicies, oldCookiePolicy.ID) {
+			data.DAL.DeleteCookiePolicyByID(oldCookiePolicy.ID)
+		}
+	}
+	for _, cookiePolicy := range newCookiePolicies {
+		cookiePolicy.AppID = app.ID
+		if cookiePolicy.ID == 0 {
+			cookiePolicy.ID, _ = data.DAL.InsertCookiePolicy(app.ID, cookiePolicy.Name, cookiePolicy.Secure, cookiePolicy.HttpOnly, cookiePolicy.SameSite, cookiePolicy.Encrypt, cookiePolicy.Description)
+		} else {
+			data.DAL.UpdateCookiePolicy(app.ID, cookiePolicy.Name, cookiePolicy.Secure, cookiePolicy.HttpOnly, cookiePolicy.SameSite, cookiePolicy.Encrypt, cookiePolicy.Description, cookiePolicy.ID)
+		}
+	}
+	app.CookiePolicies = newCookiePolicies
+}
+
+func containsCookiePolicyID(cookiePolicies []*models.CookiePolicy, id int64) bool {
+	for _, cookiePolicy := range cookiePolicies {
+		if cookiePolicy.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteCookiePoliciesByApp ...
+func DeleteCookiePoliciesByApp(appID int64) {
+	data.DAL.DeleteCookiePoliciesByAppID(appID)
+}
+
+// GetCookiePolicy return the policy of cookie name, or the policy of * if not found
+func GetCookiePolicy(app *models.Application, name string) *models.CookiePolicy {
+	var defaultPolicy *models.CookiePolicy
+	for _, cookiePolicy := range app.CookiePolicies {
+		if cookiePolicy.Name == name {
+			return cookiePolicy
+		}
+		if cookiePolicy.Name == "*" && defaultPolicy == nil {
+			defaultPolicy = cookiePolicy
+		}
+	}
+	return defaultPolicy
+}
+
+// IsGatewayCookie return true for the cookies issued or read by gateway, such as janusec-sticky,
+// janusec-group-<id> and the override cookie of traffic splits, they are not encrypted by cookie policies
+func IsGatewayCookie(app *models.Application, name string) bool {
+	if strings.HasPrefix(name, "janusec-") {
+		return true
+	}
+	for _, split := range getSplitTable(app) {
+		if len(split.OverrideCookie) > 0 && split.OverrideCookie == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasEncryptedCookie ...
+func HasEncryptedCookie(app *models.Application) bool {
+	for _, cookiePolicy := range app.CookiePolicies {
+		if cookiePolicy.Encrypt {
+			return true
+		}
+	}
+	return false
+}
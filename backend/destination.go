@@ -2,11 +2,26 @@
  * @Copyright Reserved By Janusec (https://www.janusec.com/).
  * @Author: U2
  * @Date: 2018-07-14 16:21:54
- * @Last Modified: U2, 2018-07-14 16:21:54
+ * @Last Modified: U2, 2026-10-20 02:31:08
  */
 
 package backend
 
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
 //"../models"
 
 func InterfaceContainsDestinationID(destinations []interface{}, destID int64) bool {
@@ -19,3 +34,160 @@ func InterfaceContainsDestinationID(destinations []interface{}, destID int64) bo
 	}
 	return false
 }
+
+// BuildRouteTable compile the destinations of application into the route table,
+// the destinations with the same route and conditions are grouped for load balance
+func BuildRouteTable(app *models.Application) {
+	var routeTable []*models.RouteEntry
+	routeEntries := map[string]*models.RouteEntry{}
+	for _, dest := range app.Destinations {
+		key := strings.Join([]string{strconv.FormatInt(int64(dest.MatchMode), 10), strconv.FormatInt(dest.Priority, 10),
+			dest.RequestRoute, strings.ToLower(dest.Host), strings.ToUpper(dest.Methods), http.CanonicalHeaderKey(dest.HeaderName), dest.HeaderValue}, "\n")
+		if entry, ok := routeEntries[key]; ok {
+			entry.Destinations = append(entry.Destinations, dest)
+			continue
+		}
+		entry := &models.RouteEntry{
+			MatchMode:    dest.MatchMode,
+			Priority:     dest.Priority,
+			RequestRoute: dest.RequestRoute,
+			Host:         strings.ToLower(strings.TrimSpace(dest.Host)),
+			HeaderName:   http.CanonicalHeaderKey(strings.TrimSpace(dest.HeaderName)),
+			Destinations: []*models.Destination{dest},
+		}
+		var err error
+		if dest.MatchMode == models.RouteMatch_Regex {
+			if entry.Regex, err = regexp.Compile(dest.RequestRoute); err != nil {
+				utils.CheckError("BuildRouteTable", err)
+				continue
+			}
+		}
+		if len(entry.HeaderName) > 0 && len(dest.HeaderValue) > 0 {
+			if entry.HeaderRegex, err = regexp.Compile(dest.HeaderValue); err != nil {
+				utils.CheckError("BuildRouteTable", err)
+				continue
+			}
+		}
+		for _, method := range strings.Split(dest.Methods, ",") {
+			if method = strings.ToUpper(strings.TrimSpace(method)); len(method) > 0 {
+				entry.Methods = append(entry.Methods, method)
+			}
+		}
+		routeEntries[key] = entry
+		routeTable = append(routeTable, entry)
+	}
+	sort.SliceStable(routeTable, func(i, j int) bool {
+		a, b := routeTable[i], routeTable[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if getRouteConditionCount(a) != getRouteConditionCount(b) {
+			return getRouteConditionCount(a) > getRouteConditionCount(b)
+		}
+		if getRouteKindRank(a) != getRouteKindRank(b) {
+			return getRouteKindRank(a) > getRouteKindRank(b)
+		}
+		// longest prefix first
+		return a.MatchMode == models.RouteMatch_Prefix && len(a.RequestRoute) > len(b.RequestRoute)
+	})
+	app.RouteTable.Store(routeTable)
+}
+
+// checkRoute return error if the match mode or the regular expressions of destination are invalid
+func checkRoute(dest *models.Destination) error {
+	switch dest.MatchMode {
+	case models.RouteMatch_Prefix:
+	case models.RouteMatch_Regex:
+		if _, err := regexp.Compile(dest.RequestRoute); err != nil {
+			return fmt.Errorf("invalid regex route %s: %v", dest.RequestRoute, err)
+		}
+	default:
+		return errors.New("match_mode should be 0 (prefix) or 1 (regex)")
+	}
+	if len(strings.TrimSpace(dest.HeaderName)) > 0 && len(dest.HeaderValue) > 0 {
+		if _, err := regexp.Compile(dest.HeaderValue); err != nil {
+			return fmt.Errorf("invalid regex of header value %s: %v", dest.HeaderValue, err)
+		}
+	}
+	return nil
+}
+
+func getRouteConditionCount(entry *models.RouteEntry) int {
+	count := 0
+	if len(entry.Host) > 0 {
+		count++
+	}
+	if len(entry.Methods) > 0 {
+		count++
+	}
+	if len(entry.HeaderName) > 0 {
+		count++
+	}
+	return count
+}
+
+// getRouteKindRank path prefix such as /api/v2/ first, then regex, file extension and /
+func getRouteKindRank(entry *models.RouteEntry) int {
+	switch {
+	case entry.MatchMode == models.RouteMatch_Regex:
+		return 2
+	case entry.RequestRoute == "/":
+		return 0
+	case strings.HasPrefix(entry.RequestRoute, "."):
+		return 1
+	}
+	return 3
+}
+
+// MatchRouteEntry return the first matched route entry, and the matched location of regex route
+func MatchRouteEntry(app *models.Application, r *http.Request) (*models.RouteEntry, []int) {
+	host := r.Host
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.ToLower(host)
+	routeTable, _ := app.RouteTable.Load().([]*models.RouteEntry)
+	for _, entry := range routeTable {
+		if len(entry.Host) > 0 {
+			if strings.HasPrefix(entry.Host, "*.") {
+				if !strings.HasSuffix(host, entry.Host[1:]) {
+					continue
+				}
+			} else if host != entry.Host {
+				continue
+			}
+		}
+		if len(entry.Methods) > 0 && !utils.Contains(entry.Methods, r.Method) {
+			continue
+		}
+		if len(entry.HeaderName) > 0 {
+			values, ok := r.Header[entry.HeaderName]
+			if !ok || (entry.HeaderRegex != nil && !entry.HeaderRegex.MatchString(strings.Join(values, ","))) {
+				continue
+			}
+		}
+		if entry.MatchMode == models.RouteMatch_Regex {
+			if loc := entry.Regex.FindStringSubmatchIndex(r.URL.Path); loc != nil {
+				return entry, loc
+			}
+			continue
+		}
+		if isPathPrefixMatched(r.URL.Path, entry.RequestRoute) {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// isPathPrefixMatched match / for all, .php for file extension, and /api/v2 for /api/v2 and /api/v2/*
+func isPathPrefixMatched(path string, route string) bool {
+	switch {
+	case route == "/":
+		return true
+	case strings.HasPrefix(route, "."):
+		return filepath.Ext(path) == route
+	case strings.HasSuffix(route, "/"):
+		return strings.HasPrefix(path, route)
+	}
+	return path == route || strings.HasPrefix(path, route+"/")
+}
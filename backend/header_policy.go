@@ -0,0 +1,142 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 23:58:40
+ * @Last Modified: U2, 2026-10-19 23:58:40
+ */
+
+package backend
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+)
+
+var (
+	headerNameRegex = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+	// headerPolicyTemplates are the recommended security response headers
+	headerPolicyTemplates = []*models.HeaderPolicy{
+		{Direction: models.HeaderDirection_Response, Action: models.HeaderAction_Add, Name: "Content-Security-Policy", Value: "default-src 'self'; object-src 'none'; base-uri 'self'; frame-ancestors 'self'", Description: "Restrict the sources of scripts, styles and frames"},
+		{Direction: models.HeaderDirection_Response, Action: models.HeaderAction_Add, Name: "X-Frame-Options", Value: "SAMEORIGIN", Description: "Prevent clickjacking"},
+		{Direction: models.HeaderDirection_Response, Action: models.HeaderAction_Add, Name: "X-Content-Type-Options", Value: "nosniff", Description: "Prevent MIME type sniffing"},
+		{Direction: models.HeaderDirection_Response, Action: models.HeaderAction_Add, Name: "Referrer-Policy", Value: "strict-origin-when-cross-origin", Description: "Limit the referrer sent to other sites"},
+		{Direction: models.HeaderDirection_Response, Action: models.HeaderAction_Add, Name: "Permissions-Policy", Value: "camera=(), microphone=(), geolocation=(), payment=()", Description: "Disable the browser features not used"},
+		{Direction: models.HeaderDirection_Response, Action: models.HeaderAction_Remove, Name: "Server", Description: "Hide the server banner of backend"},
+		{Direction: models.HeaderDirection_Response, Action: models.HeaderAction_Remove, Name: "X-AspNet-Version", Description: "Hide the framework version of backend"},
+	}
+)
+
+// GetHeaderPolicyTemplates return the templates of security response headers
+func GetHeaderPolicyTemplates() ([]*models.HeaderPolicy, error) {
+	return headerPolicyTemplates, nil
+}
+
+// LoadHeaderPolicies load header policies of all applications
+func LoadHeaderPolicies() {
+	for _, app := range Apps {
+		app.HeaderPolicies = data.DAL.SelectHeaderPoliciesByAppID(app.ID)
+	}
+}
+
+func parseHeaderPolicy(headerPolicyMap map[string]interface{}) (*models.HeaderPolicy, error) {
+	headerPolicy := &models.HeaderPolicy{}
+	if id, ok := headerPolicyMap["id"].(float64); ok {
+		headerPolicy.ID = int64(id)
+	}
+	headerPolicy.Direction = models.HeaderDirection_Response
+	if direction, ok := headerPolicyMap["direction"].(float64); ok && direction != 0 {
+		headerPolicy.Direction = models.HeaderDirection(direction)
+	}
+	if headerPolicy.Direction != models.HeaderDirection_Request && headerPolicy.Direction != models.HeaderDirection_Response {
+		return nil, errors.New("header direction should be 1 (request) or 2 (response)")
+	}
+	if action, ok := headerPolicyMap["action"].(float64); ok {
+		headerPolicy.Action = models.HeaderAction(action)
+	}
+	if headerPolicy.Action < models.HeaderAction_Add || headerPolicy.Action > models.HeaderAction_Append {
+		return nil, errors.New("header action should be 1 (add), 2 (override), 3 (remove) or 4 (append)")
+	}
+	name, _ := headerPolicyMap["name"].(string)
+	headerPolicy.Name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+	if !headerNameRegex.MatchString(headerPolicy.Name) {
+		return nil, errors.New("invalid header name: " + name)
+	}
+	if headerPolicy.Action != models.HeaderAction_Remove {
+		headerPolicy.Value, _ = headerPolicyMap["value"].(string)
+		headerPolicy.Value = strings.TrimSpace(headerPolicy.Value)
+		if strings.ContainsAny(headerPolicy.Value, "\r\n") {
+			return nil, errors.New("invalid value of header " + headerPolicy.Name)
+		}
+	}
+	headerPolicy.Description, _ = headerPolicyMap["description"].(string)
+	return headerPolicy, nil
+}
+
+// parseHeaderPolicies parse and check the header policies in order, nothing is saved
+func parseHeaderPolicies(headerPolicies []interface{}) ([]*models.HeaderPolicy, error) {
+	var newHeaderPolicies []*models.HeaderPolicy
+	for _, headerPolicyInterface := range headerPolicies {
+		headerPolicyMap, ok := headerPolicyInterface.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid header policy")
+		}
+		headerPolicy, err := parseHeaderPolicy(headerPolicyMap)
+		if err != nil {
+			return nil, err
+		}
+		newHeaderPolicies = append(newHeaderPolicies, headerPolicy)
+	}
+	return newHeaderPolicies, nil
+}
+
+// UpdateHeaderPolicies replace the header policies of application with the parsed ones
+func UpdateHeaderPolicies(app *models.Application, newHeaderPolicies []*models.HeaderPolicy) {
+	for _, oldHeaderPolicy := range app.HeaderPolicies {
+		if !containsHeaderPolicyID(newHeaderPolicies, oldHeaderPolicy.ID) {
+			data.DAL.DeleteHeaderPolicyByID(oldHeaderPolicy.ID)
+		}
+	}
+	for _, headerPolicy := range newHeaderPolicies {
+		headerPolicy.AppID = app.ID
+		if headerPolicy.ID == 0 {
+			headerPolicy.ID, _ = data.DAL.InsertHeaderPolicy(app.ID, headerPolicy.Direction, headerPolicy.Action, headerPolicy.Name, headerPolicy.Value, headerPolicy.Description)
+		} else {
+			data.DAL.UpdateHeaderPolicy(app.ID, headerPolicy.Direction, headerPolicy.Action, headerPolicy.Name, headerPolicy.Value, headerPolicy.Description, headerPolicy.ID)
+		}
+	}
+	app.HeaderPolicies = newHeaderPolicies
+}
+
+func containsHeaderPolicyID(headerPolicies []*models.HeaderPolicy, id int64) bool {
+	for _, headerPolicy := range headerPolicies {
+		if headerPolicy.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteHeaderPoliciesByApp ...
+func DeleteHeaderPoliciesByApp(appID int64) {
+	data.DAL.DeleteHeaderPoliciesByAppID(appID)
+}
+
+// GetHSTSValue return the value of Strict-Transport-Security header
+func GetHSTSValue(app *models.Application) string {
+	maxAge := app.HSTSMaxAge
+	if maxAge <= 0 {
+		maxAge = 31536000
+	}
+	value := "max-age=" + strconv.FormatInt(maxAge, 10) + "; includeSubDomains"
+	if app.HSTSPreload {
+		value += "; preload"
+	}
+	return value
+}
@@ -0,0 +1,91 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 03:41:15
+ * @Last Modified: U2, 2026-10-20 04:22:37
+ */
+
+package backend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Janusec/janusec/models"
+)
+
+const (
+	// circuitFailureThreshold is the consecutive failures which open the circuit of destination
+	circuitFailureThreshold = 5
+	// circuitOpenSeconds is the time an open destination is skipped, then it is tried again (half-open)
+	circuitOpenSeconds = 30
+)
+
+var (
+	// destCircuits sync.Map, map[destination ID]*destinationCircuit
+	destCircuits sync.Map
+)
+
+// destinationCircuit is the passive outlier detection of a destination,
+// the circuit is closed when openUntil is 0
+type destinationCircuit struct {
+	mutex     sync.Mutex
+	failures  int64
+	openUntil int64
+}
+
+func getDestinationCircuit(dest *models.Destination) *destinationCircuit {
+	circuitI, _ := destCircuits.LoadOrStore(dest.ID, &destinationCircuit{})
+	return circuitI.(*destinationCircuit)
+}
+
+// MarkDestinationFailed record a failure of the destination, such as connection failure, timeout or 5xx gateway errors,
+// the circuit is opened after consecutive failures, or a failure of the trial request in half-open state
+func MarkDestinationFailed(dest *models.Destination) {
+	circuit := getDestinationCircuit(dest)
+	circuit.mutex.Lock()
+	defer circuit.mutex.Unlock()
+	circuit.failures++
+	if circuit.openUntil > 0 || circuit.failures >= circuitFailureThreshold {
+		circuit.openUntil = time.Now().Unix() + circuitOpenSeconds
+	}
+}
+
+// MarkDestinationAvailable close the circuit when the destination responded successfully
+func MarkDestinationAvailable(dest *models.Destination) {
+	circuitI, ok := destCircuits.Load(dest.ID)
+	if !ok {
+		return
+	}
+	circuit := circuitI.(*destinationCircuit)
+	circuit.mutex.Lock()
+	defer circuit.mutex.Unlock()
+	circuit.failures = 0
+	circuit.openUntil = 0
+}
+
+// IsDestinationHealthy return false if the circuit of destination is open
+func IsDestinationHealthy(dest *models.Destination) bool {
+	circuitI, ok := destCircuits.Load(dest.ID)
+	if !ok {
+		return true
+	}
+	circuit := circuitI.(*destinationCircuit)
+	circuit.mutex.Lock()
+	defer circuit.mutex.Unlock()
+	return circuit.openUntil == 0 || time.Now().Unix() >= circuit.openUntil
+}
+
+// filterHealthyDestinations return the healthy destinations, or all of them if none is healthy
+func filterHealthyDestinations(dests []*models.Destination) []*models.Destination {
+	var healthyDests []*models.Destination
+	for _, dest := range dests {
+		if IsDestinationHealthy(dest) {
+			healthyDests = append(healthyDests, dest)
+		}
+	}
+	if len(healthyDests) == 0 {
+		return dests
+	}
+	return healthyDests
+}
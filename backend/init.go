@@ -18,6 +18,10 @@ func InitDatabase() {
 	dal.CreateTableIfNotExistsApplications()
 	dal.CreateTableIfNotExistsDomains()
 	dal.CreateTableIfNotExistsDestinations()
+	dal.CreateTableIfNotExistsHeaderPolicies()
+	dal.CreateTableIfNotExistsCookiePolicies()
+	dal.CreateTableIfNotExistsRewriteRules()
+	dal.CreateTableIfNotExistsTrafficSplits()
 	dal.CreateTableIfNotExistsSettings()
 	dal.CreateTableIfNotExistsAppUsers()
 	dal.InsertIfNotExistsAppUser(`admin`, `1f7d7e9decee9561f457bbc64dd76173ea3e1c6f13f0f55dc1bc4e99e5b8b494`,
@@ -37,6 +41,28 @@ func InitDatabase() {
 		// v0.9.8+ required
 		dal.ExecSQL(`alter table destinations add column route_type bigint default 1, add column request_route varchar(128) default '/', add column backend_route varchar(128) default '/'`)
 	}
+	if dal.ExistColumnInTable("applications", "max_req_body_size") == false {
+		// v0.9.9+ required
+		dal.ExecSQL(`alter table applications add column max_req_body_size bigint default 0, add column max_resp_body_size bigint default 0, add column oversize_action bigint default 1`)
+	}
+	if dal.ExistColumnInTable("applications", "hsts_max_age") == false {
+		dal.ExecSQL(`alter table applications add column hsts_max_age bigint default 0, add column hsts_preload boolean default false`)
+	}
+	if dal.ExistColumnInTable("applications", "sticky_mode") == false {
+		dal.ExecSQL(`alter table applications add column sticky_mode bigint default 0`)
+	}
+	if dal.ExistColumnInTable("applications", "connect_timeout") == false {
+		dal.ExecSQL(`alter table applications add column connect_timeout bigint default 0, add column response_timeout bigint default 0, add column retry_times bigint default 1`)
+	}
+	if dal.ExistColumnInTable("destinations", "match_mode") == false {
+		dal.ExecSQL(`alter table destinations add column match_mode bigint default 0, add column priority bigint default 0, add column host varchar(256) default '', add column methods varchar(128) default '', add column header_name varchar(128) default '', add column header_value varchar(256) default ''`)
+	}
+	if dal.ExistColumnInTable("destinations", "backend_group") == false {
+		dal.ExecSQL(`alter table destinations add column backend_group varchar(64) default ''`)
+	}
+	if dal.ExistColumnInTable("header_policies", "direction") == false {
+		dal.ExecSQL(`alter table header_policies add column direction bigint default 2`)
+	}
 }
 
 func LoadAppConfiguration() {
@@ -44,11 +70,17 @@ func LoadAppConfiguration() {
 	LoadApps()
 	if data.IsMaster {
 		LoadDestinations()
+		LoadHeaderPolicies()
+		LoadCookiePolicies()
+		LoadRewriteRules()
+		LoadTrafficSplits()
 		LoadDomains()
 		LoadAppDomainNames()
 		LoadNodes()
 	} else {
 		LoadRoute()
+		LoadRewriteRules()
+		LoadTrafficSplits()
 		LoadDomains()
 	}
 }
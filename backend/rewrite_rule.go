@@ -0,0 +1,179 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 01:47:53
+ * @Last Modified: U2, 2026-10-20 01:47:53
+ */
+
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// LoadRewriteRules load rewrite rules of all applications on master, and compile them,
+// slave nodes receive the rules with applications
+func LoadRewriteRules() {
+	for _, app := range Apps {
+		if data.IsMaster {
+			app.RewriteRules = data.DAL.SelectRewriteRulesByAppID(app.ID)
+		}
+		for _, rule := range app.RewriteRules {
+			utils.CheckError("LoadRewriteRules", compileRewriteRule(rule))
+		}
+	}
+}
+
+func compileOptionalRegex(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) == 0 {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+func compileRewriteRule(rule *models.RewriteRule) (err error) {
+	if rule.Regex, err = regexp.Compile(rule.Pattern); err != nil {
+		return err
+	}
+	if rule.CondHostRegex, err = compileOptionalRegex(rule.CondHost); err != nil {
+		return err
+	}
+	if rule.CondHeaderRegex, err = compileOptionalRegex(rule.CondHeaderValue); err != nil {
+		return err
+	}
+	rule.CondQueryRegex, err = compileOptionalRegex(rule.CondQuery)
+	return err
+}
+
+func parseRewriteRule(ruleMap map[string]interface{}) (*models.RewriteRule, error) {
+	rule := &models.RewriteRule{}
+	if id, ok := ruleMap["id"].(float64); ok {
+		rule.ID = int64(id)
+	}
+	rule.Pattern, _ = ruleMap["pattern"].(string)
+	rule.Replacement, _ = ruleMap["replacement"].(string)
+	rule.Replacement = strings.TrimSpace(rule.Replacement)
+	if action, ok := ruleMap["action"].(float64); ok {
+		rule.Action = models.RewriteAction(action)
+	}
+	switch rule.Action {
+	case models.Rewrite_Internal:
+		if !strings.HasPrefix(rule.Replacement, "/") {
+			return nil, errors.New("the replacement of internal rewrite should start with /")
+		}
+	case models.Redirect_301, models.Redirect_302, models.Redirect_307, models.Redirect_308:
+		if len(rule.Replacement) == 0 {
+			return nil, errors.New("the replacement of redirect is required")
+		}
+	default:
+		return nil, errors.New("action should be 1 (internal rewrite), 301, 302, 307 or 308 (redirect)")
+	}
+	if strings.ContainsAny(rule.Replacement, "\r\n") {
+		return nil, errors.New("invalid replacement")
+	}
+	rule.CondHost, _ = ruleMap["cond_host"].(string)
+	rule.CondHeaderName, _ = ruleMap["cond_header_name"].(string)
+	rule.CondHeaderName = http.CanonicalHeaderKey(strings.TrimSpace(rule.CondHeaderName))
+	rule.CondHeaderValue, _ = ruleMap["cond_header_value"].(string)
+	rule.CondQuery, _ = ruleMap["cond_query"].(string)
+	rule.IsLast, _ = ruleMap["is_last"].(bool)
+	rule.Description, _ = ruleMap["description"].(string)
+	if err := compileRewriteRule(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// parseRewriteRules parse and check the rewrite rules in order, nothing is saved
+func parseRewriteRules(rewriteRules []interface{}) ([]*models.RewriteRule, error) {
+	var newRewriteRules []*models.RewriteRule
+	for _, ruleInterface := range rewriteRules {
+		ruleMap, ok := ruleInterface.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("invalid rewrite rule")
+		}
+		rule, err := parseRewriteRule(ruleMap)
+		if err != nil {
+			return nil, err
+		}
+		newRewriteRules = append(newRewriteRules, rule)
+	}
+	if err := checkRedirectLoop(newRewriteRules); err != nil {
+		return nil, err
+	}
+	return newRewriteRules, nil
+}
+
+// UpdateRewriteRules replace the rewrite rules of application with the parsed ones
+func UpdateRewriteRules(app *models.Application, newRewriteRules []*models.RewriteRule) {
+	for _, oldRule := range app.RewriteRules {
+		if !containsRewriteRuleID(newRewriteRules, oldRule.ID) {
+			data.DAL.DeleteRewriteRuleByID(oldRule.ID)
+		}
+	}
+	for _, rule := range newRewriteRules {
+		rule.AppID = app.ID
+		if rule.ID == 0 {
+			rule.ID, _ = data.DAL.InsertRewriteRule(rule)
+		} else {
+			data.DAL.UpdateRewriteRule(rule)
+		}
+	}
+	app.RewriteRules = newRewriteRules
+}
+
+// expandReferenceRegex match $1, $name, ${name} and $$ in replacement
+var expandReferenceRegex = regexp.MustCompile(`\$\$|\$\{\w+\}|\$\w+`)
+
+// checkRedirectLoop return error if the target of a redirect rule matches the pattern of a redirect rule,
+// the client would be redirected again and again. Only the target path on the same site is checked,
+// the references in replacement are expanded to x, and the conditions are ignored,
+// because the client keeps the host, headers and query in the next request.
+func checkRedirectLoop(rewriteRules []*models.RewriteRule) error {
+	for i, rule := range rewriteRules {
+		if rule.Action == models.Rewrite_Internal || strings.Contains(rule.Replacement, "://") {
+			continue
+		}
+		targetPath := expandReferenceRegex.ReplaceAllStringFunc(rule.Replacement, func(reference string) string {
+			if reference == "$$" {
+				return "$"
+			}
+			return "x"
+		})
+		if index := strings.Index(targetPath, "?"); index >= 0 {
+			targetPath = targetPath[:index]
+		}
+		for j, otherRule := range rewriteRules {
+			if otherRule.Action == models.Rewrite_Internal || !otherRule.Regex.MatchString(targetPath) {
+				continue
+			}
+			if i == j {
+				return fmt.Errorf("redirect loop: the target %s of rule %s matches its own pattern", rule.Replacement, rule.Pattern)
+			}
+			return fmt.Errorf("redirect loop: the target %s of rule %s matches the pattern of rule %s", rule.Replacement, rule.Pattern, otherRule.Pattern)
+		}
+	}
+	return nil
+}
+
+func containsRewriteRuleID(rewriteRules []*models.RewriteRule, id int64) bool {
+	for _, rule := range rewriteRules {
+		if rule.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// DeleteRewriteRulesByApp ...
+func DeleteRewriteRulesByApp(appID int64) {
+	data.DAL.DeleteRewriteRulesByAppID(appID)
+}
@@ -0,0 +1,83 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 03:48:36
+ * @Last Modified: U2, 2026-10-20 03:48:36
+ */
+
+package backend
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+)
+
+const (
+	// StickyCookieName is the cookie of destination issued by gateway
+	StickyCookieName = "janusec-sticky"
+)
+
+// selectStickyDestination return the pinned destination of client, a new one is pinned
+// if the destination is not available in dests, which are healthy ones
+func selectStickyDestination(w http.ResponseWriter, r *http.Request, app *models.Application, dests []*models.Destination) *models.Destination {
+	switch app.StickyMode {
+	case models.Sticky_Cookie:
+		if cookie, err := r.Cookie(StickyCookieName); err == nil {
+			parts := strings.Split(cookie.Value, ".")
+			if len(parts) == 2 && hmac.Equal([]byte(parts[1]), []byte(signStickyDestination(app.ID, parts[0]))) {
+				for _, dest := range dests {
+					if strconv.FormatInt(dest.ID, 10) == parts[0] {
+						return dest
+					}
+				}
+			}
+		}
+		dest := selectRandomDestination(dests)
+		if w != nil {
+			destID := strconv.FormatInt(dest.ID, 10)
+			http.SetCookie(w, &http.Cookie{Name: StickyCookieName, Value: destID + "." + signStickyDestination(app.ID, destID), Path: "/", HttpOnly: true})
+		}
+		return dest
+	case models.Sticky_IPHash:
+		return selectDestinationByIPHash(r, dests)
+	}
+	return selectRandomDestination(dests)
+}
+
+func selectRandomDestination(dests []*models.Destination) *models.Destination {
+	return dests[rand.Intn(len(dests))]
+}
+
+// selectDestinationByIPHash use rendezvous hashing, only the clients of unhealthy destination are moved
+func selectDestinationByIPHash(r *http.Request, dests []*models.Destination) *models.Destination {
+	clientIP, ok := r.Context().Value("clientIP").(string)
+	if !ok {
+		clientIP, _, _ = net.SplitHostPort(r.RemoteAddr)
+	}
+	var selected *models.Destination
+	var maxScore uint64
+	for _, dest := range dests {
+		sum := sha256.Sum256([]byte(clientIP + "\n" + strconv.FormatInt(dest.ID, 10)))
+		if score := binary.BigEndian.Uint64(sum[:8]); selected == nil || score > maxScore {
+			selected, maxScore = dest, score
+		}
+	}
+	return selected
+}
+
+// signStickyDestination sign the destination of sticky cookie, so that clients can not choose the destination
+func signStickyDestination(appID int64, destID string) string {
+	mac := hmac.New(sha256.New, data.DeriveKey("sticky"))
+	mac.Write([]byte(strconv.FormatInt(appID, 10) + "\n" + destID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,301 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 03:10:27
+ * @Last Modified: U2, 2026-10-20 03:10:27
+ */
+
+package backend
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	// groupCookiePrefix + split ID is the cookie name of sticky backend group
+	groupCookiePrefix = "janusec-group-"
+)
+
+// LoadTrafficSplits load traffic splits of all applications on master, and parse the weights,
+// slave nodes receive the traffic splits with applications
+func LoadTrafficSplits() {
+	for _, app := range Apps {
+		if data.IsMaster {
+			app.TrafficSplits = data.DAL.SelectTrafficSplitsByAppID(app.ID)
+		}
+		for _, split := range app.TrafficSplits {
+			var err error
+			split.GroupWeights, err = parseGroupWeights(split.Weights)
+			utils.CheckError("LoadTrafficSplits", err)
+		}
+		app.SplitTable.Store(app.TrafficSplits)
+	}
+}
+
+// publishTrafficSplits replace the traffic splits of application as a whole, the requests being routed
+// keep using the old slice
+func publishTrafficSplits(app *models.Application, splits []*models.TrafficSplit) {
+	app.TrafficSplits = splits
+	app.SplitTable.Store(splits)
+}
+
+// getSplitTable return the traffic splits published for requests
+func getSplitTable(app *models.Application) []*models.TrafficSplit {
+	splits, _ := app.SplitTable.Load().([]*models.TrafficSplit)
+	return splits
+}
+
+// parseGroupWeights parse the weights such as blue:90,green:10
+func parseGroupWeights(weights string) ([]*models.GroupWeight, error) {
+	var groupWeights []*models.GroupWeight
+	var total int64
+	for _, item := range strings.Split(weights, ",") {
+		if len(strings.TrimSpace(item)) == 0 {
+			continue
+		}
+		kv := strings.SplitN(item, ":", 2)
+		if len(kv) != 2 {
+			return nil, errors.New("invalid weights, should be like blue:90,green:10")
+		}
+		weight, err := strconv.ParseInt(strings.TrimSpace(kv[1]), 10, 64)
+		if err != nil || weight < 0 {
+			return nil, errors.New("invalid weight of group " + kv[0])
+		}
+		group := strings.TrimSpace(kv[0])
+		if !isValidGroupName(group) {
+			return nil, errors.New("invalid group name " + group + ", it is used in cookie value")
+		}
+		groupWeights = append(groupWeights, &models.GroupWeight{Group: group, Weight: weight})
+		total += weight
+	}
+	if total == 0 {
+		return nil, errors.New("the total weight should be greater than 0")
+	}
+	return groupWeights, nil
+}
+
+// isValidGroupName return true if all characters are allowed in cookie value (RFC 6265),
+// no space, double quote, comma, semicolon or backslash
+func isValidGroupName(group string) bool {
+	for i := 0; i < len(group); i++ {
+		c := group[i]
+		if c <= ' ' || c >= 0x7F || c == '"' || c == ',' || c == ';' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// GetTrafficSplits ...
+func GetTrafficSplits(appID int64) ([]*models.TrafficSplit, error) {
+	app, err := GetApplicationByID(appID)
+	if err != nil {
+		return nil, err
+	}
+	return app.TrafficSplits, nil
+}
+
+// GetTrafficSplitByID ...
+func GetTrafficSplitByID(id int64) (*models.TrafficSplit, error) {
+	for _, app := range Apps {
+		for _, split := range app.TrafficSplits {
+			if split.ID == id {
+				return split, nil
+			}
+		}
+	}
+	return nil, errors.New("Not found")
+}
+
+// UpdateTrafficSplit add or update the traffic split, take effect without restart
+func UpdateTrafficSplit(param map[string]interface{}) (*models.TrafficSplit, error) {
+	splitMap, ok := param["object"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("UpdateTrafficSplit parse body null")
+	}
+	curSplit := &models.TrafficSplit{}
+	if id, ok := splitMap["id"].(float64); ok {
+		curSplit.ID = int64(id)
+	}
+	if appID, ok := splitMap["app_id"].(float64); ok {
+		curSplit.AppID = int64(appID)
+	}
+	app, err := GetApplicationByID(curSplit.AppID)
+	if err != nil {
+		return nil, err
+	}
+	curSplit.RequestRoute, _ = splitMap["request_route"].(string)
+	curSplit.RequestRoute = strings.TrimSpace(curSplit.RequestRoute)
+	curSplit.Weights, _ = splitMap["weights"].(string)
+	if curSplit.GroupWeights, err = parseGroupWeights(curSplit.Weights); err != nil {
+		return nil, err
+	}
+	curSplit.Sticky, _ = splitMap["sticky"].(bool)
+	curSplit.OverrideHeader, _ = splitMap["override_header"].(string)
+	curSplit.OverrideHeader = http.CanonicalHeaderKey(strings.TrimSpace(curSplit.OverrideHeader))
+	curSplit.OverrideCookie, _ = splitMap["override_cookie"].(string)
+	curSplit.OverrideCookie = strings.TrimSpace(curSplit.OverrideCookie)
+	curSplit.IsEnabled, _ = splitMap["is_enabled"].(bool)
+	curSplit.UpdateTime = time.Now().Unix()
+	if curSplit.ID == 0 {
+		newID, err := data.DAL.InsertTrafficSplit(curSplit)
+		if err != nil {
+			return nil, err
+		}
+		curSplit.ID = newID
+		newSplits := make([]*models.TrafficSplit, 0, len(app.TrafficSplits)+1)
+		newSplits = append(newSplits, app.TrafficSplits...)
+		publishTrafficSplits(app, append(newSplits, curSplit))
+	} else {
+		split, err := GetTrafficSplitByID(curSplit.ID)
+		if err != nil {
+			return nil, err
+		}
+		if split.AppID != curSplit.AppID {
+			return nil, errors.New("the application of traffic split can not be changed")
+		}
+		if err = data.DAL.UpdateTrafficSplit(curSplit); err != nil {
+			return nil, err
+		}
+		// the published split is read by requests, replace it instead of modifying it
+		newSplits := make([]*models.TrafficSplit, len(app.TrafficSplits))
+		for i, oldSplit := range app.TrafficSplits {
+			newSplits[i] = oldSplit
+			if oldSplit.ID == curSplit.ID {
+				newSplits[i] = curSplit
+			}
+		}
+		publishTrafficSplits(app, newSplits)
+	}
+	data.UpdateBackendLastModified()
+	return curSplit, nil
+}
+
+// DeleteTrafficSplitByID ...
+func DeleteTrafficSplitByID(id int64) error {
+	for _, app := range Apps {
+		for i, split := range app.TrafficSplits {
+			if split.ID == id {
+				data.DAL.DeleteTrafficSplitByID(id)
+				newSplits := make([]*models.TrafficSplit, 0, len(app.TrafficSplits)-1)
+				newSplits = append(newSplits, app.TrafficSplits[:i]...)
+				publishTrafficSplits(app, append(newSplits, app.TrafficSplits[i+1:]...))
+				data.UpdateBackendLastModified()
+				return nil
+			}
+		}
+	}
+	return errors.New("Not found")
+}
+
+// DeleteTrafficSplitsByApp ...
+func DeleteTrafficSplitsByApp(appID int64) {
+	data.DAL.DeleteTrafficSplitsByAppID(appID)
+}
+
+// GetTrafficSplit return the enabled traffic split of route, or the one for all routes
+func GetTrafficSplit(app *models.Application, requestRoute string) *models.TrafficSplit {
+	var defaultSplit *models.TrafficSplit
+	for _, split := range getSplitTable(app) {
+		if !split.IsEnabled || len(split.GroupWeights) == 0 {
+			continue
+		}
+		if split.RequestRoute == requestRoute {
+			return split
+		}
+		if len(split.RequestRoute) == 0 && defaultSplit == nil {
+			defaultSplit = split
+		}
+	}
+	return defaultSplit
+}
+
+// SelectBackendGroup choose the group by tester override, sticky cookie or weights in order
+func SelectBackendGroup(w http.ResponseWriter, r *http.Request, split *models.TrafficSplit) string {
+	if len(split.OverrideHeader) > 0 {
+		if group := r.Header.Get(split.OverrideHeader); isGroupInSplit(split, group, false) {
+			return group
+		}
+	}
+	if len(split.OverrideCookie) > 0 {
+		if cookie, err := r.Cookie(split.OverrideCookie); err == nil && isGroupInSplit(split, cookie.Value, false) {
+			return cookie.Value
+		}
+	}
+	cookieName := groupCookiePrefix + strconv.FormatInt(split.ID, 10)
+	if split.Sticky {
+		if cookie, err := r.Cookie(cookieName); err == nil {
+			// the signature has no dot, but the group name may have
+			index := strings.LastIndex(cookie.Value, ".")
+			// the group with weight 0 is drained, its clients are assigned again
+			if index >= 0 {
+				group, signature := cookie.Value[:index], cookie.Value[index+1:]
+				if hmac.Equal([]byte(signature), []byte(signBackendGroup(split.ID, group))) && isGroupInSplit(split, group, true) {
+					return group
+				}
+			}
+		}
+	}
+	group := chooseGroupByWeight(split.GroupWeights)
+	if split.Sticky && w != nil {
+		http.SetCookie(w, &http.Cookie{Name: cookieName, Value: group + "." + signBackendGroup(split.ID, group), Path: "/", HttpOnly: true})
+	}
+	return group
+}
+
+func isGroupInSplit(split *models.TrafficSplit, group string, requireWeight bool) bool {
+	for _, groupWeight := range split.GroupWeights {
+		if groupWeight.Group == group {
+			return !requireWeight || groupWeight.Weight > 0
+		}
+	}
+	return false
+}
+
+func chooseGroupByWeight(groupWeights []*models.GroupWeight) string {
+	var total int64
+	for _, groupWeight := range groupWeights {
+		total += groupWeight.Weight
+	}
+	if total <= 0 {
+		return ""
+	}
+	n := rand.Int63n(total)
+	for _, groupWeight := range groupWeights {
+		if n < groupWeight.Weight {
+			return groupWeight.Group
+		}
+		n -= groupWeight.Weight
+	}
+	return ""
+}
+
+// signBackendGroup sign the group of sticky cookie, so that clients can not choose the group themselves
+func signBackendGroup(splitID int64, group string) string {
+	mac := hmac.New(sha256.New, data.DeriveKey("group"))
+	mac.Write([]byte(strconv.FormatInt(splitID, 10) + "\n" + group))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// filterDestinationsByGroup return the destinations of group
+func filterDestinationsByGroup(dests []*models.Destination, group string) []*models.Destination {
+	var groupDests []*models.Destination
+	for _, dest := range dests {
+		if dest.Group == group {
+			groupDests = append(groupDests, dest)
+		}
+	}
+	return groupDests
+}
@@ -13,13 +13,13 @@ import (
 )
 
 func (dal *MyDAL) CreateTableIfNotExistsApplications() error {
-	const sqlCreateTableIfNotExistsApplications = `CREATE TABLE IF NOT EXISTS applications(id bigserial PRIMARY KEY,name varchar(128) NOT NULL,internal_scheme varchar(8) NOT NULL,redirect_https boolean,hsts_enabled boolean,waf_enabled boolean,ip_method bigint,description varchar(256),oauth_required boolean,session_seconds bigint default 7200,owner varchar(128))`
+	const sqlCreateTableIfNotExistsApplications = `CREATE TABLE IF NOT EXISTS applications(id bigserial PRIMARY KEY,name varchar(128) NOT NULL,internal_scheme varchar(8) NOT NULL,redirect_https boolean,hsts_enabled boolean,waf_enabled boolean,ip_method bigint,description varchar(256),oauth_required boolean,session_seconds bigint default 7200,owner varchar(128),max_req_body_size bigint default 0,max_resp_body_size bigint default 0,oversize_action bigint default 1,hsts_max_age bigint default 0,hsts_preload boolean default false,sticky_mode bigint default 0,connect_timeout bigint default 0,response_timeout bigint default 0,retry_times bigint default 1)`
 	_, err := dal.db.Exec(sqlCreateTableIfNotExistsApplications)
 	return err
 }
 
 func (dal *MyDAL) SelectApplications() []*models.DBApplication {
-	const sqlSelectApplications = `SELECT id,name,internal_scheme,redirect_https,hsts_enabled,waf_enabled,ip_method,description,oauth_required,session_seconds,owner FROM applications`
+	const sqlSelectApplications = `SELECT id,name,internal_scheme,redirect_https,hsts_enabled,waf_enabled,ip_method,description,oauth_required,session_seconds,owner,max_req_body_size,max_resp_body_size,oversize_action,hsts_max_age,hsts_preload,sticky_mode,connect_timeout,response_timeout,retry_times FROM applications`
 	rows, err := dal.db.Query(sqlSelectApplications)
 	utils.CheckError("SelectApplications", err)
 	defer rows.Close()
@@ -37,24 +37,33 @@ func (dal *MyDAL) SelectApplications() []*models.DBApplication {
 			&dbApp.Description,
 			&dbApp.OAuthRequired,
 			&dbApp.SessionSeconds,
-			&dbApp.Owner)
+			&dbApp.Owner,
+			&dbApp.MaxRequestBodySize,
+			&dbApp.MaxResponseBodySize,
+			&dbApp.OversizeAction,
+			&dbApp.HSTSMaxAge,
+			&dbApp.HSTSPreload,
+			&dbApp.StickyMode,
+			&dbApp.ConnectTimeout,
+			&dbApp.ResponseTimeout,
+			&dbApp.RetryTimes)
 		dbApps = append(dbApps, dbApp)
 	}
 	return dbApps
 }
 
-func (dal *MyDAL) InsertApplication(appName string, internalScheme string, redirectHttps bool, hstsEnabled bool, wafEnabled bool, ipMethod models.IPMethod, description string, oauthRequired bool, sessionSeconds int64, owner string) (newID int64) {
-	const sqlInsertApplication = `INSERT INTO applications(name,internal_scheme,redirect_https,hsts_enabled,waf_enabled,ip_method,description,oauth_required,session_seconds,owner) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id`
-	err := dal.db.QueryRow(sqlInsertApplication, appName, internalScheme, redirectHttps, hstsEnabled, wafEnabled, ipMethod, description, oauthRequired, sessionSeconds, owner).Scan(&newID)
+func (dal *MyDAL) InsertApplication(appName string, internalScheme string, redirectHttps bool, hstsEnabled bool, wafEnabled bool, ipMethod models.IPMethod, description string, oauthRequired bool, sessionSeconds int64, owner string, maxReqBodySize int64, maxRespBodySize int64, oversizeAction models.OversizeAction, hstsMaxAge int64, hstsPreload bool, stickyMode models.StickyMode, connectTimeout int64, responseTimeout int64, retryTimes int64) (newID int64) {
+	const sqlInsertApplication = `INSERT INTO applications(name,internal_scheme,redirect_https,hsts_enabled,waf_enabled,ip_method,description,oauth_required,session_seconds,owner,max_req_body_size,max_resp_body_size,oversize_action,hsts_max_age,hsts_preload,sticky_mode,connect_timeout,response_timeout,retry_times) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19) RETURNING id`
+	err := dal.db.QueryRow(sqlInsertApplication, appName, internalScheme, redirectHttps, hstsEnabled, wafEnabled, ipMethod, description, oauthRequired, sessionSeconds, owner, maxReqBodySize, maxRespBodySize, oversizeAction, hstsMaxAge, hstsPreload, stickyMode, connectTimeout, responseTimeout, retryTimes).Scan(&newID)
 	utils.CheckError("InsertApplication", err)
 	return newID
 }
 
-func (dal *MyDAL) UpdateApplication(appName string, internalScheme string, redirectHttps bool, hstsEnabled bool, wafEnabled bool, ipMethod models.IPMethod, description string, oauthRequired bool, sessionSeconds int64, owner string, appID int64) error {
-	const sqlUpdateApplication = `UPDATE applications SET name=$1,internal_scheme=$2,redirect_https=$3,hsts_enabled=$4,waf_enabled=$5,ip_method=$6,description=$7,oauth_required=$8,session_seconds=$9,owner=$10 WHERE id=$11`
+func (dal *MyDAL) UpdateApplication(appName string, internalScheme string, redirectHttps bool, hstsEnabled bool, wafEnabled bool, ipMethod models.IPMethod, description string, oauthRequired bool, sessionSeconds int64, owner string, maxReqBodySize int64, maxRespBodySize int64, oversizeAction models.OversizeAction, hstsMaxAge int64, hstsPreload bool, stickyMode models.StickyMode, connectTimeout int64, responseTimeout int64, retryTimes int64, appID int64) error {
+	const sqlUpdateApplication = `UPDATE applications SET name=$1,internal_scheme=$2,redirect_https=$3,hsts_enabled=$4,waf_enabled=$5,ip_method=$6,description=$7,oauth_required=$8,session_seconds=$9,owner=$10,max_req_body_size=$11,max_resp_body_size=$12,oversize_action=$13,hsts_max_age=$14,hsts_preload=$15,sticky_mode=$16,connect_timeout=$17,response_timeout=$18,retry_times=$19 WHERE id=$20`
 	stmt, err := dal.db.Prepare(sqlUpdateApplication)
 	defer stmt.Close()
-	_, err = stmt.Exec(appName, internalScheme, redirectHttps, hstsEnabled, wafEnabled, ipMethod, description, oauthRequired, sessionSeconds, owner, appID)
+	_, err = stmt.Exec(appName, internalScheme, redirectHttps, hstsEnabled, wafEnabled, ipMethod, description, oauthRequired, sessionSeconds, owner, maxReqBodySize, maxRespBodySize, oversizeAction, hstsMaxAge, hstsPreload, stickyMode, connectTimeout, responseTimeout, retryTimes, appID)
 	utils.CheckError("UpdateApplication", err)
 	return err
 }
@@ -0,0 +1,65 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 00:21:37
+ * @Last Modified: U2, 2026-10-20 00:21:37
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsCookiePolicies() error {
+	const sqlCreateTableIfNotExistsCookiePolicies = `CREATE TABLE IF NOT EXISTS cookie_policies(id bigserial PRIMARY KEY,app_id bigint NOT NULL,name varchar(128) NOT NULL,secure boolean,http_only boolean,same_site bigint default 0,encrypt boolean,description varchar(256))`
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsCookiePolicies)
+	return err
+}
+
+func (dal *MyDAL) SelectCookiePoliciesByAppID(appID int64) (cookiePolicies []*models.CookiePolicy) {
+	const sqlSelectCookiePoliciesByAppID = `SELECT id,name,secure,http_only,same_site,encrypt,description FROM cookie_policies WHERE app_id=$1 ORDER BY id`
+	rows, err := dal.db.Query(sqlSelectCookiePoliciesByAppID, appID)
+	utils.CheckError("SelectCookiePoliciesByAppID", err)
+	if err != nil {
+		return cookiePolicies
+	}
+	defer rows.Close()
+	for rows.Next() {
+		cookiePolicy := &models.CookiePolicy{AppID: appID}
+		err = rows.Scan(&cookiePolicy.ID, &cookiePolicy.Name, &cookiePolicy.Secure, &cookiePolicy.HttpOnly,
+			&cookiePolicy.SameSite, &cookiePolicy.Encrypt, &cookiePolicy.Description)
+		utils.CheckError("SelectCookiePoliciesByAppID Scan", err)
+		cookiePolicies = append(cookiePolicies, cookiePolicy)
+	}
+	return cookiePolicies
+}
+
+func (dal *MyDAL) InsertCookiePolicy(appID int64, name string, secure bool, httpOnly bool, sameSite models.CookieSameSite, encrypt bool, description string) (newID int64, err error) {
+	const sqlInsertCookiePolicy = `INSERT INTO cookie_policies(app_id,name,secure,http_only,same_site,encrypt,description) VALUES($1,$2,$3,$4,$5,$6,$7) RETURNING id`
+	err = dal.db.QueryRow(sqlInsertCookiePolicy, appID, name, secure, httpOnly, sameSite, encrypt, description).Scan(&newID)
+	utils.CheckError("InsertCookiePolicy", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdateCookiePolicy(appID int64, name string, secure bool, httpOnly bool, sameSite models.CookieSameSite, encrypt bool, description string, id int64) error {
+	const sqlUpdateCookiePolicy = `UPDATE cookie_policies SET app_id=$1,name=$2,secure=$3,http_only=$4,same_site=$5,encrypt=$6,description=$7 WHERE id=$8`
+	_, err := dal.db.Exec(sqlUpdateCookiePolicy, appID, name, secure, httpOnly, sameSite, encrypt, description, id)
+	utils.CheckError("UpdateCookiePolicy", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteCookiePolicyByID(id int64) error {
+	const sqlDeleteCookiePolicyByID = `DELETE FROM cookie_policies WHERE id=$1`
+	_, err := dal.db.Exec(sqlDeleteCookiePolicyByID, id)
+	utils.CheckError("DeleteCookiePolicyByID", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteCookiePoliciesByAppID(appID int64) error {
+	const sqlDeleteCookiePoliciesByAppID = `DELETE FROM cookie_policies WHERE app_id=$1`
+	_, err := dal.db.Exec(sqlDeleteCookiePoliciesByAppID, appID)
+	utils.CheckError("DeleteCookiePoliciesByAppID", err)
+	return err
+}
@@ -12,11 +12,12 @@ import (
 	"github.com/Janusec/janusec/utils"
 )
 
-func (dal *MyDAL) UpdateDestinationNode(routeType int64, requestRoute string, backendRoute string, destination string, appID int64, nodeID int64, id int64) error {
-	const sqlUpdateDestinationNode = `UPDATE destinations SET route_type=$1,request_route=$2,backend_route=$3,destination=$4,app_id=$5,node_id=$6 WHERE id=$7`
+func (dal *MyDAL) UpdateDestinationNode(dest *models.Destination) error {
+	const sqlUpdateDestinationNode = `UPDATE destinations SET route_type=$1,request_route=$2,backend_route=$3,destination=$4,app_id=$5,node_id=$6,match_mode=$7,priority=$8,host=$9,methods=$10,header_name=$11,header_value=$12,backend_group=$13 WHERE id=$14`
 	stmt, err := dal.db.Prepare(sqlUpdateDestinationNode)
 	defer stmt.Close()
-	_, err = stmt.Exec(routeType, requestRoute, backendRoute, destination, appID, nodeID, id)
+	_, err = stmt.Exec(dest.RouteType, dest.RequestRoute, dest.BackendRoute, dest.Destination, dest.AppID, dest.NodeID,
+		dest.MatchMode, dest.Priority, dest.Host, dest.Methods, dest.HeaderName, dest.HeaderValue, dest.Group, dest.ID)
 	utils.CheckError("UpdateDestinationNode", err)
 	return err
 }
@@ -34,13 +35,13 @@ func (dal *MyDAL) ExistsDestinationID(id int64) bool {
 }
 
 func (dal *MyDAL) CreateTableIfNotExistsDestinations() error {
-	const sqlCreateTableIfNotExistsDestinations = `CREATE TABLE IF NOT EXISTS destinations(id bigserial PRIMARY KEY,route_type bigint default 1,request_route varchar(128) default '/',backend_route varchar(128) default '/',destination varchar(128) NOT NULL,app_id bigint NOT NULL,node_id bigint NOT NULL)`
+	const sqlCreateTableIfNotExistsDestinations = `CREATE TABLE IF NOT EXISTS destinations(id bigserial PRIMARY KEY,route_type bigint default 1,request_route varchar(128) default '/',backend_route varchar(128) default '/',destination varchar(128) NOT NULL,app_id bigint NOT NULL,node_id bigint NOT NULL,match_mode bigint default 0,priority bigint default 0,host varchar(256) default '',methods varchar(128) default '',header_name varchar(128) default '',header_value varchar(256) default '',backend_group varchar(64) default '')`
 	_, err := dal.db.Exec(sqlCreateTableIfNotExistsDestinations)
 	return err
 }
 
 func (dal *MyDAL) SelectDestinationsByAppID(app_id int64) (dests []*models.Destination) {
-	const sqlSelectDestinationsByAppID = `SELECT id,route_type,request_route,backend_route,destination,node_id FROM destinations WHERE app_id=$1`
+	const sqlSelectDestinationsByAppID = `SELECT id,route_type,request_route,backend_route,destination,node_id,match_mode,priority,host,methods,header_name,header_value,backend_group FROM destinations WHERE app_id=$1`
 	rows, err := dal.db.Query(sqlSelectDestinationsByAppID, app_id)
 	utils.CheckError("SelectDestinationsByAppID", err)
 	if err != nil {
@@ -49,15 +50,17 @@ func (dal *MyDAL) SelectDestinationsByAppID(app_id int64) (dests []*models.Desti
 	defer rows.Close()
 	for rows.Next() {
 		dest := &models.Destination{AppID: app_id}
-		rows.Scan(&dest.ID, &dest.RouteType, &dest.RequestRoute, &dest.BackendRoute, &dest.Destination, &dest.NodeID)
+		rows.Scan(&dest.ID, &dest.RouteType, &dest.RequestRoute, &dest.BackendRoute, &dest.Destination, &dest.NodeID,
+			&dest.MatchMode, &dest.Priority, &dest.Host, &dest.Methods, &dest.HeaderName, &dest.HeaderValue, &dest.Group)
 		dests = append(dests, dest)
 	}
 	return dests
 }
 
-func (dal *MyDAL) InsertDestination(routeType int64, requestRoute string, backendRoute string, dest string, appID int64, nodeID int64) (newID int64, err error) {
-	const sqlInsertDestination = `INSERT INTO destinations(route_type,request_route,backend_route,destination,app_id,node_id) VALUES($1,$2,$3,$4,$5,$6) RETURNING id`
-	err = dal.db.QueryRow(sqlInsertDestination, routeType, requestRoute, backendRoute, dest, appID, nodeID).Scan(&newID)
+func (dal *MyDAL) InsertDestination(dest *models.Destination) (newID int64, err error) {
+	const sqlInsertDestination = `INSERT INTO destinations(route_type,request_route,backend_route,destination,app_id,node_id,match_mode,priority,host,methods,header_name,header_value,backend_group) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13) RETURNING id`
+	err = dal.db.QueryRow(sqlInsertDestination, dest.RouteType, dest.RequestRoute, dest.BackendRoute, dest.Destination, dest.AppID, dest.NodeID,
+		dest.MatchMode, dest.Priority, dest.Host, dest.Methods, dest.HeaderName, dest.HeaderValue, dest.Group).Scan(&newID)
 	utils.CheckError("InsertDestination", err)
 	return newID, err
 }
@@ -0,0 +1,64 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 23:52:06
+ * @Last Modified: U2, 2026-10-19 23:52:06
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsHeaderPolicies() error {
+	const sqlCreateTableIfNotExistsHeaderPolicies = `CREATE TABLE IF NOT EXISTS header_policies(id bigserial PRIMARY KEY,app_id bigint NOT NULL,direction bigint default 2,action bigint,name varchar(128) NOT NULL,value varchar(2048),description varchar(256))`
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsHeaderPolicies)
+	return err
+}
+
+func (dal *MyDAL) SelectHeaderPoliciesByAppID(appID int64) (headerPolicies []*models.HeaderPolicy) {
+	const sqlSelectHeaderPoliciesByAppID = `SELECT id,direction,action,name,value,description FROM header_policies WHERE app_id=$1 ORDER BY id`
+	rows, err := dal.db.Query(sqlSelectHeaderPoliciesByAppID, appID)
+	utils.CheckError("SelectHeaderPoliciesByAppID", err)
+	if err != nil {
+		return headerPolicies
+	}
+	defer rows.Close()
+	for rows.Next() {
+		headerPolicy := &models.HeaderPolicy{AppID: appID}
+		err = rows.Scan(&headerPolicy.ID, &headerPolicy.Direction, &headerPolicy.Action, &headerPolicy.Name, &headerPolicy.Value, &headerPolicy.Description)
+		utils.CheckError("SelectHeaderPoliciesByAppID Scan", err)
+		headerPolicies = append(headerPolicies, headerPolicy)
+	}
+	return headerPolicies
+}
+
+func (dal *MyDAL) InsertHeaderPolicy(appID int64, direction models.HeaderDirection, action models.HeaderAction, name string, value string, description string) (newID int64, err error) {
+	const sqlInsertHeaderPolicy = `INSERT INTO header_policies(app_id,direction,action,name,value,description) VALUES($1,$2,$3,$4,$5,$6) RETURNING id`
+	err = dal.db.QueryRow(sqlInsertHeaderPolicy, appID, direction, action, name, value, description).Scan(&newID)
+	utils.CheckError("InsertHeaderPolicy", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdateHeaderPolicy(appID int64, direction models.HeaderDirection, action models.HeaderAction, name string, value string, description string, id int64) error {
+	const sqlUpdateHeaderPolicy = `UPDATE header_policies SET app_id=$1,direction=$2,action=$3,name=$4,value=$5,description=$6 WHERE id=$7`
+	_, err := dal.db.Exec(sqlUpdateHeaderPolicy, appID, direction, action, name, value, description, id)
+	utils.CheckError("UpdateHeaderPolicy", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteHeaderPolicyByID(id int64) error {
+	const sqlDeleteHeaderPolicyByID = `DELETE FROM header_policies WHERE id=$1`
+	_, err := dal.db.Exec(sqlDeleteHeaderPolicyByID, id)
+	utils.CheckError("DeleteHeaderPolicyByID", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteHeaderPoliciesByAppID(appID int64) error {
+	const sqlDeleteHeaderPoliciesByAppID = `DELETE FROM header_policies WHERE app_id=$1`
+	_, err := dal.db.Exec(sqlDeleteHeaderPoliciesByAppID, appID)
+	utils.CheckError("DeleteHeaderPoliciesByAppID", err)
+	return err
+}
@@ -0,0 +1,67 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 01:40:26
+ * @Last Modified: U2, 2026-10-20 01:40:26
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsRewriteRules() error {
+	const sqlCreateTableIfNotExistsRewriteRules = `CREATE TABLE IF NOT EXISTS rewrite_rules(id bigserial PRIMARY KEY,app_id bigint NOT NULL,pattern varchar(512) NOT NULL,replacement varchar(1024),action bigint,cond_host varchar(256),cond_header_name varchar(128),cond_header_value varchar(256),cond_query varchar(256),is_last boolean,description varchar(256))`
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsRewriteRules)
+	return err
+}
+
+func (dal *MyDAL) SelectRewriteRulesByAppID(appID int64) (rewriteRules []*models.RewriteRule) {
+	const sqlSelectRewriteRulesByAppID = `SELECT id,pattern,replacement,action,cond_host,cond_header_name,cond_header_value,cond_query,is_last,description FROM rewrite_rules WHERE app_id=$1 ORDER BY id`
+	rows, err := dal.db.Query(sqlSelectRewriteRulesByAppID, appID)
+	utils.CheckError("SelectRewriteRulesByAppID", err)
+	if err != nil {
+		return rewriteRules
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rule := &models.RewriteRule{AppID: appID}
+		err = rows.Scan(&rule.ID, &rule.Pattern, &rule.Replacement, &rule.Action, &rule.CondHost,
+			&rule.CondHeaderName, &rule.CondHeaderValue, &rule.CondQuery, &rule.IsLast, &rule.Description)
+		utils.CheckError("SelectRewriteRulesByAppID Scan", err)
+		rewriteRules = append(rewriteRules, rule)
+	}
+	return rewriteRules
+}
+
+func (dal *MyDAL) InsertRewriteRule(rule *models.RewriteRule) (newID int64, err error) {
+	const sqlInsertRewriteRule = `INSERT INTO rewrite_rules(app_id,pattern,replacement,action,cond_host,cond_header_name,cond_header_value,cond_query,is_last,description) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id`
+	err = dal.db.QueryRow(sqlInsertRewriteRule, rule.AppID, rule.Pattern, rule.Replacement, rule.Action, rule.CondHost,
+		rule.CondHeaderName, rule.CondHeaderValue, rule.CondQuery, rule.IsLast, rule.Description).Scan(&newID)
+	utils.CheckError("InsertRewriteRule", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdateRewriteRule(rule *models.RewriteRule) error {
+	const sqlUpdateRewriteRule = `UPDATE rewrite_rules SET app_id=$1,pattern=$2,replacement=$3,action=$4,cond_host=$5,cond_header_name=$6,cond_header_value=$7,cond_query=$8,is_last=$9,description=$10 WHERE id=$11`
+	_, err := dal.db.Exec(sqlUpdateRewriteRule, rule.AppID, rule.Pattern, rule.Replacement, rule.Action, rule.CondHost,
+		rule.CondHeaderName, rule.CondHeaderValue, rule.CondQuery, rule.IsLast, rule.Description, rule.ID)
+	utils.CheckError("UpdateRewriteRule", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteRewriteRuleByID(id int64) error {
+	const sqlDeleteRewriteRuleByID = `DELETE FROM rewrite_rules WHERE id=$1`
+	_, err := dal.db.Exec(sqlDeleteRewriteRuleByID, id)
+	utils.CheckError("DeleteRewriteRuleByID", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteRewriteRulesByAppID(appID int64) error {
+	const sqlDeleteRewriteRulesByAppID = `DELETE FROM rewrite_rules WHERE app_id=$1`
+	_, err := dal.db.Exec(sqlDeleteRewriteRulesByAppID, appID)
+	utils.CheckError("DeleteRewriteRulesByAppID", err)
+	return err
+}
@@ -0,0 +1,67 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 03:02:44
+ * @Last Modified: U2, 2026-10-20 03:02:44
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsTrafficSplits() error {
+	const sqlCreateTableIfNotExistsTrafficSplits = `CREATE TABLE IF NOT EXISTS traffic_splits(id bigserial PRIMARY KEY,app_id bigint NOT NULL,request_route varchar(128) default '',weights varchar(512),sticky boolean,override_header varchar(128),override_cookie varchar(128),is_enabled boolean,update_time bigint)`
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsTrafficSplits)
+	return err
+}
+
+func (dal *MyDAL) SelectTrafficSplitsByAppID(appID int64) (trafficSplits []*models.TrafficSplit) {
+	const sqlSelectTrafficSplitsByAppID = `SELECT id,request_route,weights,sticky,override_header,override_cookie,is_enabled,update_time FROM traffic_splits WHERE app_id=$1 ORDER BY id`
+	rows, err := dal.db.Query(sqlSelectTrafficSplitsByAppID, appID)
+	utils.CheckError("SelectTrafficSplitsByAppID", err)
+	if err != nil {
+		return trafficSplits
+	}
+	defer rows.Close()
+	for rows.Next() {
+		split := &models.TrafficSplit{AppID: appID}
+		err = rows.Scan(&split.ID, &split.RequestRoute, &split.Weights, &split.Sticky,
+			&split.OverrideHeader, &split.OverrideCookie, &split.IsEnabled, &split.UpdateTime)
+		utils.CheckError("SelectTrafficSplitsByAppID Scan", err)
+		trafficSplits = append(trafficSplits, split)
+	}
+	return trafficSplits
+}
+
+func (dal *MyDAL) InsertTrafficSplit(split *models.TrafficSplit) (newID int64, err error) {
+	const sqlInsertTrafficSplit = `INSERT INTO traffic_splits(app_id,request_route,weights,sticky,override_header,override_cookie,is_enabled,update_time) VALUES($1,$2,$3,$4,$5,$6,$7,$8) RETURNING id`
+	err = dal.db.QueryRow(sqlInsertTrafficSplit, split.AppID, split.RequestRoute, split.Weights, split.Sticky,
+		split.OverrideHeader, split.OverrideCookie, split.IsEnabled, split.UpdateTime).Scan(&newID)
+	utils.CheckError("InsertTrafficSplit", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdateTrafficSplit(split *models.TrafficSplit) error {
+	const sqlUpdateTrafficSplit = `UPDATE traffic_splits SET app_id=$1,request_route=$2,weights=$3,sticky=$4,override_header=$5,override_cookie=$6,is_enabled=$7,update_time=$8 WHERE id=$9`
+	_, err := dal.db.Exec(sqlUpdateTrafficSplit, split.AppID, split.RequestRoute, split.Weights, split.Sticky,
+		split.OverrideHeader, split.OverrideCookie, split.IsEnabled, split.UpdateTime, split.ID)
+	utils.CheckError("UpdateTrafficSplit", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteTrafficSplitByID(id int64) error {
+	const sqlDeleteTrafficSplitByID = `DELETE FROM traffic_splits WHERE id=$1`
+	_, err := dal.db.Exec(sqlDeleteTrafficSplitByID, id)
+	utils.CheckError("DeleteTrafficSplitByID", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteTrafficSplitsByAppID(appID int64) error {
+	const sqlDeleteTrafficSplitsByAppID = `DELETE FROM traffic_splits WHERE app_id=$1`
+	_, err := dal.db.Exec(sqlDeleteTrafficSplitsByAppID, appID)
+	utils.CheckError("DeleteTrafficSplitsByAppID", err)
+	return err
+}
@@ -14,6 +14,7 @@ import (
 	"strings"
 
 	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
 	//"fmt"
 )
 
@@ -24,16 +25,14 @@ func NewConfig(filename string) (*models.Config, error) {
 		return nil, err
 	}
 	json.Unmarshal(configBytes, config)
+	encryptedConfig := models.EncryptedConfig(*config)
+	needSave := false
 	if strings.ToLower(config.NodeRole) == "master" {
 		dbPassword := config.MasterNode.Database.Password
 		if len(dbPassword) <= 32 {
 			// Encrypt password
-			encryptedPasswordBytes := AES256Encrypt([]byte(dbPassword), true)
-			encryptedPassword := hex.EncodeToString(encryptedPasswordBytes)
-			encryptedConfig := models.EncryptedConfig(*config)
-			encryptedConfig.MasterNode.Database.Password = encryptedPassword
-			encryptedConfigBytes, _ := json.MarshalIndent(encryptedConfig, "", "\t")
-			err = ioutil.WriteFile(filename, encryptedConfigBytes, 0644)
+			encryptedConfig.MasterNode.Database.Password = encryptConfigPassword(dbPassword)
+			needSave = true
 		} else {
 			// Decrypt password
 			encryptedPassword, err := hex.DecodeString(dbPassword)
@@ -44,6 +43,44 @@ func NewConfig(filename string) (*models.Config, error) {
 			config.MasterNode.Database.Password = string(passwordBytes)
 		}
 	}
+	// The shared CC store is used by both master and slave nodes
+	ccStorePassword := config.CCStore.Password
+	if len(ccStorePassword) > 0 {
+		if plainPassword, ok := decryptConfigPassword(ccStorePassword); ok {
+			config.CCStore.Password = plainPassword
+		} else {
+			encryptedConfig.CCStore.Password = encryptConfigPassword(ccStorePassword)
+			needSave = true
+		}
+	}
+	if needSave {
+		encryptedConfigBytes, _ := json.MarshalIndent(encryptedConfig, "", "\t")
+		err = ioutil.WriteFile(filename, encryptedConfigBytes, 0644)
+		utils.CheckError("NewConfig WriteFile", err)
+	}
 	//fmt.Println("NewConfig config.Database.Password=",config.Database.Password)
 	return config, nil
 }
+
+// encryptConfigPassword encrypt the password with root key, return the hex string saved in config file
+func encryptConfigPassword(password string) string {
+	encryptedPasswordBytes := AES256Encrypt([]byte(password), true)
+	return hex.EncodeToString(encryptedPasswordBytes)
+}
+
+// decryptConfigPassword return the plain password and true if the password in config file is encrypted,
+// a plain password longer than 32 characters is also recognized because it cannot be decrypted
+func decryptConfigPassword(password string) (string, bool) {
+	if len(password) <= 32 {
+		return password, false
+	}
+	encryptedPassword, err := hex.DecodeString(password)
+	if err != nil || len(encryptedPassword) <= 12 {
+		return password, false
+	}
+	passwordBytes, err := AES256Decrypt(encryptedPassword, true)
+	if err != nil {
+		return password, false
+	}
+	return string(passwordBytes), true
+}
@@ -10,6 +10,7 @@ package data
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
@@ -138,6 +139,18 @@ func SHA256Hash(plaintext string) string {
 	return result
 }
 
+// DeriveKey derive the key of purpose from the key shared by master and slave nodes,
+// so that the data signed by a node can be verified by other nodes
+func DeriveKey(purpose string) []byte {
+	sharedKey := NodesKey
+	if !IsMaster {
+		sharedKey = NodeKey
+	}
+	mac := hmac.New(sha256.New, sharedKey)
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
 func NodeHexKeyToCryptKey(hexKey string) []byte {
 	encrptedKey, err := hex.DecodeString(hexKey)
 	utils.CheckError("NodeHexKeyToCryptKey DecodeString", err)
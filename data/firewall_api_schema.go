@@ -0,0 +1,62 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 16:05:12
+ * @Last Modified: U2, 2026-10-19 16:05:12
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	sqlCreateTableIfNotExistsAPISchemas = `CREATE TABLE IF NOT EXISTS api_schemas(id bigserial primary key,app_id bigint,description varchar(256),content text,action bigint,is_enabled boolean,user_id bigint,update_time bigint)`
+	sqlSelectAPISchemas                 = `SELECT id,app_id,description,content,action,is_enabled,user_id,update_time FROM api_schemas`
+	sqlInsertAPISchema                  = `INSERT INTO api_schemas(app_id,description,content,action,is_enabled,user_id,update_time) VALUES($1,$2,$3,$4,$5,$6,$7) RETURNING id`
+	sqlUpdateAPISchema                  = `UPDATE api_schemas SET app_id=$1,description=$2,content=$3,action=$4,is_enabled=$5,user_id=$6,update_time=$7 WHERE id=$8`
+	sqlDeleteAPISchemaByID              = `DELETE FROM api_schemas WHERE id=$1`
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsAPISchemas() error {
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsAPISchemas)
+	utils.CheckError("CreateTableIfNotExistsAPISchemas", err)
+	return err
+}
+
+func (dal *MyDAL) SelectAPISchemas() (apiSchemas []*models.APISchema) {
+	rows, err := dal.db.Query(sqlSelectAPISchemas)
+	utils.CheckError("SelectAPISchemas", err)
+	if err != nil {
+		return apiSchemas
+	}
+	defer rows.Close()
+	for rows.Next() {
+		apiSchema := new(models.APISchema)
+		err = rows.Scan(&apiSchema.ID, &apiSchema.AppID, &apiSchema.Description, &apiSchema.Content,
+			&apiSchema.Action, &apiSchema.IsEnabled, &apiSchema.UserID, &apiSchema.UpdateTime)
+		utils.CheckError("SelectAPISchemas Scan", err)
+		apiSchemas = append(apiSchemas, apiSchema)
+	}
+	return apiSchemas
+}
+
+func (dal *MyDAL) InsertAPISchema(appID int64, description string, content string, action models.PolicyAction, isEnabled bool, userID int64, updateTime int64) (newID int64, err error) {
+	err = dal.db.QueryRow(sqlInsertAPISchema, appID, description, content, action, isEnabled, userID, updateTime).Scan(&newID)
+	utils.CheckError("InsertAPISchema", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdateAPISchema(appID int64, description string, content string, action models.PolicyAction, isEnabled bool, userID int64, updateTime int64, id int64) error {
+	_, err := dal.db.Exec(sqlUpdateAPISchema, appID, description, content, action, isEnabled, userID, updateTime, id)
+	utils.CheckError("UpdateAPISchema", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteAPISchemaByID(id int64) error {
+	_, err := dal.db.Exec(sqlDeleteAPISchemaByID, id)
+	utils.CheckError("DeleteAPISchemaByID", err)
+	return err
+}
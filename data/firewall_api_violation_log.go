@@ -0,0 +1,83 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 16:11:37
+ * @Last Modified: U2, 2026-10-19 16:11:37
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	sqlCreateTableIfNotExistsAPIViolationLog = `CREATE TABLE IF NOT EXISTS api_violation_logs(id bigserial primary key,request_time bigint,client_ip varchar(256),host varchar(256),method varchar(16),url_path varchar(2048),url_query varchar(2048),content_type varchar(128),user_agent varchar(1024),cookies varchar(1024),raw_request varchar(16384),action bigint,schema_id bigint,violation varchar(1024),app_id bigint)`
+	sqlInsertAPIViolationLog                 = `INSERT INTO api_violation_logs(request_time,client_ip,host,method,url_path,url_query,content_type,user_agent,cookies,raw_request,action,schema_id,violation,app_id) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)`
+	sqlSelectAPIViolationLogByID             = `SELECT id,request_time,client_ip,host,method,url_path,url_query,content_type,user_agent,cookies,raw_request,action,schema_id,violation,app_id FROM api_violation_logs WHERE id=$1`
+	sqlSelectSimpleAPIViolationLogs          = `SELECT id,request_time,client_ip,host,method,url_path,action,schema_id,violation,app_id FROM api_violation_logs WHERE app_id=$1 and request_time between $2 and $3 LIMIT $4 OFFSET $5`
+	sqlSelectAPIViolationLogsCount           = `SELECT COUNT(1) FROM api_violation_logs WHERE app_id=$1 and request_time between $2 and $3`
+	sqlDeleteAPIViolationLogsBeforeTime      = `DELETE FROM api_violation_logs WHERE request_time<$1`
+)
+
+func (dal *MyDAL) DeleteAPIViolationLogsBeforeTime(expiredTime int64) error {
+	_, err := dal.db.Exec(sqlDeleteAPIViolationLogsBeforeTime, expiredTime)
+	utils.CheckError("DeleteAPIViolationLogsBeforeTime", err)
+	return err
+}
+
+func (dal *MyDAL) CreateTableIfNotExistsAPIViolationLog() error {
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsAPIViolationLog)
+	utils.CheckError("CreateTableIfNotExistsAPIViolationLog", err)
+	return err
+}
+
+func (dal *MyDAL) InsertAPIViolationLog(requestTime int64, clientIP string, host string, method string, urlPath string, urlQuery string, contentType string, userAgent string, cookies string, rawRequest string, action int64, schemaID int64, violation string, appID int64) error {
+	_, err := dal.db.Exec(sqlInsertAPIViolationLog, requestTime, clientIP, host, method, urlPath, urlQuery, contentType, userAgent, cookies, rawRequest, action, schemaID, violation, appID)
+	utils.CheckError("InsertAPIViolationLog Exec", err)
+	return err
+}
+
+func (dal *MyDAL) SelectAPIViolationLogsCount(appID int64, startTime int64, endTime int64) (int64, error) {
+	var count int64
+	err := dal.db.QueryRow(sqlSelectAPIViolationLogsCount, appID, startTime, endTime).Scan(&count)
+	utils.CheckError("SelectAPIViolationLogsCount QueryRow", err)
+	return count, err
+}
+
+func (dal *MyDAL) SelectAPIViolationLogByID(id int64) (*models.APIViolationLog, error) {
+	violationLog := new(models.APIViolationLog)
+	err := dal.db.QueryRow(sqlSelectAPIViolationLogByID, id).Scan(&violationLog.ID,
+		&violationLog.RequestTime,
+		&violationLog.ClientIP,
+		&violationLog.Host,
+		&violationLog.Method,
+		&violationLog.UrlPath,
+		&violationLog.UrlQuery,
+		&violationLog.ContentType,
+		&violationLog.UserAgent,
+		&violationLog.Cookies,
+		&violationLog.RawRequest,
+		&violationLog.Action,
+		&violationLog.SchemaID,
+		&violationLog.Violation,
+		&violationLog.AppID)
+	utils.CheckError("SelectAPIViolationLogByID QueryRow", err)
+	return violationLog, err
+}
+
+func (dal *MyDAL) SelectAPIViolationLogs(appID int64, startTime int64, endTime int64, requestCount int64, offset int64) (simpleLogs []*models.SimpleAPIViolationLog) {
+	rows, err := dal.db.Query(sqlSelectSimpleAPIViolationLogs, appID, startTime, endTime, requestCount, offset)
+	utils.CheckError("SelectAPIViolationLogs Query", err)
+	if err != nil {
+		return simpleLogs
+	}
+	defer rows.Close()
+	for rows.Next() {
+		simpleLog := new(models.SimpleAPIViolationLog)
+		rows.Scan(&simpleLog.ID, &simpleLog.RequestTime, &simpleLog.ClientIP, &simpleLog.Host, &simpleLog.Method, &simpleLog.UrlPath, &simpleLog.Action, &simpleLog.SchemaID, &simpleLog.Violation, &simpleLog.AppID)
+		simpleLogs = append(simpleLogs, simpleLog)
+	}
+	return simpleLogs
+}
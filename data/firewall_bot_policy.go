@@ -0,0 +1,62 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 22:18:40
+ * @Last Modified: U2, 2026-10-19 22:18:40
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	sqlCreateTableIfNotExistsBotPolicies = `CREATE TABLE IF NOT EXISTS bot_policies(id bigserial primary key,app_id bigint,category bigint,action bigint,is_enabled boolean,user_id bigint,update_time bigint)`
+	sqlSelectBotPolicies                 = `SELECT id,app_id,category,action,is_enabled,user_id,update_time FROM bot_policies`
+	sqlInsertBotPolicy                   = `INSERT INTO bot_policies(app_id,category,action,is_enabled,user_id,update_time) VALUES($1,$2,$3,$4,$5,$6) RETURNING id`
+	sqlUpdateBotPolicy                   = `UPDATE bot_policies SET app_id=$1,category=$2,action=$3,is_enabled=$4,user_id=$5,update_time=$6 WHERE id=$7`
+	sqlDeleteBotPolicyByID               = `DELETE FROM bot_policies WHERE id=$1`
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsBotPolicies() error {
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsBotPolicies)
+	utils.CheckError("CreateTableIfNotExistsBotPolicies", err)
+	return err
+}
+
+func (dal *MyDAL) SelectBotPolicies() (botPolicies []*models.BotPolicy) {
+	rows, err := dal.db.Query(sqlSelectBotPolicies)
+	utils.CheckError("SelectBotPolicies", err)
+	if err != nil {
+		return botPolicies
+	}
+	defer rows.Close()
+	for rows.Next() {
+		botPolicy := new(models.BotPolicy)
+		err = rows.Scan(&botPolicy.ID, &botPolicy.AppID, &botPolicy.Category,
+			&botPolicy.Action, &botPolicy.IsEnabled, &botPolicy.UserID, &botPolicy.UpdateTime)
+		utils.CheckError("SelectBotPolicies Scan", err)
+		botPolicies = append(botPolicies, botPolicy)
+	}
+	return botPolicies
+}
+
+func (dal *MyDAL) InsertBotPolicy(appID int64, category models.BotCategory, action models.PolicyAction, isEnabled bool, userID int64, updateTime int64) (newID int64, err error) {
+	err = dal.db.QueryRow(sqlInsertBotPolicy, appID, category, action, isEnabled, userID, updateTime).Scan(&newID)
+	utils.CheckError("InsertBotPolicy", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdateBotPolicy(appID int64, category models.BotCategory, action models.PolicyAction, isEnabled bool, userID int64, updateTime int64, id int64) error {
+	_, err := dal.db.Exec(sqlUpdateBotPolicy, appID, category, action, isEnabled, userID, updateTime, id)
+	utils.CheckError("UpdateBotPolicy", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteBotPolicyByID(id int64) error {
+	_, err := dal.db.Exec(sqlDeleteBotPolicyByID, id)
+	utils.CheckError("DeleteBotPolicyByID", err)
+	return err
+}
@@ -15,17 +15,22 @@ import (
 )
 
 const (
-	sqlCreateTableIfNotExistsCCPolicy = `CREATE TABLE IF NOT EXISTS ccpolicies(app_id bigint primary key,interval_seconds bigint,max_count bigint,block_seconds bigint,action bigint,stat_by_url boolean,stat_by_ua boolean,stat_by_cookie boolean,is_enabled boolean)`
+	sqlCreateTableIfNotExistsCCPolicy = `CREATE TABLE IF NOT EXISTS ccpolicies(app_id bigint primary key,interval_seconds bigint,max_count bigint,block_seconds bigint,action bigint,stat_by_url boolean,stat_by_ua boolean,stat_by_cookie boolean,stat_by_tls_fingerprint boolean default false,is_enabled boolean)`
 	sqlExistsCCPolicy                 = `SELECT coalesce((SELECT 1 FROM ccpolicies LIMIT 1),0)`
 	sqlExistsCCPolicyByAppID          = `SELECT coalesce((SELECT 1 FROM ccpolicies WHERE app_id=$1 LIMIT 1),0)`
-	sqlInsertCCPolicy                 = `INSERT INTO ccpolicies(app_id,interval_seconds,max_count,block_seconds,action,stat_by_url,stat_by_ua,stat_by_cookie,is_enabled) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)`
-	sqlSelectCCPolicies               = `SELECT app_id,interval_seconds,max_count,block_seconds,action,stat_by_url,stat_by_ua,stat_by_cookie,is_enabled FROM ccpolicies`
-	sqlUpdateCCPolicy                 = `UPDATE ccpolicies SET interval_seconds=$1,max_count=$2,block_seconds=$3,action=$4,stat_by_url=$5,stat_by_ua=$6,stat_by_cookie=$7,is_enabled=$8 where app_id=$9`
+	sqlInsertCCPolicy                 = `INSERT INTO ccpolicies(app_id,interval_seconds,max_count,block_seconds,action,stat_by_url,stat_by_ua,stat_by_cookie,stat_by_tls_fingerprint,is_enabled) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`
+	sqlSelectCCPolicies               = `SELECT app_id,interval_seconds,max_count,block_seconds,action,stat_by_url,stat_by_ua,stat_by_cookie,stat_by_tls_fingerprint,is_enabled FROM ccpolicies`
+	sqlUpdateCCPolicy                 = `UPDATE ccpolicies SET interval_seconds=$1,max_count=$2,block_seconds=$3,action=$4,stat_by_url=$5,stat_by_ua=$6,stat_by_cookie=$7,stat_by_tls_fingerprint=$8,is_enabled=$9 where app_id=$10`
 	sqlDeleteCCPolicy                 = `DELETE FROM ccpolicies WHERE app_id=$1`
 )
 
 func (dal *MyDAL) CreateTableIfNotExistsCCPolicy() error {
 	_, err := dal.db.Exec(sqlCreateTableIfNotExistsCCPolicy)
+	if dal.ExistColumnInTable("ccpolicies", "stat_by_tls_fingerprint") == false {
+		// Upgrade
+		err = dal.ExecSQL(`alter table ccpolicies add column stat_by_tls_fingerprint boolean default false`)
+		utils.CheckError("CreateTableIfNotExistsCCPolicy alter table", err)
+	}
 	return err
 }
 
@@ -39,11 +44,11 @@ func (dal *MyDAL) DeleteCCPolicy(appID int64) error {
 
 func (dal *MyDAL) UpdateCCPolicy(intervalSeconds time.Duration, maxCount int64,
 	blockSeconds time.Duration, action models.PolicyAction,
-	statByUrl bool, statByUA bool, statByCookie bool, isEnabled bool, appID int64) error {
+	statByUrl bool, statByUA bool, statByCookie bool, statByTLSFingerprint bool, isEnabled bool, appID int64) error {
 	stmt, err := dal.db.Prepare(sqlUpdateCCPolicy)
 	defer stmt.Close()
 	_, err = stmt.Exec(intervalSeconds, maxCount, blockSeconds, action,
-		statByUrl, statByUA, statByCookie, isEnabled, appID)
+		statByUrl, statByUA, statByCookie, statByTLSFingerprint, isEnabled, appID)
 	utils.CheckError("UpdateCCPolicy", err)
 	return err
 }
@@ -71,9 +76,9 @@ func (dal *MyDAL) ExistsCCPolicyByAppID(appID int64) bool {
 }
 
 func (dal *MyDAL) InsertCCPolicy(appID int64, intervalSeconds time.Duration, maxCount int64, blockSeconds time.Duration,
-	action models.PolicyAction, statByUrl bool, statByUA bool, statByCookie bool, isEnabled bool) error {
+	action models.PolicyAction, statByUrl bool, statByUA bool, statByCookie bool, statByTLSFingerprint bool, isEnabled bool) error {
 	_, err := dal.db.Exec(sqlInsertCCPolicy, appID, intervalSeconds, maxCount, blockSeconds,
-		action, statByUrl, statByUA, statByCookie, isEnabled)
+		action, statByUrl, statByUA, statByCookie, statByTLSFingerprint, isEnabled)
 	utils.CheckError("InsertCCPolicy", err)
 	return err
 }
@@ -85,7 +90,7 @@ func (dal *MyDAL) SelectCCPolicies() (ccPolicies []*models.CCPolicy) {
 	for rows.Next() {
 		ccPolicy := new(models.CCPolicy)
 		rows.Scan(&ccPolicy.AppID, &ccPolicy.IntervalSeconds, &ccPolicy.MaxCount, &ccPolicy.BlockSeconds,
-			&ccPolicy.Action, &ccPolicy.StatByURL, &ccPolicy.StatByUserAgent, &ccPolicy.StatByCookie, &ccPolicy.IsEnabled)
+			&ccPolicy.Action, &ccPolicy.StatByURL, &ccPolicy.StatByUserAgent, &ccPolicy.StatByCookie, &ccPolicy.StatByTLSFingerprint, &ccPolicy.IsEnabled)
 		ccPolicies = append(ccPolicies, ccPolicy)
 	}
 	return ccPolicies
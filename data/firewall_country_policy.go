@@ -0,0 +1,89 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 20:31:14
+ * @Last Modified: U2, 2026-10-19 20:31:14
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	sqlCreateTableIfNotExistsCountryPolicies = `CREATE TABLE IF NOT EXISTS country_policies(id bigserial primary key,app_id bigint,list_type bigint,countries varchar(1024),is_enabled boolean,user_id bigint,update_time bigint)`
+	sqlSelectCountryPolicies                 = `SELECT id,app_id,list_type,countries,is_enabled,user_id,update_time FROM country_policies`
+	sqlInsertCountryPolicy                   = `INSERT INTO country_policies(app_id,list_type,countries,is_enabled,user_id,update_time) VALUES($1,$2,$3,$4,$5,$6) RETURNING id`
+	sqlUpdateCountryPolicy                   = `UPDATE country_policies SET app_id=$1,list_type=$2,countries=$3,is_enabled=$4,user_id=$5,update_time=$6 WHERE id=$7`
+	sqlDeleteCountryPolicyByID               = `DELETE FROM country_policies WHERE id=$1`
+	sqlSelectIPStatByAppID                   = `SELECT client_ip,COUNT(client_ip) FROM group_hit_logs WHERE app_id=$1 and request_time between $2 and $3 GROUP BY client_ip`
+	sqlSelectAllIPStat                       = `SELECT client_ip,COUNT(client_ip) FROM group_hit_logs WHERE request_time between $1 and $2 GROUP BY client_ip`
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsCountryPolicies() error {
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsCountryPolicies)
+	utils.CheckError("CreateTableIfNotExistsCountryPolicies", err)
+	return err
+}
+
+func (dal *MyDAL) SelectCountryPolicies() (countryPolicies []*models.CountryPolicy) {
+	rows, err := dal.db.Query(sqlSelectCountryPolicies)
+	utils.CheckError("SelectCountryPolicies", err)
+	if err != nil {
+		return countryPolicies
+	}
+	defer rows.Close()
+	for rows.Next() {
+		countryPolicy := new(models.CountryPolicy)
+		err = rows.Scan(&countryPolicy.ID, &countryPolicy.AppID, &countryPolicy.ListType,
+			&countryPolicy.Countries, &countryPolicy.IsEnabled, &countryPolicy.UserID, &countryPolicy.UpdateTime)
+		utils.CheckError("SelectCountryPolicies Scan", err)
+		countryPolicies = append(countryPolicies, countryPolicy)
+	}
+	return countryPolicies
+}
+
+func (dal *MyDAL) InsertCountryPolicy(appID int64, listType models.IPListType, countries string, isEnabled bool, userID int64, updateTime int64) (newID int64, err error) {
+	err = dal.db.QueryRow(sqlInsertCountryPolicy, appID, listType, countries, isEnabled, userID, updateTime).Scan(&newID)
+	utils.CheckError("InsertCountryPolicy", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdateCountryPolicy(appID int64, listType models.IPListType, countries string, isEnabled bool, userID int64, updateTime int64, id int64) error {
+	_, err := dal.db.Exec(sqlUpdateCountryPolicy, appID, listType, countries, isEnabled, userID, updateTime, id)
+	utils.CheckError("UpdateCountryPolicy", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteCountryPolicyByID(id int64) error {
+	_, err := dal.db.Exec(sqlDeleteCountryPolicyByID, id)
+	utils.CheckError("DeleteCountryPolicyByID", err)
+	return err
+}
+
+// SelectIPStat count group hit logs by client IP, appID 0 for all applications
+func (dal *MyDAL) SelectIPStat(appID int64, startTime int64, endTime int64) (ipStat []*models.IPStat, err error) {
+	var args []interface{}
+	sqlQuery := sqlSelectAllIPStat
+	if appID == 0 {
+		args = []interface{}{startTime, endTime}
+	} else {
+		sqlQuery = sqlSelectIPStatByAppID
+		args = []interface{}{appID, startTime, endTime}
+	}
+	rows, err := dal.db.Query(sqlQuery, args...)
+	utils.CheckError("SelectIPStat", err)
+	if err != nil {
+		return ipStat, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		stat := new(models.IPStat)
+		err = rows.Scan(&stat.ClientIP, &stat.Count)
+		utils.CheckError("SelectIPStat Scan", err)
+		ipStat = append(ipStat, stat)
+	}
+	return ipStat, nil
+}
@@ -13,9 +13,9 @@ import (
 )
 
 const (
-	sqlCreateTableIfNotExistsGroupHitLog  = `CREATE TABLE IF NOT EXISTS group_hit_logs(id bigserial primary key,request_time bigint,client_ip varchar(256),host varchar(256),method varchar(16),url_path varchar(2048),url_query varchar(2048),content_type varchar(128),user_agent varchar(1024),cookies varchar(1024),raw_request varchar(16384),action bigint,policy_id bigint,vuln_id bigint,app_id bigint)`
-	sqlInsertGroupHitLog                  = `INSERT INTO group_hit_logs(request_time,client_ip,host,method,url_path,url_query,content_type,user_agent,cookies,raw_request,action,policy_id,vuln_id,app_id) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)`
-	sqlSelectGroupHitLogByID              = `SELECT id,request_time,client_ip,host,method,url_path,url_query,content_type,user_agent,cookies,raw_request,action,policy_id,vuln_id,app_id FROM group_hit_logs WHERE id=$1`
+	sqlCreateTableIfNotExistsGroupHitLog  = `CREATE TABLE IF NOT EXISTS group_hit_logs(id bigserial primary key,request_time bigint,client_ip varchar(256),host varchar(256),method varchar(16),url_path varchar(2048),url_query varchar(2048),content_type varchar(128),user_agent varchar(1024),cookies varchar(1024),raw_request varchar(16384),action bigint,policy_id bigint,vuln_id bigint,app_id bigint,ja3 varchar(32) default '',ja4 varchar(40) default '')`
+	sqlInsertGroupHitLog                  = `INSERT INTO group_hit_logs(request_time,client_ip,host,method,url_path,url_query,content_type,user_agent,cookies,raw_request,action,policy_id,vuln_id,app_id,ja3,ja4) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)`
+	sqlSelectGroupHitLogByID              = `SELECT id,request_time,client_ip,host,method,url_path,url_query,content_type,user_agent,cookies,raw_request,action,policy_id,vuln_id,app_id,ja3,ja4 FROM group_hit_logs WHERE id=$1`
 	sqlSelectSimpleGroupHitLogs           = `SELECT id,request_time,client_ip,host,method,url_path,action,policy_id,app_id FROM group_hit_logs WHERE app_id=$1 and request_time between $2 and $3 LIMIT $4 OFFSET $5`
 	sqlSelectGroupHitLogsCount            = `SELECT COUNT(1) FROM group_hit_logs WHERE app_id=$1 and request_time between $2 and $3`
 	sqlSelectGroupHitLogsCountByVulnID    = `SELECT COUNT(1) FROM group_hit_logs WHERE app_id=$1 and vuln_id=$2 and request_time between $3 and $4`
@@ -35,10 +35,15 @@ func (dal *MyDAL) DeleteHitLogsBeforeTime(expiredTime int64) error {
 func (dal *MyDAL) CreateTableIfNotExistsGroupHitLog() error {
 	_, err := dal.db.Exec(sqlCreateTableIfNotExistsGroupHitLog)
 	utils.CheckError("CreateTableIfNotExistsGroupHitLog", err)
+	if dal.ExistColumnInTable("group_hit_logs", "ja3") == false {
+		// Upgrade
+		err = dal.ExecSQL(`alter table group_hit_logs add column ja3 varchar(32) default '', add column ja4 varchar(40) default ''`)
+		utils.CheckError("CreateTableIfNotExistsGroupHitLog alter table", err)
+	}
 	return err
 }
 
-func (dal *MyDAL) InsertGroupHitLog(requestTime int64, clientIP string, host string, method string, urlPath string, urlQuery string, contentType string, userAgent string, cookies string, rawRequest string, action int64, policyID int64, vulnID int64, appID int64) error {
+func (dal *MyDAL) InsertGroupHitLog(requestTime int64, clientIP string, host string, method string, urlPath string, urlQuery string, contentType string, userAgent string, cookies string, rawRequest string, action int64, policyID int64, vulnID int64, appID int64, ja3 string, ja4 string) error {
 	/*
 		stmt, err := dal.db.Prepare(sqlInsertGroupHitLog)
 		utils.CheckError("InsertGroupHitLog Prepare", err)
@@ -46,7 +51,7 @@ func (dal *MyDAL) InsertGroupHitLog(requestTime int64, clientIP string, host str
 
 		_, err = stmt.Exec(requestTime, clientIP, host, method, urlPath, urlQuery, contentType, userAgent, cookies, rawRequest, action, policyID, vulnID, appID)
 	*/
-	_, err := dal.db.Exec(sqlInsertGroupHitLog, requestTime, clientIP, host, method, urlPath, urlQuery, contentType, userAgent, cookies, rawRequest, action, policyID, vulnID, appID)
+	_, err := dal.db.Exec(sqlInsertGroupHitLog, requestTime, clientIP, host, method, urlPath, urlQuery, contentType, userAgent, cookies, rawRequest, action, policyID, vulnID, appID, ja3, ja4)
 	utils.CheckError("InsertGroupHitLog Exec", err)
 	return err
 }
@@ -110,7 +115,9 @@ func (dal *MyDAL) SelectGroupHitLogByID(id int64) (*models.GroupHitLog, error) {
 		&group_hit_log.Action,
 		&group_hit_log.PolicyID,
 		&group_hit_log.VulnID,
-		&group_hit_log.AppID)
+		&group_hit_log.AppID,
+		&group_hit_log.JA3,
+		&group_hit_log.JA4)
 	utils.CheckError("SelectGroupHitLogByID QueryRow", err)
 	return group_hit_log, err
 }
@@ -0,0 +1,69 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 19:40:18
+ * @Last Modified: U2, 2026-10-19 19:40:18
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	sqlCreateTableIfNotExistsIPLists = `CREATE TABLE IF NOT EXISTS ip_lists(id bigserial primary key,app_id bigint,ip_net varchar(64),list_type bigint,comment varchar(256),expire_time bigint,user_id bigint,update_time bigint)`
+	sqlSelectIPListEntries           = `SELECT id,app_id,ip_net,list_type,comment,expire_time,user_id,update_time FROM ip_lists`
+	sqlInsertIPListEntry             = `INSERT INTO ip_lists(app_id,ip_net,list_type,comment,expire_time,user_id,update_time) VALUES($1,$2,$3,$4,$5,$6,$7) RETURNING id`
+	sqlUpdateIPListEntry             = `UPDATE ip_lists SET app_id=$1,ip_net=$2,list_type=$3,comment=$4,expire_time=$5,user_id=$6,update_time=$7 WHERE id=$8`
+	sqlDeleteIPListEntryByID         = `DELETE FROM ip_lists WHERE id=$1`
+	sqlDeleteExpiredIPListEntries    = `DELETE FROM ip_lists WHERE expire_time>0 AND expire_time<$1`
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsIPLists() error {
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsIPLists)
+	utils.CheckError("CreateTableIfNotExistsIPLists", err)
+	return err
+}
+
+func (dal *MyDAL) SelectIPListEntries() (entries []*models.IPListEntry) {
+	rows, err := dal.db.Query(sqlSelectIPListEntries)
+	utils.CheckError("SelectIPListEntries", err)
+	if err != nil {
+		return entries
+	}
+	defer rows.Close()
+	for rows.Next() {
+		entry := new(models.IPListEntry)
+		err = rows.Scan(&entry.ID, &entry.AppID, &entry.IPNet, &entry.ListType,
+			&entry.Comment, &entry.ExpireTime, &entry.UserID, &entry.UpdateTime)
+		utils.CheckError("SelectIPListEntries Scan", err)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (dal *MyDAL) InsertIPListEntry(appID int64, ipNet string, listType models.IPListType, comment string, expireTime int64, userID int64, updateTime int64) (newID int64, err error) {
+	err = dal.db.QueryRow(sqlInsertIPListEntry, appID, ipNet, listType, comment, expireTime, userID, updateTime).Scan(&newID)
+	utils.CheckError("InsertIPListEntry", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdateIPListEntry(appID int64, ipNet string, listType models.IPListType, comment string, expireTime int64, userID int64, updateTime int64, id int64) error {
+	_, err := dal.db.Exec(sqlUpdateIPListEntry, appID, ipNet, listType, comment, expireTime, userID, updateTime, id)
+	utils.CheckError("UpdateIPListEntry", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteIPListEntryByID(id int64) error {
+	_, err := dal.db.Exec(sqlDeleteIPListEntryByID, id)
+	utils.CheckError("DeleteIPListEntryByID", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteExpiredIPListEntries(curTime int64) error {
+	_, err := dal.db.Exec(sqlDeleteExpiredIPListEntries, curTime)
+	utils.CheckError("DeleteExpiredIPListEntries", err)
+	return err
+}
@@ -0,0 +1,63 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 23:25:02
+ * @Last Modified: U2, 2026-10-19 23:25:02
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	sqlCreateTableIfNotExistsMaskingLog = `CREATE TABLE IF NOT EXISTS masking_logs(id bigserial primary key,request_time bigint,client_ip varchar(256),host varchar(256),method varchar(16),url_path varchar(2048),masked_count bigint,detail varchar(1024),app_id bigint)`
+	sqlInsertMaskingLog                 = `INSERT INTO masking_logs(request_time,client_ip,host,method,url_path,masked_count,detail,app_id) VALUES($1,$2,$3,$4,$5,$6,$7,$8)`
+	sqlSelectMaskingLogs                = `SELECT id,request_time,client_ip,host,method,url_path,masked_count,detail,app_id FROM masking_logs WHERE app_id=$1 and request_time between $2 and $3 LIMIT $4 OFFSET $5`
+	sqlSelectMaskingLogsCount           = `SELECT COUNT(1) FROM masking_logs WHERE app_id=$1 and request_time between $2 and $3`
+	sqlDeleteMaskingLogsBeforeTime      = `DELETE FROM masking_logs WHERE request_time<$1`
+)
+
+func (dal *MyDAL) DeleteMaskingLogsBeforeTime(expiredTime int64) error {
+	_, err := dal.db.Exec(sqlDeleteMaskingLogsBeforeTime, expiredTime)
+	utils.CheckError("DeleteMaskingLogsBeforeTime", err)
+	return err
+}
+
+func (dal *MyDAL) CreateTableIfNotExistsMaskingLog() error {
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsMaskingLog)
+	utils.CheckError("CreateTableIfNotExistsMaskingLog", err)
+	return err
+}
+
+func (dal *MyDAL) InsertMaskingLog(requestTime int64, clientIP string, host string, method string, urlPath string, maskedCount int64, detail string, appID int64) error {
+	_, err := dal.db.Exec(sqlInsertMaskingLog, requestTime, clientIP, host, method, urlPath, maskedCount, detail, appID)
+	utils.CheckError("InsertMaskingLog Exec", err)
+	return err
+}
+
+func (dal *MyDAL) SelectMaskingLogsCount(appID int64, startTime int64, endTime int64) (int64, error) {
+	var count int64
+	err := dal.db.QueryRow(sqlSelectMaskingLogsCount, appID, startTime, endTime).Scan(&count)
+	utils.CheckError("SelectMaskingLogsCount QueryRow", err)
+	return count, err
+}
+
+func (dal *MyDAL) SelectMaskingLogs(appID int64, startTime int64, endTime int64, requestCount int64, offset int64) (maskingLogs []*models.MaskingLog) {
+	rows, err := dal.db.Query(sqlSelectMaskingLogs, appID, startTime, endTime, requestCount, offset)
+	utils.CheckError("SelectMaskingLogs Query", err)
+	if err != nil {
+		return maskingLogs
+	}
+	defer rows.Close()
+	for rows.Next() {
+		maskingLog := new(models.MaskingLog)
+		err = rows.Scan(&maskingLog.ID, &maskingLog.RequestTime, &maskingLog.ClientIP, &maskingLog.Host,
+			&maskingLog.Method, &maskingLog.UrlPath, &maskingLog.MaskedCount, &maskingLog.Detail, &maskingLog.AppID)
+		utils.CheckError("SelectMaskingLogs Scan", err)
+		maskingLogs = append(maskingLogs, maskingLog)
+	}
+	return maskingLogs
+}
@@ -0,0 +1,67 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 23:21:36
+ * @Last Modified: U2, 2026-10-19 23:21:36
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	sqlCreateTableIfNotExistsMaskingRules = `CREATE TABLE IF NOT EXISTS masking_rules(id bigserial primary key,app_id bigint,url_path varchar(256),mask_type bigint,pattern varchar(1024),keep_prefix bigint,keep_suffix bigint,mask_char varchar(8),replacement varchar(256),is_enabled boolean,user_id bigint,update_time bigint)`
+	sqlSelectMaskingRules                 = `SELECT id,app_id,url_path,mask_type,pattern,keep_prefix,keep_suffix,mask_char,replacement,is_enabled,user_id,update_time FROM masking_rules ORDER BY id`
+	sqlInsertMaskingRule                  = `INSERT INTO masking_rules(app_id,url_path,mask_type,pattern,keep_prefix,keep_suffix,mask_char,replacement,is_enabled,user_id,update_time) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) RETURNING id`
+	sqlUpdateMaskingRule                  = `UPDATE masking_rules SET app_id=$1,url_path=$2,mask_type=$3,pattern=$4,keep_prefix=$5,keep_suffix=$6,mask_char=$7,replacement=$8,is_enabled=$9,user_id=$10,update_time=$11 WHERE id=$12`
+	sqlDeleteMaskingRuleByID              = `DELETE FROM masking_rules WHERE id=$1`
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsMaskingRules() error {
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsMaskingRules)
+	utils.CheckError("CreateTableIfNotExistsMaskingRules", err)
+	return err
+}
+
+func (dal *MyDAL) SelectMaskingRules() (maskingRules []*models.MaskingRule) {
+	rows, err := dal.db.Query(sqlSelectMaskingRules)
+	utils.CheckError("SelectMaskingRules", err)
+	if err != nil {
+		return maskingRules
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rule := new(models.MaskingRule)
+		err = rows.Scan(&rule.ID, &rule.AppID, &rule.URLPath, &rule.MaskType, &rule.Pattern,
+			&rule.KeepPrefix, &rule.KeepSuffix, &rule.MaskChar, &rule.Replacement,
+			&rule.IsEnabled, &rule.UserID, &rule.UpdateTime)
+		utils.CheckError("SelectMaskingRules Scan", err)
+		maskingRules = append(maskingRules, rule)
+	}
+	return maskingRules
+}
+
+func (dal *MyDAL) InsertMaskingRule(rule *models.MaskingRule) (newID int64, err error) {
+	err = dal.db.QueryRow(sqlInsertMaskingRule, rule.AppID, rule.URLPath, rule.MaskType, rule.Pattern,
+		rule.KeepPrefix, rule.KeepSuffix, rule.MaskChar, rule.Replacement,
+		rule.IsEnabled, rule.UserID, rule.UpdateTime).Scan(&newID)
+	utils.CheckError("InsertMaskingRule", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdateMaskingRule(rule *models.MaskingRule) error {
+	_, err := dal.db.Exec(sqlUpdateMaskingRule, rule.AppID, rule.URLPath, rule.MaskType, rule.Pattern,
+		rule.KeepPrefix, rule.KeepSuffix, rule.MaskChar, rule.Replacement,
+		rule.IsEnabled, rule.UserID, rule.UpdateTime, rule.ID)
+	utils.CheckError("UpdateMaskingRule", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteMaskingRuleByID(id int64) error {
+	_, err := dal.db.Exec(sqlDeleteMaskingRuleByID, id)
+	utils.CheckError("DeleteMaskingRuleByID", err)
+	return err
+}
@@ -0,0 +1,64 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 11:20:36
+ * @Last Modified: U2, 2026-10-19 11:20:36
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	sqlCreateTableIfNotExistsPolicyExceptions = `CREATE TABLE IF NOT EXISTS policy_exceptions(id bigserial primary key,description varchar(256),app_id bigint,policy_id bigint,vuln_id bigint,url_path varchar(512),param_name varchar(256),username varchar(128),is_enabled boolean,user_id bigint,update_time bigint)`
+	sqlSelectPolicyExceptions                 = `SELECT id,description,app_id,policy_id,vuln_id,url_path,param_name,username,is_enabled,user_id,update_time FROM policy_exceptions`
+	sqlInsertPolicyException                  = `INSERT INTO policy_exceptions(description,app_id,policy_id,vuln_id,url_path,param_name,username,is_enabled,user_id,update_time) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING id`
+	sqlUpdatePolicyException                  = `UPDATE policy_exceptions SET description=$1,app_id=$2,policy_id=$3,vuln_id=$4,url_path=$5,param_name=$6,username=$7,is_enabled=$8,user_id=$9,update_time=$10 WHERE id=$11`
+	sqlDeletePolicyExceptionByID              = `DELETE FROM policy_exceptions WHERE id=$1`
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsPolicyExceptions() error {
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsPolicyExceptions)
+	utils.CheckError("CreateTableIfNotExistsPolicyExceptions", err)
+	return err
+}
+
+func (dal *MyDAL) SelectPolicyExceptions() (policyExceptions []*models.PolicyException) {
+	rows, err := dal.db.Query(sqlSelectPolicyExceptions)
+	utils.CheckError("SelectPolicyExceptions", err)
+	if err != nil {
+		return policyExceptions
+	}
+	defer rows.Close()
+	for rows.Next() {
+		policyException := new(models.PolicyException)
+		err = rows.Scan(&policyException.ID, &policyException.Description, &policyException.AppID,
+			&policyException.PolicyID, &policyException.VulnID, &policyException.URLPath,
+			&policyException.ParamName, &policyException.Username, &policyException.IsEnabled,
+			&policyException.UserID, &policyException.UpdateTime)
+		utils.CheckError("SelectPolicyExceptions Scan", err)
+		policyExceptions = append(policyExceptions, policyException)
+	}
+	return policyExceptions
+}
+
+func (dal *MyDAL) InsertPolicyException(description string, appID int64, policyID int64, vulnID int64, urlPath string, paramName string, username string, isEnabled bool, userID int64, updateTime int64) (newID int64, err error) {
+	err = dal.db.QueryRow(sqlInsertPolicyException, description, appID, policyID, vulnID, urlPath, paramName, username, isEnabled, userID, updateTime).Scan(&newID)
+	utils.CheckError("InsertPolicyException", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdatePolicyException(description string, appID int64, policyID int64, vulnID int64, urlPath string, paramName string, username string, isEnabled bool, userID int64, updateTime int64, id int64) error {
+	_, err := dal.db.Exec(sqlUpdatePolicyException, description, appID, policyID, vulnID, urlPath, paramName, username, isEnabled, userID, updateTime, id)
+	utils.CheckError("UpdatePolicyException", err)
+	return err
+}
+
+func (dal *MyDAL) DeletePolicyExceptionByID(id int64) error {
+	_, err := dal.db.Exec(sqlDeletePolicyExceptionByID, id)
+	utils.CheckError("DeletePolicyExceptionByID", err)
+	return err
+}
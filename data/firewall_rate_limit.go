@@ -0,0 +1,70 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 19:02:47
+ * @Last Modified: U2, 2026-10-19 19:02:47
+ */
+
+package data
+
+import (
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	sqlCreateTableIfNotExistsRateLimitRules = `CREATE TABLE IF NOT EXISTS rate_limit_rules(id bigserial primary key,description varchar(256),app_id bigint,url_path varchar(512),key_type bigint,key_name varchar(128),cidr_prefix bigint,algorithm bigint,rate_limit bigint,period_seconds bigint,burst bigint,action bigint,is_enabled boolean,user_id bigint,update_time bigint)`
+	sqlSelectRateLimitRules                 = `SELECT id,description,app_id,url_path,key_type,key_name,cidr_prefix,algorithm,rate_limit,period_seconds,burst,action,is_enabled,user_id,update_time FROM rate_limit_rules ORDER BY id`
+	sqlInsertRateLimitRule                  = `INSERT INTO rate_limit_rules(description,app_id,url_path,key_type,key_name,cidr_prefix,algorithm,rate_limit,period_seconds,burst,action,is_enabled,user_id,update_time) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14) RETURNING id`
+	sqlUpdateRateLimitRule                  = `UPDATE rate_limit_rules SET description=$1,app_id=$2,url_path=$3,key_type=$4,key_name=$5,cidr_prefix=$6,algorithm=$7,rate_limit=$8,period_seconds=$9,burst=$10,action=$11,is_enabled=$12,user_id=$13,update_time=$14 WHERE id=$15`
+	sqlDeleteRateLimitRuleByID              = `DELETE FROM rate_limit_rules WHERE id=$1`
+)
+
+func (dal *MyDAL) CreateTableIfNotExistsRateLimitRules() error {
+	_, err := dal.db.Exec(sqlCreateTableIfNotExistsRateLimitRules)
+	utils.CheckError("CreateTableIfNotExistsRateLimitRules", err)
+	return err
+}
+
+func (dal *MyDAL) SelectRateLimitRules() (rules []*models.RateLimitRule) {
+	rows, err := dal.db.Query(sqlSelectRateLimitRules)
+	utils.CheckError("SelectRateLimitRules", err)
+	if err != nil {
+		return rules
+	}
+	defer rows.Close()
+	for rows.Next() {
+		rule := new(models.RateLimitRule)
+		err = rows.Scan(&rule.ID, &rule.Description, &rule.AppID, &rule.URLPath,
+			&rule.KeyType, &rule.KeyName, &rule.CIDRPrefix, &rule.Algorithm,
+			&rule.Limit, &rule.PeriodSeconds, &rule.Burst, &rule.Action,
+			&rule.IsEnabled, &rule.UserID, &rule.UpdateTime)
+		utils.CheckError("SelectRateLimitRules Scan", err)
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func (dal *MyDAL) InsertRateLimitRule(rule *models.RateLimitRule) (newID int64, err error) {
+	err = dal.db.QueryRow(sqlInsertRateLimitRule, rule.Description, rule.AppID, rule.URLPath,
+		rule.KeyType, rule.KeyName, rule.CIDRPrefix, rule.Algorithm,
+		rule.Limit, rule.PeriodSeconds, rule.Burst, rule.Action,
+		rule.IsEnabled, rule.UserID, rule.UpdateTime).Scan(&newID)
+	utils.CheckError("InsertRateLimitRule", err)
+	return newID, err
+}
+
+func (dal *MyDAL) UpdateRateLimitRule(rule *models.RateLimitRule) error {
+	_, err := dal.db.Exec(sqlUpdateRateLimitRule, rule.Description, rule.AppID, rule.URLPath,
+		rule.KeyType, rule.KeyName, rule.CIDRPrefix, rule.Algorithm,
+		rule.Limit, rule.PeriodSeconds, rule.Burst, rule.Action,
+		rule.IsEnabled, rule.UserID, rule.UpdateTime, rule.ID)
+	utils.CheckError("UpdateRateLimitRule", err)
+	return err
+}
+
+func (dal *MyDAL) DeleteRateLimitRuleByID(id int64) error {
+	_, err := dal.db.Exec(sqlDeleteRateLimitRuleByID, id)
+	utils.CheckError("DeleteRateLimitRuleByID", err)
+	return err
+}
@@ -8,6 +8,7 @@
 package data
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/Janusec/janusec/models"
@@ -18,6 +19,21 @@ var (
 	Backend_Last_Modified  int64         = 0 // seconds since 1970.01.01
 	Firewall_Last_Modified int64         = 0
 	Sync_Seconds           time.Duration = (300 * time.Second)
+	// Challenge_PoW_Difficulty is the leading zero bits of proof-of-work challenge
+	Challenge_PoW_Difficulty int64 = 18
+	// Challenge_Pass_Seconds is the validity of pass token after solving a challenge
+	Challenge_Pass_Seconds int64 = 1800
+)
+
+const (
+	// MinChallengePoWDifficulty below it the proof-of-work challenge is solved instantly
+	MinChallengePoWDifficulty int64 = 8
+	// MaxChallengePoWDifficulty above it the browser may take minutes to solve the challenge
+	MaxChallengePoWDifficulty int64 = 26
+	// MinChallengePassSeconds avoid solving challenges again and again
+	MinChallengePassSeconds int64 = 60
+	// MaxChallengePassSeconds limit the validity of pass token to 7 days
+	MaxChallengePassSeconds int64 = 7 * 86400
 )
 
 func UpdateBackendLastModified() {
@@ -42,3 +58,47 @@ func GetSettingByName(name string) *models.Setting {
 	}
 	return nil
 }
+
+// CheckChallengePoWDifficulty return error if the difficulty is out of range
+func CheckChallengePoWDifficulty(difficulty int64) error {
+	if difficulty < MinChallengePoWDifficulty || difficulty > MaxChallengePoWDifficulty {
+		return fmt.Errorf("Challenge_PoW_Difficulty should be between %d and %d", MinChallengePoWDifficulty, MaxChallengePoWDifficulty)
+	}
+	return nil
+}
+
+// CheckChallengePassSeconds return error if the validity of pass token is out of range
+func CheckChallengePassSeconds(seconds int64) error {
+	if seconds < MinChallengePassSeconds || seconds > MaxChallengePassSeconds {
+		return fmt.Errorf("Challenge_Pass_Seconds should be between %d and %d", MinChallengePassSeconds, MaxChallengePassSeconds)
+	}
+	return nil
+}
+
+// UpdateChallengePoWDifficulty save the difficulty, slave nodes get it in the next sync
+func UpdateChallengePoWDifficulty(difficulty int64) error {
+	if err := CheckChallengePoWDifficulty(difficulty); err != nil {
+		return err
+	}
+	if err := DAL.SaveIntSetting("Challenge_PoW_Difficulty", difficulty); err != nil {
+		return err
+	}
+	Challenge_PoW_Difficulty = difficulty
+	setting := GetSettingByName("Challenge_PoW_Difficulty")
+	setting.Value = difficulty
+	return nil
+}
+
+// UpdateChallengePassSeconds save the validity of pass token, slave nodes get it in the next sync
+func UpdateChallengePassSeconds(seconds int64) error {
+	if err := CheckChallengePassSeconds(seconds); err != nil {
+		return err
+	}
+	if err := DAL.SaveIntSetting("Challenge_Pass_Seconds", seconds); err != nil {
+		return err
+	}
+	Challenge_Pass_Seconds = seconds
+	setting := GetSettingByName("Challenge_Pass_Seconds")
+	setting.Value = seconds
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 16:58:20
+ * @Last Modified: U2, 2026-10-19 16:58:20
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+var (
+	apiSchemas []*models.APISchema
+	// openAPIDocuments map[int64]*OpenAPIDocument, compiled content of API schemas
+	openAPIDocuments sync.Map
+)
+
+// InitAPISchema ...
+func InitAPISchema() {
+	if data.IsMaster {
+		data.DAL.CreateTableIfNotExistsAPISchemas()
+		data.DAL.CreateTableIfNotExistsAPIViolationLog()
+		apiSchemas = data.DAL.SelectAPISchemas()
+	} else {
+		apiSchemas = RPCSelectAPISchemas()
+	}
+	for _, apiSchema := range apiSchemas {
+		compileAPISchema(apiSchema)
+	}
+}
+
+func compileAPISchema(apiSchema *models.APISchema) error {
+	doc, err := ParseOpenAPI(apiSchema.Content)
+	utils.CheckError("compileAPISchema", err)
+	if err != nil {
+		openAPIDocuments.Delete(apiSchema.ID)
+		return err
+	}
+	openAPIDocuments.Store(apiSchema.ID, doc)
+	return nil
+}
+
+// GetAPISchemas ...
+func GetAPISchemas() ([]*models.APISchema, error) {
+	return apiSchemas, nil
+}
+
+// GetAPISchemaByID ...
+func GetAPISchemaByID(id int64) (*models.APISchema, error) {
+	for _, apiSchema := range apiSchemas {
+		if apiSchema.ID == id {
+			return apiSchema, nil
+		}
+	}
+	return nil, errors.New("Not found")
+}
+
+// UpdateAPISchema ...
+func UpdateAPISchema(r *http.Request, userID int64) (*models.APISchema, error) {
+	var setAPISchemaRequest models.RPCSetAPISchema
+	err := json.NewDecoder(r.Body).Decode(&setAPISchemaRequest)
+	defer r.Body.Close()
+	utils.CheckError("UpdateAPISchema Decode", err)
+	curSchema := setAPISchemaRequest.Object
+	if curSchema == nil {
+		return nil, errors.New("UpdateAPISchema parse body null")
+	}
+	if curSchema.AppID == 0 {
+		return nil, errors.New("app_id is required")
+	}
+	if _, err := ParseOpenAPI(curSchema.Content); err != nil {
+		return nil, err
+	}
+	curSchema.UserID = userID
+	curSchema.UpdateTime = time.Now().Unix()
+	if curSchema.ID == 0 {
+		newID, err := data.DAL.InsertAPISchema(curSchema.AppID, curSchema.Description, curSchema.Content, curSchema.Action, curSchema.IsEnabled, curSchema.UserID, curSchema.UpdateTime)
+		if err != nil {
+			return nil, err
+		}
+		curSchema.ID = newID
+		apiSchemas = append(apiSchemas, curSchema)
+	} else {
+		apiSchema, err := GetAPISchemaByID(curSchema.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = data.DAL.UpdateAPISchema(curSchema.AppID, curSchema.Description, curSchema.Content, curSchema.Action, curSchema.IsEnabled, curSchema.UserID, curSchema.UpdateTime, curSchema.ID)
+		if err != nil {
+			return nil, err
+		}
+		*apiSchema = *curSchema
+		curSchema = apiSchema
+	}
+	compileAPISchema(curSchema)
+	data.UpdateFirewallLastModified()
+	return curSchema, nil
+}
+
+// DeleteAPISchemaByID ...
+func DeleteAPISchemaByID(id int64) error {
+	for i, apiSchema := range apiSchemas {
+		if apiSchema.ID == id {
+			data.DAL.DeleteAPISchemaByID(id)
+			apiSchemas = append(apiSchemas[:i], apiSchemas[i+1:]...)
+			openAPIDocuments.Delete(id)
+			data.UpdateFirewallLastModified()
+			return nil
+		}
+	}
+	return errors.New("Not found")
+}
+
+// HasAPISchema return true if the application has an enabled API schema
+func HasAPISchema(appID int64) bool {
+	for _, apiSchema := range apiSchemas {
+		if apiSchema.IsEnabled && apiSchema.AppID == appID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRequestViolateAPISchema validate the request against the enabled API schema of application,
+// the JSON body is validated only if it is buffered
+func IsRequestViolateAPISchema(r *http.Request, appID int64) (bool, *models.APISchema, string) {
+	for _, apiSchema := range apiSchemas {
+		if !apiSchema.IsEnabled || apiSchema.AppID != appID {
+			continue
+		}
+		docI, ok := openAPIDocuments.Load(apiSchema.ID)
+		if !ok {
+			continue
+		}
+		violation := docI.(*OpenAPIDocument).ValidateRequest(r, GetBufferedBody(r))
+		if len(violation) > 0 {
+			return true, apiSchema, violation
+		}
+	}
+	return false, nil, ""
+}
@@ -0,0 +1,137 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 13:02:47
+ * @Last Modified: U2, 2026-10-19 13:02:47
+ */
+
+package firewall
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Janusec/janusec/models"
+)
+
+const (
+	// DefaultMaxRequestBodySize is used when application MaxRequestBodySize is 0
+	DefaultMaxRequestBodySize int64 = 16 << 20
+	// DefaultMaxResponseBodySize is used when application MaxResponseBodySize is 0
+	DefaultMaxResponseBodySize int64 = 4 << 20
+)
+
+var (
+	// ErrBodyTooLarge means the body exceeds the max inspected size
+	ErrBodyTooLarge = errors.New("body exceeds the max inspected size")
+
+	requestBodyCheckPoints = []models.ChkPoint{models.ChkPointGetPostKey, models.ChkPointGetPostValue,
+		models.ChkPointValueLength, models.ChkPointUploadFileExt,
+		models.ChkPointXMLDoctype, models.ChkPointXMLEntityExpansion,
+		models.ChkPointGraphQLDepth, models.ChkPointGraphQLComplexity, models.ChkPointGraphQLField,
+		models.ChkPointJSONDepth, models.ChkPointJSONElementCount, models.ChkPointJSONSize,
+		models.ChkPointUploadFileType, models.ChkPointUploadTypeMismatch, models.ChkPointUploadContent, models.ChkPointUploadVirus}
+)
+
+// bufferedBody is a body read into memory, it can be dumped without consuming
+type bufferedBody struct {
+	*bytes.Reader
+	buf []byte
+}
+
+func (body *bufferedBody) Close() error {
+	return nil
+}
+
+func newBufferedBody(buf []byte) *bufferedBody {
+	return &bufferedBody{Reader: bytes.NewReader(buf), buf: buf}
+}
+
+// partialBody replays the read part and streams the rest of original body
+type partialBody struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (body *partialBody) Close() error {
+	return body.closer.Close()
+}
+
+// HasCheckItems return true if any check item exists for the check points
+func HasCheckItems(checkPoints ...models.ChkPoint) bool {
+	for _, checkPoint := range checkPoints {
+		if value, ok := checkPointCheckItemsMap.Load(checkPoint); ok {
+			if len(value.([]*models.CheckItem)) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readLimitedBody read at most maxSize bytes, if exceeded, the original body is kept streaming
+func readLimitedBody(body io.ReadCloser, contentLength int64, maxSize int64) (io.ReadCloser, []byte, error) {
+	if contentLength > maxSize {
+		return body, nil, ErrBodyTooLarge
+	}
+	buf, err := ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		return &partialBody{Reader: io.MultiReader(bytes.NewReader(buf), body), closer: body}, nil, err
+	}
+	if int64(len(buf)) > maxSize {
+		return &partialBody{Reader: io.MultiReader(bytes.NewReader(buf), body), closer: body}, nil, ErrBodyTooLarge
+	}
+	body.Close()
+	return newBufferedBody(buf), buf, nil
+}
+
+// PrepareRequestBody buffer the request body for inspection if any body check item or API schema exists,
+// return false if the body will be passed to backend without inspection
+func PrepareRequestBody(r *http.Request, appID int64, maxSize int64) (bool, error) {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return false, nil
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxRequestBodySize
+	}
+	if r.ContentLength > maxSize {
+		return false, ErrBodyTooLarge
+	}
+	if !HasCheckItems(requestBodyCheckPoints...) && !HasAPISchema(appID) {
+		return false, nil
+	}
+	body, _, err := readLimitedBody(r.Body, r.ContentLength, maxSize)
+	r.Body = body
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PrepareResponseBody read the response body if response body check items exist,
+// return nil if the body is passed to client without inspection
+func PrepareResponseBody(resp *http.Response, maxSize int64) []byte {
+	if resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	if !HasCheckItems(models.ChkPointResponseBody) {
+		return nil
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxResponseBodySize
+	}
+	body, buf, _ := readLimitedBody(resp.Body, resp.ContentLength, maxSize)
+	resp.Body = body
+	return buf
+}
+
+// GetBufferedBody return the inspected request body, nil if it is not buffered
+func GetBufferedBody(r *http.Request) []byte {
+	if body, ok := r.Body.(*bufferedBody); ok {
+		return body.buf
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 22:24:16
+ * @Last Modified: U2, 2026-10-19 22:24:16
+ */
+
+package firewall
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// KnownCrawler is the search engine crawler which can be verified by forward-confirmed reverse DNS
+type KnownCrawler struct {
+	Name string
+	// UAKeyword is the lower case keyword in User-Agent
+	UAKeyword string
+	// Domains are the suffixes of the host name resolved by reverse DNS
+	Domains []string
+}
+
+type crawlerVerifyResult struct {
+	IsVerified bool
+	ExpireTime int64
+}
+
+var (
+	botPolicies   []*models.BotPolicy
+	botResolver   = net.DefaultResolver
+	botDNSTimeout = 2 * time.Second
+	// crawlerVerifyResults map[string]*crawlerVerifyResult, client IP and crawler name
+	crawlerVerifyResults sync.Map
+
+	knownCrawlers = []*KnownCrawler{
+		{Name: "Googlebot", UAKeyword: "googlebot", Domains: []string{".googlebot.com", ".google.com"}},
+		{Name: "Bingbot", UAKeyword: "bingbot", Domains: []string{".search.msn.com"}},
+		{Name: "Baiduspider", UAKeyword: "baiduspider", Domains: []string{".baidu.com", ".baidu.jp"}},
+		{Name: "YandexBot", UAKeyword: "yandex", Domains: []string{".yandex.ru", ".yandex.net", ".yandex.com"}},
+		{Name: "Applebot", UAKeyword: "applebot", Domains: []string{".applebot.apple.com"}},
+		{Name: "Sogou", UAKeyword: "sogou", Domains: []string{".sogou.com"}},
+		{Name: "Yahoo Slurp", UAKeyword: "slurp", Domains: []string{".crawl.yahoo.net"}},
+		{Name: "PetalBot", UAKeyword: "petalbot", Domains: []string{".petalsearch.com"}},
+	}
+
+	// scannerUAKeywords are lower case keywords of vulnerability scanners in User-Agent
+	scannerUAKeywords = []string{"sqlmap", "nikto", "nmap", "masscan", "zgrab", "nuclei", "acunetix",
+		"netsparker", "nessus", "openvas", "wpscan", "dirbuster", "gobuster", "ffuf", "wfuzz", "whatweb",
+		"w3af", "arachni", "appscan", "webinspect", "fimap", "havij", "commix", "zmeu", "morfeus", "jorgee"}
+	// scannerHeaders are the headers added by vulnerability scanners
+	scannerHeaders = []string{"Acunetix-Product", "Acunetix-Scanning-Agreement", "Acunetix-User-Agreement",
+		"X-WIPP", "X-Scan-Memo", "X-Request-Memo", "X-RequestManager-Memo"}
+	// automationUAKeywords are lower case keywords of headless browsers, automation frameworks and HTTP libraries
+	automationUAKeywords = []string{"headlesschrome", "phantomjs", "slimerjs", "selenium", "webdriver",
+		"puppeteer", "playwright", "python-requests", "python-urllib", "aiohttp", "go-http-client", "curl/",
+		"wget/", "libwww-perl", "okhttp", "java/", "apache-httpclient", "node-fetch", "axios/", "scrapy"}
+)
+
+// InitBot set the resolver for crawler verification and load bot policies
+func InitBot() {
+	initBotResolver()
+	if data.IsMaster {
+		data.DAL.CreateTableIfNotExistsBotPolicies()
+		botPolicies = data.DAL.SelectBotPolicies()
+	} else {
+		botPolicies = RPCSelectBotPolicies()
+	}
+}
+
+// initBotResolver use the DNS server in config to verify crawlers, or the system resolver if not set
+func initBotResolver() {
+	if data.CFG != nil {
+		if dnsServer := data.CFG.Bot.DNSServer; len(dnsServer) > 0 {
+			botResolver = &net.Resolver{
+				PreferGo: true,
+				Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+					dialer := net.Dialer{}
+					return dialer.DialContext(ctx, network, dnsServer)
+				},
+			}
+		}
+		if data.CFG.Bot.DNSTimeoutMilliseconds > 0 {
+			botDNSTimeout = time.Duration(data.CFG.Bot.DNSTimeoutMilliseconds) * time.Millisecond
+		}
+	}
+}
+
+// GetBotPolicies ...
+func GetBotPolicies() ([]*models.BotPolicy, error) {
+	return botPolicies, nil
+}
+
+// GetBotPolicyByID ...
+func GetBotPolicyByID(id int64) (*models.BotPolicy, error) {
+	for _, botPolicy := range botPolicies {
+		if botPolicy.ID == id {
+			return botPolicy, nil
+		}
+	}
+	return nil, errors.New("Not found")
+}
+
+// UpdateBotPolicy ...
+func UpdateBotPolicy(r *http.Request, userID int64) (*models.BotPolicy, error) {
+	var setBotPolicyRequest models.RPCSetBotPolicy
+	err := json.NewDecoder(r.Body).Decode(&setBotPolicyRequest)
+	defer r.Body.Close()
+	utils.CheckError("UpdateBotPolicy Decode", err)
+	curPolicy := setBotPolicyRequest.Object
+	if curPolicy == nil {
+		return nil, errors.New("UpdateBotPolicy parse body null")
+	}
+	if curPolicy.Category < models.Bot_VerifiedCrawler || curPolicy.Category > models.Bot_Scanner {
+		return nil, errors.New("category should be 1 (verified crawler), 2 (fake crawler), 3 (automation) or 4 (scanner)")
+	}
+	curPolicy.UserID = userID
+	curPolicy.UpdateTime = time.Now().Unix()
+	if curPolicy.ID == 0 {
+		newID, err := data.DAL.InsertBotPolicy(curPolicy.AppID, curPolicy.Category, curPolicy.Action, curPolicy.IsEnabled, curPolicy.UserID, curPolicy.UpdateTime)
+		if err != nil {
+			return nil, err
+		}
+		curPolicy.ID = newID
+		botPolicies = append(botPolicies, curPolicy)
+	} else {
+		botPolicy, err := GetBotPolicyByID(curPolicy.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = data.DAL.UpdateBotPolicy(curPolicy.AppID, curPolicy.Category, curPolicy.Action, curPolicy.IsEnabled, curPolicy.UserID, curPolicy.UpdateTime, curPolicy.ID)
+		if err != nil {
+			return nil, err
+		}
+		*botPolicy = *curPolicy
+		curPolicy = botPolicy
+	}
+	data.UpdateFirewallLastModified()
+	return curPolicy, nil
+}
+
+// DeleteBotPolicyByID ...
+func DeleteBotPolicyByID(id int64) error {
+	for i, botPolicy := range botPolicies {
+		if botPolicy.ID == id {
+			data.DAL.DeleteBotPolicyByID(id)
+			botPolicies = append(botPolicies[:i], botPolicies[i+1:]...)
+			data.UpdateFirewallLastModified()
+			return nil
+		}
+	}
+	return errors.New("Not found")
+}
+
+// GetBotPolicy return the enabled policy of the category, the policy of application takes precedence
+func GetBotPolicy(appID int64, category models.BotCategory) *models.BotPolicy {
+	var globalPolicy *models.BotPolicy
+	for _, botPolicy := range botPolicies {
+		if !botPolicy.IsEnabled || botPolicy.Category != category {
+			continue
+		}
+		if botPolicy.AppID == appID {
+			return botPolicy
+		}
+		if botPolicy.AppID == 0 && globalPolicy == nil {
+			globalPolicy = botPolicy
+		}
+	}
+	return globalPolicy
+}
+
+func hasBotPolicy(appID int64) bool {
+	for _, botPolicy := range botPolicies {
+		if botPolicy.IsEnabled && (botPolicy.AppID == 0 || botPolicy.AppID == appID) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBotHitPolicy classify the request and return the policy of bot category, and the bot name
+func IsBotHitPolicy(r *http.Request, appID int64, srcIP string) (bool, *models.BotPolicy, string) {
+	if !hasBotPolicy(appID) {
+		return false, nil, ""
+	}
+	category, botName := ClassifyBot(r, srcIP)
+	if category == models.Bot_None {
+		return false, nil, ""
+	}
+	botPolicy := GetBotPolicy(appID, category)
+	if botPolicy == nil {
+		return false, nil, ""
+	}
+	return true, botPolicy, botName
+}
+
+// ClassifyBot return the bot category and the bot name (or the signature) of request
+func ClassifyBot(r *http.Request, srcIP string) (models.BotCategory, string) {
+	ua := strings.ToLower(r.UserAgent())
+	for _, keyword := range scannerUAKeywords {
+		if strings.Contains(ua, keyword) {
+			return models.Bot_Scanner, keyword
+		}
+	}
+	for _, header := range scannerHeaders {
+		if len(r.Header.Get(header)) > 0 {
+			return models.Bot_Scanner, header
+		}
+	}
+	for _, crawler := range knownCrawlers {
+		if strings.Contains(ua, crawler.UAKeyword) {
+			if VerifyCrawler(srcIP, crawler) {
+				return models.Bot_VerifiedCrawler, crawler.Name
+			}
+			return models.Bot_FakeCrawler, crawler.Name
+		}
+	}
+	if signature := getAutomationSignature(r, ua); len(signature) > 0 {
+		return models.Bot_Automation, signature
+	}
+	return models.Bot_None, ""
+}
+
+// getAutomationSignature check User-Agent, headers of browser and TLS ClientHello
+func getAutomationSignature(r *http.Request, ua string) string {
+	if len(ua) == 0 {
+		return "empty user-agent"
+	}
+	for _, keyword := range automationUAKeywords {
+		if strings.Contains(ua, keyword) {
+			return keyword
+		}
+	}
+	if !strings.HasPrefix(ua, "mozilla/") {
+		return ""
+	}
+	// the following User-Agent claims to be a browser
+	if len(r.Header.Get("Accept")) == 0 {
+		return "browser without accept"
+	}
+	if len(r.Header.Get("Accept-Language")) == 0 {
+		return "browser without accept-language"
+	}
+	clientHello, ok := r.Context().Value("clientHello").(*models.ClientHello)
+	if !ok || len(clientHello.CipherSuites) == 0 {
+		return ""
+	}
+	if len(clientHello.SupportedProtos) == 0 {
+		return "browser tls without alpn"
+	}
+	// Chromium based browsers always send GREASE cipher suites
+	if strings.Contains(ua, "chrome/") && !hasGREASE(clientHello.CipherSuites) {
+		return "chrome tls without grease"
+	}
+	return ""
+}
+
+// IsGREASE return true for the reserved values like 0x0A0A, 0x1A1A ... 0xFAFA (RFC 8701)
+func IsGREASE(value uint16) bool {
+	return value&0x0F0F == 0x0A0A && value>>8 == value&0xFF
+}
+
+func hasGREASE(values []uint16) bool {
+	for _, value := range values {
+		if IsGREASE(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyCrawler check the client IP by forward-confirmed reverse DNS, the result is cached
+// by client IP and crawler, so that the IP verified for one crawler is not trusted for another
+func VerifyCrawler(srcIP string, crawler *KnownCrawler) bool {
+	curTime := time.Now().Unix()
+	cacheKey := srcIP + "\n" + crawler.Name
+	if resultI, ok := crawlerVerifyResults.Load(cacheKey); ok {
+		result := resultI.(*crawlerVerifyResult)
+		if result.ExpireTime > curTime {
+			return result.IsVerified
+		}
+	}
+	isVerified, err := lookupCrawler(srcIP, crawler.Domains)
+	result := &crawlerVerifyResult{IsVerified: isVerified, ExpireTime: curTime + 3600}
+	if isVerified {
+		result.ExpireTime = curTime + 86400
+	} else if err != nil {
+		// retry soon if the resolver is not available
+		result.ExpireTime = curTime + 60
+	}
+	crawlerVerifyResults.Store(cacheKey, result)
+	return isVerified
+}
+
+// lookupCrawler the host name of IP should match the domains, and the host name should be resolved to the IP
+func lookupCrawler(srcIP string, domains []string) (bool, error) {
+	ip := net.ParseIP(srcIP)
+	if ip == nil {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), botDNSTimeout)
+	defer cancel()
+	names, err := botResolver.LookupAddr(ctx, srcIP)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); ok && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		isMatched := false
+		for _, domain := range domains {
+			if strings.HasSuffix(name, domain) {
+				isMatched = true
+				break
+			}
+		}
+		if !isMatched {
+			continue
+		}
+		addrs, err := botResolver.LookupIPAddr(ctx, name)
+		if err != nil {
+			utils.DebugPrintln("lookupCrawler LookupIPAddr", name, err)
+			continue
+		}
+		for _, addr := range addrs {
+			if addr.IP.Equal(ip) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// BotTick clear expired crawler verify results
+func BotTick() {
+	botTicker := time.NewTicker(time.Duration(10*60) * time.Second)
+	for range botTicker.C {
+		curTime := time.Now().Unix()
+		crawlerVerifyResults.Range(func(key, value interface{}) bool {
+			if value.(*crawlerVerifyResult).ExpireTime <= curTime {
+				crawlerVerifyResults.Delete(key)
+			}
+			return true
+		})
+	}
+}
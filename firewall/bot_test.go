@@ -0,0 +1,157 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 11:16:48
+ * @Last Modified: U2, 2026-10-20 11:16:48
+ */
+
+package firewall
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// dnsStub is a UDP DNS server answering PTR and A queries from the maps
+type dnsStub struct {
+	conn    net.PacketConn
+	ptr     map[string]string
+	a       map[string]string
+	queries int64
+}
+
+func newDNSStub(t *testing.T, ptr map[string]string, a map[string]string) *dnsStub {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &dnsStub{conn: conn, ptr: ptr, a: a}
+	go stub.serve()
+	return stub
+}
+
+func (stub *dnsStub) serve() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := stub.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var parser dnsmessage.Parser
+		header, err := parser.Start(buf[:n])
+		if err != nil {
+			continue
+		}
+		question, err := parser.Question()
+		if err != nil {
+			continue
+		}
+		atomic.AddInt64(&stub.queries, 1)
+		name := strings.ToLower(question.Name.String())
+		respHeader := dnsmessage.Header{ID: header.ID, Response: true, Authoritative: true, RecursionDesired: header.RecursionDesired}
+		var ptrName, aAddr string
+		var found bool
+		switch question.Type {
+		case dnsmessage.TypePTR:
+			ptrName, found = stub.ptr[name]
+		case dnsmessage.TypeA:
+			aAddr, found = stub.a[name]
+		default:
+			// no AAAA records, answer empty if the name exists
+			_, found = stub.a[name]
+		}
+		if !found {
+			respHeader.RCode = dnsmessage.RCodeNameError
+		}
+		builder := dnsmessage.NewBuilder(nil, respHeader)
+		builder.StartQuestions()
+		builder.Question(question)
+		builder.StartAnswers()
+		resource := dnsmessage.ResourceHeader{Name: question.Name, Type: question.Type, Class: dnsmessage.ClassINET, TTL: 60}
+		if len(ptrName) > 0 {
+			builder.PTRResource(resource, dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(ptrName)})
+		}
+		if len(aAddr) > 0 {
+			var a [4]byte
+			copy(a[:], net.ParseIP(aAddr).To4())
+			builder.AResource(resource, dnsmessage.AResource{A: a})
+		}
+		resp, err := builder.Finish()
+		if err != nil {
+			continue
+		}
+		stub.conn.WriteTo(resp, addr)
+	}
+}
+
+func setupBotResolver(t *testing.T, dnsServer string) {
+	oldCFG, oldResolver, oldTimeout := data.CFG, botResolver, botDNSTimeout
+	data.CFG = &models.Config{Bot: models.BotConfig{DNSServer: dnsServer, DNSTimeoutMilliseconds: 1000}}
+	initBotResolver()
+	crawlerVerifyResults = sync.Map{}
+	t.Cleanup(func() {
+		data.CFG, botResolver, botDNSTimeout = oldCFG, oldResolver, oldTimeout
+		crawlerVerifyResults = sync.Map{}
+	})
+}
+
+func getKnownCrawler(name string) *KnownCrawler {
+	for _, crawler := range knownCrawlers {
+		if crawler.Name == name {
+			return crawler
+		}
+	}
+	return nil
+}
+
+func TestVerifyCrawler(t *testing.T) {
+	stub := newDNSStub(t, map[string]string{
+		"1.66.249.66.in-addr.arpa.": "crawl-66-249-66-1.googlebot.com.",
+		"10.2.0.192.in-addr.arpa.":  "crawl.googlebot.com.example.net.",
+		"20.2.0.192.in-addr.arpa.":  "fake.googlebot.com.",
+	}, map[string]string{
+		"crawl-66-249-66-1.googlebot.com.": "66.249.66.1",
+		"crawl.googlebot.com.example.net.": "192.0.2.10",
+		"fake.googlebot.com.":              "192.0.2.99",
+	})
+	defer stub.conn.Close()
+	setupBotResolver(t, stub.conn.LocalAddr().String())
+	googlebot := getKnownCrawler("Googlebot")
+	bingbot := getKnownCrawler("Bingbot")
+	tests := []struct {
+		srcIP   string
+		crawler *KnownCrawler
+		want    bool
+	}{
+		{"66.249.66.1", googlebot, true},
+		// host name does not match the domains
+		{"192.0.2.10", googlebot, false},
+		// host name is not resolved to the client IP
+		{"192.0.2.20", googlebot, false},
+		// no PTR record
+		{"192.0.2.30", googlebot, false},
+		// verified as Googlebot, but it is not Bingbot
+		{"66.249.66.1", bingbot, false},
+		{"66.249.66.1", googlebot, true},
+	}
+	for _, test := range tests {
+		if got := VerifyCrawler(test.srcIP, test.crawler); got != test.want {
+			t.Errorf("VerifyCrawler(%s, %s) = %v, want %v", test.srcIP, test.crawler.Name, got, test.want)
+		}
+	}
+	// all results are cached
+	queries := atomic.LoadInt64(&stub.queries)
+	for _, test := range tests {
+		VerifyCrawler(test.srcIP, test.crawler)
+	}
+	if got := atomic.LoadInt64(&stub.queries); got != queries {
+		t.Errorf("got %d DNS queries for cached results", got-queries)
+	}
+}
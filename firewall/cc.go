@@ -30,6 +30,7 @@ func ClearCCStatByClientID(policyAppID int64, clientID string) {
 		appCCCount := ccCount.(*sync.Map)
 		appCCCount.Delete(clientID)
 	}
+	ClearSharedCCStat(policyAppID, clientID)
 }
 
 // CCAttackTick CC tick
@@ -111,6 +112,10 @@ func IsCCAttack(r *http.Request, appID int64, srcIP string) (bool, *models.CCPol
 		cookie := r.Header.Get("Cookie")
 		preHashContent += cookie
 	}
+	if ccPolicy.StatByTLSFingerprint == true {
+		_, ja4 := GetTLSFingerprint(r)
+		preHashContent += ja4
+	}
 	clientID := data.SHA256Hash(preHashContent)
 	clientIDStat, _ := appCCCount.LoadOrStore(clientID, &models.ClientStat{Count: 0, IsBlackIP: false, RemainSeconds: 0})
 	clientStat := clientIDStat.(*models.ClientStat)
@@ -122,6 +127,20 @@ func IsCCAttack(r *http.Request, appID int64, srcIP string) (bool, *models.CCPol
 		}
 		return true, ccPolicy, clientID, needLog
 	}
+	if IsCCStoreAvailable() {
+		// Count among all nodes, the local stat caches the block state only
+		remainSeconds, err := IncreaseSharedCCCount(appID, clientID, ccPolicy)
+		if err == nil {
+			if remainSeconds > 0 {
+				clientStat.IsBlackIP = true
+				clientStat.RemainSeconds = remainSeconds
+				clientStat.Count = 1
+				return true, ccPolicy, clientID, true
+			}
+			return false, nil, "", false
+		}
+		// fallback to local counting
+	}
 	clientStat.Count++
 	//fmt.Println("IsCCAttack:", r.URL.Path, clientID, clientStat.Count, clientStat.IsBlackIP, clientStat.RemainSeconds)
 	return false, nil, "", false
@@ -134,7 +153,7 @@ func InitCCPolicy() {
 		data.DAL.CreateTableIfNotExistsCCPolicy()
 		existCCPolicy := data.DAL.ExistsCCPolicy()
 		if existCCPolicy == false {
-			data.DAL.InsertCCPolicy(0, 10, 60, 300, models.Action_Block_100, true, true, false, true)
+			data.DAL.InsertCCPolicy(0, 10, 60, 300, models.Action_Block_100, true, true, false, false, true)
 		}
 		ccPoliciesList = data.DAL.SelectCCPolicies()
 	} else {
@@ -157,11 +176,12 @@ func UpdateCCPolicy(param map[string]interface{}) error {
 	statByURL := ccPolicyMap["stat_by_url"].(bool)
 	statByUA := ccPolicyMap["stat_by_ua"].(bool)
 	statByCookie := ccPolicyMap["stat_by_cookie"].(bool)
+	statByTLSFingerprint, _ := ccPolicyMap["stat_by_tls_fingerprint"].(bool)
 	isEnabled := ccPolicyMap["is_enabled"].(bool)
 	existAppID := data.DAL.ExistsCCPolicyByAppID(appID)
 	if existAppID == false {
 		// new policy
-		err := data.DAL.InsertCCPolicy(appID, intervalSeconds, maxCount, blockSeconds, action, statByURL, statByUA, statByCookie, isEnabled)
+		err := data.DAL.InsertCCPolicy(appID, intervalSeconds, maxCount, blockSeconds, action, statByURL, statByUA, statByCookie, statByTLSFingerprint, isEnabled)
 		if err != nil {
 			return err
 		}
@@ -169,14 +189,14 @@ func UpdateCCPolicy(param map[string]interface{}) error {
 			AppID:           appID,
 			IntervalSeconds: intervalSeconds, MaxCount: maxCount, BlockSeconds: blockSeconds,
 			Action: action, StatByURL: statByURL, StatByUserAgent: statByUA, StatByCookie: statByCookie,
-			IsEnabled: isEnabled}
+			StatByTLSFingerprint: statByTLSFingerprint, IsEnabled: isEnabled}
 		ccPolicies.Store(appID, ccPolicy)
 		if ccPolicy.IsEnabled == true {
 			go CCAttackTick(appID)
 		}
 	} else {
 		// update policy
-		err := data.DAL.UpdateCCPolicy(intervalSeconds, maxCount, blockSeconds, action, statByURL, statByUA, statByCookie, isEnabled, appID)
+		err := data.DAL.UpdateCCPolicy(intervalSeconds, maxCount, blockSeconds, action, statByURL, statByUA, statByCookie, statByTLSFingerprint, isEnabled, appID)
 		if err != nil {
 			return err
 		}
@@ -192,6 +212,7 @@ func UpdateCCPolicy(param map[string]interface{}) error {
 		ccPolicy.StatByURL = statByURL
 		ccPolicy.StatByUserAgent = statByUA
 		ccPolicy.StatByCookie = statByCookie
+		ccPolicy.StatByTLSFingerprint = statByTLSFingerprint
 		ccPolicy.Action = action
 		ccPolicy.IsEnabled = isEnabled
 		if ccPolicy.IsEnabled == true {
@@ -0,0 +1,233 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 18:40:12
+ * @Last Modified: U2, 2026-10-19 18:40:12
+ */
+
+package firewall
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	ccStoreKeyPrefix = "janusec:cc:"
+	// ccStoreRetrySeconds is the time of local counting after the shared store failed
+	ccStoreRetrySeconds = 10
+	ccStoreMaxIdleConns = 16
+)
+
+var (
+	ccStoreConns = make(chan *ccStoreConn, ccStoreMaxIdleConns)
+	// ccStoreRetryTime is the unix time the shared store will be tried again
+	ccStoreRetryTime int64
+)
+
+// ccStoreConn is a connection to Redis protocol compatible server
+type ccStoreConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// ccStoreError is the error reply of server
+type ccStoreError string
+
+func (e ccStoreError) Error() string {
+	return string(e)
+}
+
+// IsCCStoreAvailable return true if the shared CC store is enabled and not in fallback
+func IsCCStoreAvailable() bool {
+	if data.CFG == nil || !data.CFG.CCStore.Enabled {
+		return false
+	}
+	return atomic.LoadInt64(&ccStoreRetryTime) <= time.Now().Unix()
+}
+
+func getCCStoreTimeout() time.Duration {
+	timeout := time.Duration(data.CFG.CCStore.TimeoutMilliseconds) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 200 * time.Millisecond
+	}
+	return timeout
+}
+
+func getCCStoreConn() (*ccStoreConn, error) {
+	select {
+	case storeConn := <-ccStoreConns:
+		return storeConn, nil
+	default:
+	}
+	conn, err := net.DialTimeout("tcp", data.CFG.CCStore.Address, getCCStoreTimeout())
+	if err != nil {
+		return nil, err
+	}
+	storeConn := &ccStoreConn{conn: conn, reader: bufio.NewReader(conn)}
+	var cmds [][]string
+	if len(data.CFG.CCStore.Password) > 0 {
+		cmds = append(cmds, []string{"AUTH", data.CFG.CCStore.Password})
+	}
+	if data.CFG.CCStore.DB > 0 {
+		cmds = append(cmds, []string{"SELECT", strconv.FormatInt(data.CFG.CCStore.DB, 10)})
+	}
+	if len(cmds) > 0 {
+		if _, err = storeConn.do(cmds...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return storeConn, nil
+}
+
+func putCCStoreConn(storeConn *ccStoreConn) {
+	select {
+	case ccStoreConns <- storeConn:
+	default:
+		storeConn.conn.Close()
+	}
+}
+
+// do send commands in pipeline and read one reply for each command
+func (storeConn *ccStoreConn) do(cmds ...[]string) ([]interface{}, error) {
+	storeConn.conn.SetDeadline(time.Now().Add(getCCStoreTimeout()))
+	writer := bufio.NewWriter(storeConn.conn)
+	for _, cmd := range cmds {
+		fmt.Fprintf(writer, "*%d\r\n", len(cmd))
+		for _, arg := range cmd {
+			fmt.Fprintf(writer, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return nil, err
+	}
+	replies := make([]interface{}, len(cmds))
+	for i := range cmds {
+		reply, err := readCCStoreReply(storeConn.reader)
+		if err != nil {
+			return nil, err
+		}
+		if replyErr, ok := reply.(ccStoreError); ok {
+			return nil, replyErr
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+// readCCStoreReply read a RESP reply, return string, int64, nil, ccStoreError or []interface{}
+func readCCStoreReply(reader *bufio.Reader) (interface{}, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errors.New("cc store: invalid reply")
+	}
+	content := line[1 : len(line)-2]
+	switch line[0] {
+	case '+':
+		return content, nil
+	case '-':
+		return ccStoreError(content), nil
+	case ':':
+		return strconv.ParseInt(content, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(content)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err = io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(content)
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = readCCStoreReply(reader); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, errors.New("cc store: unknown reply type")
+}
+
+// ccStoreDo run commands on the shared store, switch to local counting for a while if failed
+func ccStoreDo(cmds ...[]string) ([]interface{}, error) {
+	storeConn, err := getCCStoreConn()
+	if err == nil {
+		var replies []interface{}
+		replies, err = storeConn.do(cmds...)
+		if err == nil {
+			putCCStoreConn(storeConn)
+			return replies, nil
+		}
+		storeConn.conn.Close()
+	}
+	if _, ok := err.(ccStoreError); !ok {
+		atomic.StoreInt64(&ccStoreRetryTime, time.Now().Unix()+ccStoreRetrySeconds)
+	}
+	utils.CheckError("ccStoreDo", err)
+	return nil, err
+}
+
+// IncreaseSharedCCCount count the request in the shared store within current interval,
+// return the remain block seconds if the client is blocked
+func IncreaseSharedCCCount(appID int64, clientID string, ccPolicy *models.CCPolicy) (time.Duration, error) {
+	intervalSeconds := int64(ccPolicy.IntervalSeconds)
+	if intervalSeconds <= 0 {
+		intervalSeconds = 1
+	}
+	blockKey := fmt.Sprintf("%s%d:%s:block", ccStoreKeyPrefix, appID, clientID)
+	countKey := fmt.Sprintf("%s%d:%s:%d", ccStoreKeyPrefix, appID, clientID, time.Now().Unix()/intervalSeconds)
+	replies, err := ccStoreDo(
+		[]string{"TTL", blockKey},
+		[]string{"INCR", countKey},
+		[]string{"EXPIRE", countKey, strconv.FormatInt(intervalSeconds*2, 10)})
+	if err != nil {
+		return 0, err
+	}
+	ttl, _ := replies[0].(int64)
+	if ttl > 0 {
+		return time.Duration(ttl), nil
+	}
+	count, _ := replies[1].(int64)
+	// Same threshold as local counting in CCAttackTick
+	if count >= ccPolicy.MaxCount {
+		blockSeconds := ccPolicy.BlockSeconds
+		if blockSeconds <= 0 {
+			blockSeconds = time.Duration(intervalSeconds)
+		}
+		if _, err = ccStoreDo([]string{"SET", blockKey, "1", "EX", strconv.FormatInt(int64(blockSeconds), 10)}); err != nil {
+			return 0, err
+		}
+		return blockSeconds, nil
+	}
+	return 0, nil
+}
+
+// ClearSharedCCStat remove the shared block state of client
+func ClearSharedCCStat(appID int64, clientID string) {
+	if !IsCCStoreAvailable() {
+		return
+	}
+	blockKey := fmt.Sprintf("%s%d:%s:block", ccStoreKeyPrefix, appID, clientID)
+	ccStoreDo([]string{"DEL", blockKey})
+}
@@ -0,0 +1,212 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 10:02:36
+ * @Last Modified: U2, 2026-10-20 10:02:36
+ */
+
+package firewall
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+)
+
+// respStub is a minimal RESP server for the commands used by the shared CC store
+type respStub struct {
+	listener net.Listener
+	password string
+	mutex    sync.Mutex
+	values   map[string]int64
+	ttls     map[string]int64
+	commands []string
+}
+
+func newRESPStub(t *testing.T, password string) *respStub {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &respStub{listener: listener, password: password, values: map[string]int64{}, ttls: map[string]int64{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go stub.serve(conn)
+		}
+	}()
+	return stub
+}
+
+func (stub *respStub) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authed := len(stub.password) == 0
+	for {
+		reply, err := readCCStoreReply(reader)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]interface{})
+		var args []string
+		for _, item := range items {
+			arg, _ := item.(string)
+			args = append(args, arg)
+		}
+		if len(args) == 0 {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		stub.mutex.Lock()
+		stub.commands = append(stub.commands, cmd)
+		var resp string
+		switch {
+		case cmd == "AUTH":
+			if len(args) == 2 && args[1] == stub.password {
+				authed = true
+				resp = "+OK\r\n"
+			} else {
+				resp = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			resp = "-NOAUTH Authentication required.\r\n"
+		case cmd == "SELECT":
+			resp = "+OK\r\n"
+		case cmd == "INCR":
+			stub.values[args[1]]++
+			resp = fmt.Sprintf(":%d\r\n", stub.values[args[1]])
+		case cmd == "EXPIRE":
+			seconds, _ := strconv.ParseInt(args[2], 10, 64)
+			stub.ttls[args[1]] = seconds
+			resp = ":1\r\n"
+		case cmd == "TTL":
+			ttl, ok := stub.ttls[args[1]]
+			if !ok {
+				ttl = -2
+			}
+			resp = fmt.Sprintf(":%d\r\n", ttl)
+		case cmd == "SET":
+			stub.values[args[1]] = 1
+			if len(args) == 5 && strings.ToUpper(args[3]) == "EX" {
+				stub.ttls[args[1]], _ = strconv.ParseInt(args[4], 10, 64)
+			}
+			resp = "+OK\r\n"
+		case cmd == "DEL":
+			delete(stub.values, args[1])
+			delete(stub.ttls, args[1])
+			resp = ":1\r\n"
+		default:
+			resp = "-ERR unknown command\r\n"
+		}
+		stub.mutex.Unlock()
+		if _, err = conn.Write([]byte(resp)); err != nil {
+			return
+		}
+	}
+}
+
+func (stub *respStub) hasCommand(cmd string) bool {
+	stub.mutex.Lock()
+	defer stub.mutex.Unlock()
+	for _, command := range stub.commands {
+		if command == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+func setupCCStore(t *testing.T, address string, password string) {
+	oldCFG := data.CFG
+	data.CFG = &models.Config{CCStore: models.CCStoreConfig{Enabled: true, Address: address, Password: password, DB: 1, TimeoutMilliseconds: 1000}}
+	ccStoreRetryTime = 0
+	t.Cleanup(func() {
+		closeCCStoreConns()
+		data.CFG = oldCFG
+		ccStoreRetryTime = 0
+	})
+}
+
+func closeCCStoreConns() {
+	for {
+		select {
+		case storeConn := <-ccStoreConns:
+			storeConn.conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+func TestIncreaseSharedCCCount(t *testing.T) {
+	stub := newRESPStub(t, "secret")
+	defer stub.listener.Close()
+	setupCCStore(t, stub.listener.Addr().String(), "secret")
+	ccPolicy := &models.CCPolicy{IntervalSeconds: 60, MaxCount: 3, BlockSeconds: 300}
+	for i := int64(1); i < ccPolicy.MaxCount; i++ {
+		remainSeconds, err := IncreaseSharedCCCount(1, "client", ccPolicy)
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		if remainSeconds != 0 {
+			t.Fatalf("request %d blocked before reaching max count", i)
+		}
+	}
+	remainSeconds, err := IncreaseSharedCCCount(1, "client", ccPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remainSeconds != ccPolicy.BlockSeconds {
+		t.Fatalf("got remain seconds %d at max count, want %d", remainSeconds, ccPolicy.BlockSeconds)
+	}
+	remainSeconds, err = IncreaseSharedCCCount(1, "client", ccPolicy)
+	if err != nil || remainSeconds != ccPolicy.BlockSeconds {
+		t.Fatalf("got remain seconds %d, err %v for blocked client", remainSeconds, err)
+	}
+	if !stub.hasCommand("AUTH") || !stub.hasCommand("SELECT") {
+		t.Fatal("AUTH and SELECT not sent on new connection")
+	}
+	ClearSharedCCStat(1, "client")
+	if !stub.hasCommand("DEL") {
+		t.Fatal("block state not cleared")
+	}
+	if !IsCCStoreAvailable() {
+		t.Fatal("cc store unavailable after successful commands")
+	}
+}
+
+func TestCCStoreFallback(t *testing.T) {
+	stub := newRESPStub(t, "secret")
+	defer stub.listener.Close()
+	setupCCStore(t, stub.listener.Addr().String(), "wrong")
+	ccPolicy := &models.CCPolicy{IntervalSeconds: 60, MaxCount: 3, BlockSeconds: 300}
+	if _, err := IncreaseSharedCCCount(1, "client", ccPolicy); err == nil {
+		t.Fatal("want error with wrong password")
+	}
+	// An error reply does not switch to local counting
+	if !IsCCStoreAvailable() {
+		t.Fatal("cc store unavailable after error reply")
+	}
+	stub.listener.Close()
+	setupCCStore(t, stub.listener.Addr().String(), "secret")
+	if _, err := IncreaseSharedCCCount(1, "client", ccPolicy); err == nil {
+		t.Fatal("want error with closed listener")
+	}
+	if IsCCStoreAvailable() {
+		t.Fatal("cc store still available after connection failed")
+	}
+	if retrySeconds := ccStoreRetryTime - time.Now().Unix(); retrySeconds <= 0 || retrySeconds > ccStoreRetrySeconds {
+		t.Fatalf("got retry after %d seconds, want within %d", retrySeconds, ccStoreRetrySeconds)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 18:08:33
+ * @Last Modified: U2, 2026-10-19 18:08:33
+ */
+
+package firewall
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	// clamdChunkSize should be less than StreamMaxLength of clamd.conf
+	clamdChunkSize = 64 << 10
+)
+
+// ScanByClamAV send content to local clamd with INSTREAM command,
+// return the virus name, empty if the content is clean or clamd is not enabled
+func ScanByClamAV(content []byte) (string, error) {
+	if data.CFG == nil || !data.CFG.ClamAV.Enabled || len(content) == 0 {
+		return "", nil
+	}
+	network := "tcp"
+	address := data.CFG.ClamAV.Address
+	if strings.HasPrefix(address, "unix:") {
+		network = "unix"
+		address = strings.TrimPrefix(address, "unix:")
+	}
+	timeout := time.Duration(data.CFG.ClamAV.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		utils.CheckError("ScanByClamAV Dial", err)
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err = conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		utils.CheckError("ScanByClamAV Write", err)
+		return "", err
+	}
+	sizeBuf := make([]byte, 4)
+	for start := 0; start < len(content); start += clamdChunkSize {
+		end := start + clamdChunkSize
+		if end > len(content) {
+			end = len(content)
+		}
+		binary.BigEndian.PutUint32(sizeBuf, uint32(end-start))
+		if _, err = conn.Write(sizeBuf); err != nil {
+			break
+		}
+		if _, err = conn.Write(content[start:end]); err != nil {
+			break
+		}
+	}
+	// zero length chunk terminates the stream
+	binary.BigEndian.PutUint32(sizeBuf, 0)
+	conn.Write(sizeBuf)
+	// the reply is read even if writing failed, such as INSTREAM size limit exceeded
+	reply, readErr := ioutil.ReadAll(conn)
+	if readErr != nil && len(reply) == 0 {
+		utils.CheckError("ScanByClamAV Read", readErr)
+		return "", readErr
+	}
+	// stream: OK, stream: Eicar-Signature FOUND, or ... ERROR
+	result := strings.TrimSpace(string(bytes.TrimRight(reply, "\x00")))
+	result = strings.TrimPrefix(result, "stream: ")
+	switch {
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	case strings.HasSuffix(result, "ERROR"):
+		return "", errors.New("clamd: " + result)
+	}
+	return "", nil
+}
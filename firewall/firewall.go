@@ -9,15 +9,12 @@ package firewall
 
 import (
 	"bytes"
-	"encoding/json"
-	"io"
 	"io/ioutil"
 	"mime"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"path/filepath"
-	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -59,22 +56,30 @@ func IsStaticResource(r *http.Request) bool {
 
 // UnEscapeRawValue ...
 func UnEscapeRawValue(rawQuery string) string {
+	decodeQuery := DecodeRawValue(rawQuery)
+	decodeQuery = PreProcessString(decodeQuery)
+	//fmt.Println("UnEscapeRawValue decodeQuery", decodeQuery)
+	return decodeQuery
+}
+
+// DecodeRawValue url decode without PreProcessString, quotes are kept for lexical detection
+func DecodeRawValue(rawQuery string) string {
 	rawQuery = strings.Replace(rawQuery, "%%", "%25%", -1)
 	rawQuery = strings.Replace(rawQuery, "%'", "%25'", -1)
 	rawQuery = strings.Replace(rawQuery, `%"`, `%25"`, -1)
 	re := regexp.MustCompile(`%$`)
 	rawQuery = re.ReplaceAllString(rawQuery, `%25`)
 	decodeQuery, err := url.QueryUnescape(rawQuery)
-	utils.CheckError("UnEscapeRawValue", err)
-	decodeQuery = PreProcessString(decodeQuery)
-	//fmt.Println("UnEscapeRawValue decodeQuery", decodeQuery)
+	utils.CheckError("DecodeRawValue", err)
 	return decodeQuery
 }
 
 // IsRequestHitPolicy ...
-func IsRequestHitPolicy(r *http.Request, appID int64, srcIP string) (bool, *models.GroupPolicy) {
+// inspectBody is false if the request body is not buffered by PrepareRequestBody
+func IsRequestHitPolicy(r *http.Request, appID int64, srcIP string, inspectBody bool) (bool, *models.GroupPolicy) {
 	//fmt.Println("IsForbiddenRequest")
 	ctxMap := r.Context().Value("groupPolicyHitValue").(*sync.Map)
+	LoadRequestPolicyExceptions(ctxMap, r, appID)
 
 	// ChkPoint_Host
 	matched, policy := IsMatchGroupPolicy(ctxMap, appID, r.Host, models.ChkPointHost, "", false)
@@ -88,6 +93,25 @@ func IsRequestHitPolicy(r *http.Request, appID int64, srcIP string) (bool, *mode
 		return matched, policy
 	}
 
+	// ChkPoint_Country
+	if HasCheckItems(models.ChkPointCountry) {
+		matched, policy = IsMatchGroupPolicy(ctxMap, appID, GetCountryCode(srcIP), models.ChkPointCountry, "", false)
+		if matched == true {
+			return matched, policy
+		}
+	}
+
+	// ChkPoint_TLSFingerprint
+	if HasCheckItems(models.ChkPointTLSFingerprint) {
+		ja3, ja4 := GetTLSFingerprint(r)
+		for _, fingerprint := range []string{ja3, ja4} {
+			matched, policy = IsMatchGroupPolicy(ctxMap, appID, fingerprint, models.ChkPointTLSFingerprint, "", false)
+			if matched == true {
+				return matched, policy
+			}
+		}
+	}
+
 	// ChkPoint_Method
 	matched, policy = IsMatchGroupPolicy(ctxMap, appID, r.Method, models.ChkPointMethod, "", false)
 	if matched == true {
@@ -109,64 +133,102 @@ func IsRequestHitPolicy(r *http.Request, appID int64, srcIP string) (bool, *mode
 		}
 	}
 
+	// GraphQL over GET
+	if r.Method == "GET" {
+		for _, query := range GetGraphQLQueries(r, "", nil, nil) {
+			matched, policy = IsGraphQLHitPolicy(ctxMap, appID, query)
+			if matched == true {
+				return matched, policy
+			}
+		}
+	}
+
 	// ChkPoint_ParameterCount
 
-	bodyBuf, _ := ioutil.ReadAll(r.Body)
-	r.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBuf))
 	contentType := r.Header.Get("Content-Type")
-
 	mediaType, mediaParams, _ := mime.ParseMediaType(contentType)
-	if strings.HasPrefix(mediaType, "multipart/form-data") {
-		// ChkPoint_UploadFileExt
-		r.ParseMultipartForm(1024)
-		if r.MultipartForm != nil {
-			for _, filesHeader := range r.MultipartForm.File {
-				for _, fileHeader := range filesHeader {
-					fileExtension := filepath.Ext(fileHeader.Filename) // .php
-					matched, policy = IsMatchGroupPolicy(ctxMap, appID, fileExtension, models.ChkPointUploadFileExt, "", false)
+	var params url.Values
+	if !inspectBody {
+		// body is absent, too large or not required by any check item, only inspect query
+		params = r.URL.Query()
+	} else {
+		bodyBuf := GetBufferedBody(r)
+		// the body may be consumed by parsing, reset it for the backend even if a policy is hit
+		defer func() {
+			r.Body = newBufferedBody(bodyBuf)
+		}()
+		if strings.HasPrefix(mediaType, "multipart/form-data") {
+			// ChkPoint_UploadFileExt
+			r.ParseMultipartForm(1024)
+			if r.MultipartForm != nil {
+				for _, filesHeader := range r.MultipartForm.File {
+					for _, fileHeader := range filesHeader {
+						fileExtension := filepath.Ext(fileHeader.Filename) // .php
+						matched, policy = IsMatchGroupPolicy(ctxMap, appID, fileExtension, models.ChkPointUploadFileExt, "", false)
+						if matched == true {
+							return matched, policy
+						}
+						// ChkPoint_UploadFileType, UploadTypeMismatch, UploadContent and UploadVirus
+						matched, policy = IsUploadFileHitPolicy(ctxMap, appID, fileHeader)
+						if matched == true {
+							return matched, policy
+						}
+					}
+				}
+
+				// Multipart Content
+				multiReader := multipart.NewReader(bytes.NewReader(bodyBuf), mediaParams["boundary"])
+				for {
+					p, err := multiReader.NextPart()
+					if err != nil {
+						break
+					}
+					partContent, err := ioutil.ReadAll(p)
+					//fmt.Println("part_content=", string(part_content))
+					matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, string(partContent), models.ChkPointGetPostValue, "", p.FormName(), true)
 					if matched == true {
 						return matched, policy
 					}
 				}
 			}
 
-			// Multipart Content
-			body1 := ioutil.NopCloser(bytes.NewBuffer(bodyBuf))
-			multiReader := multipart.NewReader(body1, mediaParams["boundary"])
-			for {
-				p, err := multiReader.NextPart()
-				if err == io.EOF {
-					break
-				}
-				partContent, err := ioutil.ReadAll(p)
-				//fmt.Println("part_content=", string(part_content))
-				matched, policy = IsMatchGroupPolicy(ctxMap, appID, string(partContent), models.ChkPointGetPostValue, "", true)
+		} else if strings.HasPrefix(mediaType, "application/json") {
+			jsonParams, err := ParseJSONBody(bodyBuf)
+			utils.CheckError("IsRequestHitPolicy ParseJSONBody", err)
+			matched, policy := IsJSONBodyHitPolicy(ctxMap, appID, bodyBuf, jsonParams)
+			if matched == true {
+				return matched, policy
+			}
+			// GraphQL over JSON, including batch
+			for _, query := range GetGraphQLQueries(r, mediaType, jsonParams, bodyBuf) {
+				matched, policy = IsGraphQLHitPolicy(ctxMap, appID, query)
 				if matched == true {
 					return matched, policy
 				}
 			}
+		} else if mediaType == "application/graphql" {
+			matched, policy = IsGraphQLHitPolicy(ctxMap, appID, string(bodyBuf))
+			if matched == true {
+				return matched, policy
+			}
+		} else if IsXMLMediaType(mediaType) {
+			// XML and SOAP
+			matched, policy = IsXMLBodyHitPolicy(ctxMap, appID, bodyBuf)
+			if matched == true {
+				return matched, policy
+			}
+		} else {
+			r.ParseForm()
 		}
 
-	} else if strings.HasPrefix(mediaType, "application/json") {
-		var params interface{}
-		err := json.Unmarshal(bodyBuf, &params)
-		utils.CheckError("IsRequestHitPolicy Unmarshal", err)
-		matched, policy := IsJSONValueHitPolicy(ctxMap, appID, params)
-		if matched == true {
-			return matched, policy
-		}
-	} else {
-		r.ParseForm()
-	}
-
-	params := r.Form // include GET/POST/ Multipart non-File , but not include json
+		params = r.Form // include GET/POST/ Multipart non-File , but not include json
 
-	//fmt.Println("IsRequestHitPolicy params:", params, "count:", len(params))
-	r.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBuf))
+		//fmt.Println("IsRequestHitPolicy params:", params, "count:", len(params))
+	}
 	for key, values := range params {
 		//fmt.Println("IsRequestHitPolicy param", key, ":", values)
 		// ChkPoint_GetPostKey
-		matched, policy = IsMatchGroupPolicy(ctxMap, appID, key, models.ChkPointGetPostKey, "", false)
+		matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, key, models.ChkPointGetPostKey, "", key, false)
 		if matched == true {
 			return matched, policy
 		}
@@ -179,12 +241,12 @@ func IsRequestHitPolicy(r *http.Request, appID int64, srcIP string) (bool, *mode
 			}
 			// ChkPoint_ValueLength
 			valueLength := strconv.Itoa(len(value))
-			matched, policy = IsMatchGroupPolicy(ctxMap, appID, valueLength, models.ChkPointValueLength, "", false)
+			matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, valueLength, models.ChkPointValueLength, "", key, false)
 			if matched == true {
 				return matched, policy
 			}
 			// ChkPoint_GetPostValue
-			matched, policy = IsMatchGroupPolicy(ctxMap, appID, value, models.ChkPointGetPostValue, "", true)
+			matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, value, models.ChkPointGetPostValue, "", key, true)
 			//fmt.Println("ChkPoint_GetPostValue:", value2, matched)
 			if matched == true {
 				return matched, policy
@@ -197,14 +259,14 @@ func IsRequestHitPolicy(r *http.Request, appID int64, srcIP string) (bool, *mode
 	cookies := r.Cookies()
 	for _, cookie := range cookies {
 		// ChkPoint_CookieKey
-		matched, policy = IsMatchGroupPolicy(ctxMap, appID, cookie.Name, models.ChkPointCookieKey, "", false)
+		matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, cookie.Name, models.ChkPointCookieKey, "", cookie.Name, false)
 		if matched == true {
 			return matched, policy
 		}
 		// ChkPoint_CookieValue
 		//value := UnEscapeRawValue(cookie.Value)
 		//fmt.Println("CookieValue:", value)
-		matched, policy = IsMatchGroupPolicy(ctxMap, appID, cookie.Value, models.ChkPointCookieValue, "", true)
+		matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, cookie.Value, models.ChkPointCookieValue, "", cookie.Name, true)
 		if matched == true {
 			return matched, policy
 		}
@@ -225,7 +287,7 @@ func IsRequestHitPolicy(r *http.Request, appID int64, srcIP string) (bool, *mode
 	// ChkPoint_Header
 	for headerKey, headerValues := range r.Header {
 		// ChkPoint_HeaderKey
-		matched, policy = IsMatchGroupPolicy(ctxMap, appID, headerKey, models.ChkPointHeaderKey, "", false)
+		matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, headerKey, models.ChkPointHeaderKey, "", headerKey, false)
 		if matched == true {
 			return matched, policy
 		}
@@ -233,7 +295,7 @@ func IsRequestHitPolicy(r *http.Request, appID int64, srcIP string) (bool, *mode
 		for _, headerValue := range headerValues {
 
 			//headerValue = UnEscapeRawValue(headerValue)
-			matched, policy = IsMatchGroupPolicy(ctxMap, appID, headerValue, models.ChkPointHeaderValue, headerKey, false)
+			matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, headerValue, models.ChkPointHeaderValue, headerKey, headerKey, false)
 			//fmt.Println("ChkPoint_HeaderValue", headerKey, headerValue, matched)
 			if matched == true {
 				return matched, policy
@@ -251,7 +313,7 @@ func IsRequestHitPolicy(r *http.Request, appID int64, srcIP string) (bool, *mode
 }
 
 // IsResponseHitPolicy ...
-func IsResponseHitPolicy(resp *http.Response, appID int64) (bool, *models.GroupPolicy) {
+func IsResponseHitPolicy(resp *http.Response, appID int64, maxBodySize int64) (bool, *models.GroupPolicy) {
 	if resp.StatusCode == http.StatusSwitchingProtocols {
 		return false, nil
 	}
@@ -259,6 +321,7 @@ func IsResponseHitPolicy(resp *http.Response, appID int64) (bool, *models.GroupP
 		return false, nil
 	}
 	ctxMap := resp.Request.Context().Value("groupPolicyHitValue").(*sync.Map)
+	LoadRequestPolicyExceptions(ctxMap, resp.Request, appID)
 	// ChkPoint_ResponseStatusCode
 	matched, policy := IsMatchGroupPolicy(ctxMap, appID, strconv.Itoa(resp.StatusCode), models.ChkPointResponseStatusCode, "", false)
 	//fmt.Println("IsResponseHitPolicy ResponseStatusCode", matched)
@@ -268,13 +331,13 @@ func IsResponseHitPolicy(resp *http.Response, appID int64) (bool, *models.GroupP
 	// ChkPoint_ResponseHeaderKey
 	for headerKey, headerValues := range resp.Header {
 		// ChkPoint_ResponseHeaderKey
-		matched, policy = IsMatchGroupPolicy(ctxMap, appID, headerKey, models.ChkPointResponseHeaderKey, "", false)
+		matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, headerKey, models.ChkPointResponseHeaderKey, "", headerKey, false)
 		if matched == true {
 			return matched, policy
 		}
 		// ChkPoint_ResponseHeaderValue
 		for _, headerValue := range headerValues {
-			matched, policy = IsMatchGroupPolicy(ctxMap, appID, headerValue, models.ChkPointResponseHeaderValue, headerKey, false)
+			matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, headerValue, models.ChkPointResponseHeaderValue, headerKey, headerKey, false)
 			//fmt.Println("ChkPoint_ResponseHeaderValue", headerKey, headerValue, matched)
 			if matched == true {
 				return matched, policy
@@ -288,10 +351,11 @@ func IsResponseHitPolicy(resp *http.Response, appID int64) (bool, *models.GroupP
 	if matched == true {
 		return matched, policy
 	}
-	// ChkPoint_ResponseBody
-	bodyBuf, _ := ioutil.ReadAll(resp.Body)
-	resp.Body = ioutil.NopCloser(bytes.NewBuffer(bodyBuf))
-	defer resp.Body.Close()
+	// ChkPoint_ResponseBody, the body exceeds maxBodySize is passed without inspection
+	bodyBuf := PrepareResponseBody(resp, maxBodySize)
+	if bodyBuf == nil {
+		return false, nil
+	}
 	body1 := string(bodyBuf)
 	matched, policy = IsMatchGroupPolicy(ctxMap, appID, body1, models.ChkPointResponseBody, "", false)
 	//fmt.Println("IsResponseHitPolicy ChkPoint_ResponseBody", matched)
@@ -302,36 +366,3 @@ func IsResponseHitPolicy(resp *http.Response, appID int64) (bool, *models.GroupP
 	// Not hit any policy
 	return false, nil
 }
-
-// IsJSONValueHitPolicy ...
-func IsJSONValueHitPolicy(ctxMap *sync.Map, appID int64, value interface{}) (bool, *models.GroupPolicy) {
-	if value == nil {
-		return false, nil
-	}
-	valueKind := reflect.TypeOf(value).Kind()
-	switch valueKind {
-	case reflect.String:
-		value2 := value.(string)
-		matched, policy := IsMatchGroupPolicy(ctxMap, appID, value2, models.ChkPointGetPostValue, "", true)
-		if matched == true {
-			return matched, policy
-		}
-	case reflect.Map:
-		value2 := value.(map[string]interface{})
-		for _, subValue := range value2 {
-			matched, policy := IsJSONValueHitPolicy(ctxMap, appID, subValue)
-			if matched == true {
-				return matched, policy
-			}
-		}
-	case reflect.Slice:
-		value2 := value.([]interface{})
-		for _, subValue := range value2 {
-			matched, policy := IsJSONValueHitPolicy(ctxMap, appID, subValue)
-			if matched == true {
-				return matched, policy
-			}
-		}
-	}
-	return false, nil
-}
@@ -0,0 +1,227 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 20:38:27
+ * @Last Modified: U2, 2026-10-19 20:38:27
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+var (
+	geoIPDB         *GeoIPDB
+	countryPolicies []*models.CountryPolicy
+	// countrySets map[int64]map[string]bool, compiled Countries of country policies
+	countrySets sync.Map
+)
+
+// InitGeoIP load the GeoIP database and country policies
+func InitGeoIP() {
+	if data.CFG != nil && data.CFG.GeoIP.Enabled &&
+		(geoIPDB == nil || !geoIPDB.IsSameFile(data.CFG.GeoIP.Database)) {
+		// InitFirewall is called again on slave nodes, reload only if the database file changed
+		db, err := OpenGeoIPDB(data.CFG.GeoIP.Database)
+		utils.CheckError("InitGeoIP OpenGeoIPDB", err)
+		if err == nil {
+			geoIPDB = db
+		}
+	}
+	if data.IsMaster {
+		data.DAL.CreateTableIfNotExistsCountryPolicies()
+		countryPolicies = data.DAL.SelectCountryPolicies()
+	} else {
+		countryPolicies = RPCSelectCountryPolicies()
+	}
+	for _, countryPolicy := range countryPolicies {
+		compileCountrySet(countryPolicy)
+	}
+}
+
+// GetCountryCode return the ISO country code of IP, such as US, empty if unknown
+func GetCountryCode(srcIP string) string {
+	// the cached country codes belong to the database instance, they are dropped together when reloaded
+	db := geoIPDB
+	if db == nil {
+		return ""
+	}
+	ip := net.ParseIP(srcIP)
+	if ip == nil {
+		return ""
+	}
+	offset, err := db.lookupOffset(ip)
+	if err != nil || offset < 0 {
+		return ""
+	}
+	if countryCode, ok := db.countryCodes.Load(offset); ok {
+		return countryCode.(string)
+	}
+	countryCode := ""
+	decoder := &mmdbDecoder{buffer: db.dataSection}
+	recordI, _, err := decoder.decode(uint(offset), 0)
+	if record, ok := recordI.(map[string]interface{}); ok && err == nil {
+		// registered_country is used for anonymous proxies and satellite providers
+		for _, field := range []string{"country", "registered_country"} {
+			if country, ok := record[field].(map[string]interface{}); ok {
+				if isoCode, ok := country["iso_code"].(string); ok {
+					countryCode = isoCode
+					break
+				}
+			}
+		}
+	}
+	db.countryCodes.Store(offset, countryCode)
+	return countryCode
+}
+
+func compileCountrySet(countryPolicy *models.CountryPolicy) {
+	countrySet := map[string]bool{}
+	for _, country := range strings.Split(countryPolicy.Countries, ",") {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if len(country) > 0 {
+			countrySet[country] = true
+		}
+	}
+	countrySets.Store(countryPolicy.ID, countrySet)
+}
+
+// GetCountryPolicies ...
+func GetCountryPolicies() ([]*models.CountryPolicy, error) {
+	return countryPolicies, nil
+}
+
+// GetCountryPolicyByID ...
+func GetCountryPolicyByID(id int64) (*models.CountryPolicy, error) {
+	for _, countryPolicy := range countryPolicies {
+		if countryPolicy.ID == id {
+			return countryPolicy, nil
+		}
+	}
+	return nil, errors.New("Not found")
+}
+
+// UpdateCountryPolicy ...
+func UpdateCountryPolicy(r *http.Request, userID int64) (*models.CountryPolicy, error) {
+	var setCountryPolicyRequest models.RPCSetCountryPolicy
+	err := json.NewDecoder(r.Body).Decode(&setCountryPolicyRequest)
+	defer r.Body.Close()
+	utils.CheckError("UpdateCountryPolicy Decode", err)
+	curPolicy := setCountryPolicyRequest.Object
+	if curPolicy == nil {
+		return nil, errors.New("UpdateCountryPolicy parse body null")
+	}
+	if curPolicy.ListType != models.IPList_Allow && curPolicy.ListType != models.IPList_Deny {
+		return nil, errors.New("list_type should be 1 (allow) or 2 (deny)")
+	}
+	curPolicy.Countries = strings.ToUpper(strings.Replace(curPolicy.Countries, " ", "", -1))
+	curPolicy.UserID = userID
+	curPolicy.UpdateTime = time.Now().Unix()
+	if curPolicy.ID == 0 {
+		newID, err := data.DAL.InsertCountryPolicy(curPolicy.AppID, curPolicy.ListType, curPolicy.Countries, curPolicy.IsEnabled, curPolicy.UserID, curPolicy.UpdateTime)
+		if err != nil {
+			return nil, err
+		}
+		curPolicy.ID = newID
+		countryPolicies = append(countryPolicies, curPolicy)
+	} else {
+		countryPolicy, err := GetCountryPolicyByID(curPolicy.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = data.DAL.UpdateCountryPolicy(curPolicy.AppID, curPolicy.ListType, curPolicy.Countries, curPolicy.IsEnabled, curPolicy.UserID, curPolicy.UpdateTime, curPolicy.ID)
+		if err != nil {
+			return nil, err
+		}
+		*countryPolicy = *curPolicy
+		curPolicy = countryPolicy
+	}
+	compileCountrySet(curPolicy)
+	data.UpdateFirewallLastModified()
+	return curPolicy, nil
+}
+
+// DeleteCountryPolicyByID ...
+func DeleteCountryPolicyByID(id int64) error {
+	for i, countryPolicy := range countryPolicies {
+		if countryPolicy.ID == id {
+			data.DAL.DeleteCountryPolicyByID(id)
+			countryPolicies = append(countryPolicies[:i], countryPolicies[i+1:]...)
+			countrySets.Delete(id)
+			data.UpdateFirewallLastModified()
+			return nil
+		}
+	}
+	return errors.New("Not found")
+}
+
+// IsCountryDenied check the country of client IP against the country policies of application and global,
+// IP with unknown country is not denied
+func IsCountryDenied(appID int64, srcIP string) (bool, *models.CountryPolicy) {
+	if geoIPDB == nil || len(countryPolicies) == 0 {
+		return false, nil
+	}
+	country := GetCountryCode(srcIP)
+	if len(country) == 0 {
+		return false, nil
+	}
+	for _, countryPolicy := range countryPolicies {
+		if !countryPolicy.IsEnabled || (countryPolicy.AppID != 0 && countryPolicy.AppID != appID) {
+			continue
+		}
+		countrySetI, ok := countrySets.Load(countryPolicy.ID)
+		if !ok {
+			continue
+		}
+		isListed := countrySetI.(map[string]bool)[country]
+		if (countryPolicy.ListType == models.IPList_Deny && isListed) ||
+			(countryPolicy.ListType == models.IPList_Allow && !isListed) {
+			return true, countryPolicy
+		}
+	}
+	return false, nil
+}
+
+// GetCountryStat count group hit logs by the country of client IP
+func GetCountryStat(param map[string]interface{}) ([]*models.CountryStat, error) {
+	appID := int64(param["app_id"].(float64))
+	startTime := int64(param["start_time"].(float64))
+	endTime := int64(param["end_time"].(float64))
+	countryCounts, err := getCountryCounts(appID, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	countryStat := []*models.CountryStat{}
+	for country, count := range countryCounts {
+		countryStat = append(countryStat, &models.CountryStat{Country: country, Count: count})
+	}
+	sort.Slice(countryStat, func(i, j int) bool {
+		return countryStat[i].Count > countryStat[j].Count
+	})
+	return countryStat, nil
+}
+
+// getCountryCounts return the count of each country, unknown country is counted as empty string
+func getCountryCounts(appID int64, startTime int64, endTime int64) (map[string]int64, error) {
+	ipStat, err := data.DAL.SelectIPStat(appID, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	countryCounts := map[string]int64{}
+	for _, stat := range ipStat {
+		countryCounts[GetCountryCode(stat.ClientIP)] += stat.Count
+	}
+	return countryCounts, nil
+}
@@ -0,0 +1,343 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 20:12:50
+ * @Last Modified: U2, 2026-10-19 20:12:50
+ */
+
+package firewall
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// mmdbMetadataMarker precedes the metadata at the end of MaxMind DB file
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+var errMMDBInvalid = errors.New("invalid MaxMind DB")
+
+// GeoIPDB is the reader of MaxMind DB format (GeoLite2/GeoIP2 Country or City)
+type GeoIPDB struct {
+	buffer      []byte
+	nodeCount   uint
+	recordSize  uint
+	ipVersion   uint
+	treeSize    uint
+	dataSection []byte
+	// ipv4Start is the node of ::/96 in IPv6 database
+	ipv4Start uint
+	// countryCodes map[int]string, offset of record in data section, ISO country code
+	countryCodes sync.Map
+	// path and modTime of the database file, used to skip reloading the same file
+	path    string
+	modTime time.Time
+}
+
+// OpenGeoIPDB load the whole database into memory
+func OpenGeoIPDB(path string) (*GeoIPDB, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	buffer, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := NewGeoIPDB(buffer)
+	if err != nil {
+		return nil, err
+	}
+	db.path = path
+	db.modTime = fileInfo.ModTime()
+	return db, nil
+}
+
+// IsSameFile return true if the database was loaded from path and the file is not modified
+func (db *GeoIPDB) IsSameFile(path string) bool {
+	if db.path != path {
+		return false
+	}
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return fileInfo.ModTime().Equal(db.modTime)
+}
+
+// NewGeoIPDB parse the metadata and search tree of database
+func NewGeoIPDB(buffer []byte) (*GeoIPDB, error) {
+	markerIndex := bytes.LastIndex(buffer, mmdbMetadataMarker)
+	if markerIndex < 0 {
+		return nil, errMMDBInvalid
+	}
+	metadataStart := markerIndex + len(mmdbMetadataMarker)
+	metadataDecoder := &mmdbDecoder{buffer: buffer[metadataStart:]}
+	metadataI, _, err := metadataDecoder.decode(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	metadata, ok := metadataI.(map[string]interface{})
+	if !ok {
+		return nil, errMMDBInvalid
+	}
+	db := &GeoIPDB{buffer: buffer}
+	db.nodeCount = mmdbUint(metadata["node_count"])
+	db.recordSize = mmdbUint(metadata["record_size"])
+	db.ipVersion = mmdbUint(metadata["ip_version"])
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, errors.New("unsupported MaxMind DB record size")
+	}
+	db.treeSize = db.nodeCount * db.recordSize / 4
+	dataStart := db.treeSize + 16
+	if dataStart > uint(markerIndex) {
+		return nil, errMMDBInvalid
+	}
+	db.dataSection = buffer[dataStart:markerIndex]
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			node = db.readNode(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+func mmdbUint(value interface{}) uint {
+	switch v := value.(type) {
+	case uint64:
+		return uint(v)
+	case uint32:
+		return uint(v)
+	case uint16:
+		return uint(v)
+	}
+	return 0
+}
+
+// readNode return the left (bit 0) or right (bit 1) record of node
+func (db *GeoIPDB) readNode(node uint, bit uint) uint {
+	offset := node * db.recordSize / 4
+	b := db.buffer[offset : offset+db.recordSize/4]
+	switch db.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:]))
+	}
+}
+
+// Lookup return the record of IP, nil if not found
+func (db *GeoIPDB) Lookup(ip net.IP) (interface{}, error) {
+	offset, err := db.lookupOffset(ip)
+	if err != nil || offset < 0 {
+		return nil, err
+	}
+	decoder := &mmdbDecoder{buffer: db.dataSection}
+	record, _, err := decoder.decode(uint(offset), 0)
+	return record, err
+}
+
+// lookupOffset return the offset of record in data section, -1 if not found
+func (db *GeoIPDB) lookupOffset(ip net.IP) (int, error) {
+	node := uint(0)
+	bitCount := 128
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+		bitCount = 32
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if db.ipVersion == 4 {
+		return -1, errors.New("IPv6 is not supported by IPv4 database")
+	}
+	for i := 0; i < bitCount && node < db.nodeCount; i++ {
+		bit := uint(ip[i/8]>>(7-uint(i%8))) & 1
+		node = db.readNode(node, bit)
+	}
+	if node == db.nodeCount {
+		return -1, nil
+	}
+	if node < db.nodeCount {
+		return -1, errMMDBInvalid
+	}
+	offset := node - db.nodeCount - 16
+	if offset >= uint(len(db.dataSection)) {
+		return -1, errMMDBInvalid
+	}
+	return int(offset), nil
+}
+
+// mmdbDecoder decode the data section of MaxMind DB
+type mmdbDecoder struct {
+	buffer []byte
+}
+
+const mmdbMaxDepth = 32
+
+// decode the value at offset, return the value and the offset after it
+func (decoder *mmdbDecoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, errMMDBInvalid
+	}
+	if offset >= uint(len(decoder.buffer)) {
+		return nil, 0, errMMDBInvalid
+	}
+	ctrl := decoder.buffer[offset]
+	offset++
+	dataType := uint(ctrl >> 5)
+	if dataType == 1 {
+		// pointer, the value is decoded at the target and the offset moves after the pointer
+		pointer, newOffset, err := decoder.decodePointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := decoder.decode(pointer, depth+1)
+		return value, newOffset, err
+	}
+	if dataType == 0 {
+		if offset >= uint(len(decoder.buffer)) {
+			return nil, 0, errMMDBInvalid
+		}
+		dataType = 7 + uint(decoder.buffer[offset])
+		offset++
+	}
+	size := uint(ctrl & 0x1F)
+	if size >= 29 {
+		extraBytes := size - 28
+		if offset+extraBytes > uint(len(decoder.buffer)) {
+			return nil, 0, errMMDBInvalid
+		}
+		extra := uint(0)
+		for _, b := range decoder.buffer[offset : offset+extraBytes] {
+			extra = extra<<8 | uint(b)
+		}
+		offset += extraBytes
+		switch size {
+		case 29:
+			size = 29 + extra
+		case 30:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+	switch dataType {
+	case 7:
+		// map
+		// the size is read from file, each pair takes 2 bytes at least
+		result := make(map[string]interface{}, minUint(size, (uint(len(decoder.buffer))-offset)/2))
+		for i := uint(0); i < size; i++ {
+			keyI, newOffset, err := decoder.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			key, ok := keyI.(string)
+			if !ok {
+				return nil, 0, errMMDBInvalid
+			}
+			value, newOffset, err := decoder.decode(newOffset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			result[key] = value
+			offset = newOffset
+		}
+		return result, offset, nil
+	case 11:
+		// array
+		// the size is read from file, each item takes 1 byte at least
+		result := make([]interface{}, 0, minUint(size, uint(len(decoder.buffer))-offset))
+		for i := uint(0); i < size; i++ {
+			value, newOffset, err := decoder.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			result = append(result, value)
+			offset = newOffset
+		}
+		return result, offset, nil
+	case 14:
+		// boolean, the value is the size
+		return size != 0, offset, nil
+	}
+	if offset+size > uint(len(decoder.buffer)) {
+		return nil, 0, errMMDBInvalid
+	}
+	content := decoder.buffer[offset : offset+size]
+	offset += size
+	switch dataType {
+	case 2:
+		return string(content), offset, nil
+	case 3:
+		if size != 8 {
+			return nil, 0, errMMDBInvalid
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(content)), offset, nil
+	case 4:
+		return content, offset, nil
+	case 5, 6, 9:
+		value := uint64(0)
+		for _, b := range content {
+			value = value<<8 | uint64(b)
+		}
+		return value, offset, nil
+	case 8:
+		value := uint32(0)
+		for _, b := range content {
+			value = value<<8 | uint32(b)
+		}
+		return int32(value), offset, nil
+	case 10:
+		return new(big.Int).SetBytes(content), offset, nil
+	case 15:
+		if size != 4 {
+			return nil, 0, errMMDBInvalid
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(content)), offset, nil
+	}
+	// data cache container, end marker and unknown types
+	return nil, offset, nil
+}
+
+func minUint(a uint, b uint) uint {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (decoder *mmdbDecoder) decodePointer(ctrl byte, offset uint) (uint, uint, error) {
+	pointerSize := uint((ctrl>>3)&0x3) + 1
+	if offset+pointerSize > uint(len(decoder.buffer)) {
+		return 0, 0, errMMDBInvalid
+	}
+	b := decoder.buffer[offset : offset+pointerSize]
+	var pointer uint
+	switch pointerSize {
+	case 1:
+		pointer = uint(ctrl&0x7)<<8 | uint(b[0])
+	case 2:
+		pointer = (uint(ctrl&0x7)<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 3:
+		pointer = (uint(ctrl&0x7)<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	default:
+		pointer = uint(binary.BigEndian.Uint32(b))
+	}
+	return pointer, offset + pointerSize, nil
+}
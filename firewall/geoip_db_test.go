@@ -0,0 +1,223 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 16:05:31
+ * @Last Modified: U2, 2026-10-20 16:05:31
+ */
+
+package firewall
+
+import (
+	"net"
+	"testing"
+)
+
+// testMMDBNode is a node of the search tree, a leaf if data >= 0
+type testMMDBNode struct {
+	children [2]*testMMDBNode
+	data     int
+}
+
+func mmdbString(s string) []byte {
+	return append([]byte{byte(2<<5 | len(s))}, s...)
+}
+
+func mmdbMap(size int) []byte {
+	return []byte{byte(7<<5 | size)}
+}
+
+func mmdbUint32(value uint32) []byte {
+	return []byte{6<<5 | 4, byte(value >> 24), byte(value >> 16), byte(value >> 8), byte(value)}
+}
+
+func mmdbUint16(value uint16) []byte {
+	return []byte{5<<5 | 2, byte(value >> 8), byte(value)}
+}
+
+func mmdbPointer(pointer int) []byte {
+	return []byte{byte(1<<5 | pointer>>8&0x7), byte(pointer)}
+}
+
+// buildTestMMDB generate a MaxMind DB with record size 24, networks map CIDR to offset in data section
+func buildTestMMDB(t *testing.T, ipVersion int, networks map[string]int, dataSection []byte) []byte {
+	root := &testMMDBNode{data: -1}
+	for cidr, offset := range networks {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ip := ipNet.IP.To16()
+		prefixLen, _ := ipNet.Mask.Size()
+		if ipVersion == 6 && len(ipNet.Mask) == net.IPv4len {
+			// IPv4 networks are stored in ::/96 of IPv6 database
+			ip = append(make(net.IP, 12), ipNet.IP.To4()...)
+			prefixLen += 96
+		} else if ipVersion == 4 {
+			ip = ipNet.IP.To4()
+		}
+		node := root
+		for i := 0; i < prefixLen; i++ {
+			bit := ip[i/8] >> (7 - uint(i%8)) & 1
+			if node.children[bit] == nil {
+				node.children[bit] = &testMMDBNode{data: -1}
+			}
+			node = node.children[bit]
+		}
+		node.data = offset
+	}
+	var nodes []*testMMDBNode
+	index := map[*testMMDBNode]int{}
+	var walk func(node *testMMDBNode)
+	walk = func(node *testMMDBNode) {
+		index[node] = len(nodes)
+		nodes = append(nodes, node)
+		for _, child := range node.children {
+			if child != nil && child.data < 0 {
+				walk(child)
+			}
+		}
+	}
+	walk(root)
+	nodeCount := len(nodes)
+	var buffer []byte
+	for _, node := range nodes {
+		for _, child := range node.children {
+			record := nodeCount
+			if child != nil && child.data >= 0 {
+				record = nodeCount + 16 + child.data
+			} else if child != nil {
+				record = index[child]
+			}
+			buffer = append(buffer, byte(record>>16), byte(record>>8), byte(record))
+		}
+	}
+	buffer = append(buffer, make([]byte, 16)...)
+	buffer = append(buffer, dataSection...)
+	buffer = append(buffer, mmdbMetadataMarker...)
+	buffer = append(buffer, mmdbMap(3)...)
+	buffer = append(buffer, mmdbString("node_count")...)
+	buffer = append(buffer, mmdbUint32(uint32(nodeCount))...)
+	buffer = append(buffer, mmdbString("record_size")...)
+	buffer = append(buffer, mmdbUint16(24)...)
+	buffer = append(buffer, mmdbString("ip_version")...)
+	buffer = append(buffer, mmdbUint16(uint16(ipVersion))...)
+	return buffer
+}
+
+// testMMDBData return the data section and offsets of records US, DE and a pointer to US country
+func testMMDBData() ([]byte, int, int, int) {
+	var dataSection []byte
+	usOffset := len(dataSection)
+	dataSection = append(dataSection, mmdbMap(1)...)
+	dataSection = append(dataSection, mmdbString("country")...)
+	usCountryOffset := len(dataSection)
+	dataSection = append(dataSection, mmdbMap(1)...)
+	dataSection = append(dataSection, mmdbString("iso_code")...)
+	dataSection = append(dataSection, mmdbString("US")...)
+	deOffset := len(dataSection)
+	dataSection = append(dataSection, mmdbMap(1)...)
+	dataSection = append(dataSection, mmdbString("country")...)
+	dataSection = append(dataSection, mmdbMap(1)...)
+	dataSection = append(dataSection, mmdbString("iso_code")...)
+	dataSection = append(dataSection, mmdbString("DE")...)
+	pointerOffset := len(dataSection)
+	dataSection = append(dataSection, mmdbMap(1)...)
+	dataSection = append(dataSection, mmdbString("registered_country")...)
+	dataSection = append(dataSection, mmdbPointer(usCountryOffset)...)
+	return dataSection, usOffset, deOffset, pointerOffset
+}
+
+func TestGeoIPDBLookup(t *testing.T) {
+	dataSection, usOffset, deOffset, pointerOffset := testMMDBData()
+	for _, ipVersion := range []int{4, 6} {
+		networks := map[string]int{
+			"1.2.3.0/24": usOffset,
+			"5.6.0.0/16": pointerOffset,
+		}
+		if ipVersion == 6 {
+			networks["2001:db8::/32"] = deOffset
+		}
+		db, err := NewGeoIPDB(buildTestMMDB(t, ipVersion, networks, dataSection))
+		if err != nil {
+			t.Fatalf("ip_version %d: %v", ipVersion, err)
+		}
+		geoIPDB = db
+		tests := []struct {
+			ip          string
+			countryCode string
+		}{
+			{"1.2.3.4", "US"},
+			{"1.2.4.4", ""},
+			{"5.6.7.8", "US"},
+			{"8.8.8.8", ""},
+		}
+		if ipVersion == 6 {
+			tests = append(tests, []struct {
+				ip          string
+				countryCode string
+			}{
+				{"2001:db8::1", "DE"},
+				{"2001:db9::1", ""},
+			}...)
+		}
+		for _, test := range tests {
+			// twice, the second one is from cache
+			for i := 0; i < 2; i++ {
+				if countryCode := GetCountryCode(test.ip); countryCode != test.countryCode {
+					t.Errorf("ip_version %d: GetCountryCode(%s) = %q, want %q", ipVersion, test.ip, countryCode, test.countryCode)
+				}
+			}
+		}
+		record, err := db.Lookup(net.ParseIP("5.6.7.8"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		country := record.(map[string]interface{})["registered_country"].(map[string]interface{})
+		if country["iso_code"] != "US" {
+			t.Errorf("ip_version %d: pointer record = %v", ipVersion, record)
+		}
+		if ipVersion == 4 {
+			if _, err := db.Lookup(net.ParseIP("2001:db8::1")); err == nil {
+				t.Error("IPv6 lookup in IPv4 database should fail")
+			}
+		}
+	}
+	geoIPDB = nil
+}
+
+func TestGeoIPDBCorrupt(t *testing.T) {
+	dataSection, usOffset, _, _ := testMMDBData()
+	buffer := buildTestMMDB(t, 4, map[string]int{"1.2.3.0/24": usOffset}, dataSection)
+	// truncated file without metadata
+	if _, err := NewGeoIPDB(buffer[:len(buffer)/2]); err == nil {
+		t.Error("truncated database should fail")
+	}
+	// node_count larger than the file
+	corrupt := append([]byte{}, buffer...)
+	nodeCountIndex := len(corrupt) - len(mmdbUint16(4)) - len(mmdbString("ip_version")) -
+		len(mmdbUint16(24)) - len(mmdbString("record_size")) - 4
+	corrupt[nodeCountIndex] = 0xFF
+	if _, err := NewGeoIPDB(corrupt); err == nil {
+		t.Error("database with invalid node_count should fail")
+	}
+	// the record points out of the data section
+	corrupt = buildTestMMDB(t, 4, map[string]int{"1.2.3.0/24": len(dataSection) + 100}, dataSection)
+	db, err := NewGeoIPDB(corrupt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Lookup(net.ParseIP("1.2.3.4")); err == nil {
+		t.Error("record out of data section should fail")
+	}
+	// huge sizes of map and array are rejected without large allocation
+	for _, value := range [][]byte{
+		{7<<5 | 31, 0xFF, 0xFF, 0xFF},
+		{0<<5 | 31, 11 - 7, 0xFF, 0xFF, 0xFF},
+		{mmdbPointer(0)[0], mmdbPointer(0)[1]},
+	} {
+		decoder := &mmdbDecoder{buffer: value}
+		if _, _, err := decoder.decode(0, 0); err == nil {
+			t.Errorf("decode(%x) should fail", value)
+		}
+	}
+}
@@ -0,0 +1,600 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 14:36:52
+ * @Last Modified: U2, 2026-10-19 14:36:52
+ */
+
+package firewall
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Janusec/janusec/models"
+)
+
+const (
+	// graphQLMaxNesting limits the recursion of parser, a deeper query is reported with this depth
+	graphQLMaxNesting = 512
+	// graphQLMaxComplexity is the cap of computed complexity
+	graphQLMaxComplexity int64 = 1 << 40
+)
+
+var (
+	errGraphQLSyntax  = errors.New("graphql syntax error")
+	errGraphQLTooDeep = errors.New("graphql nesting too deep")
+
+	// graphQLListArguments multiply the complexity of sub selections
+	graphQLListArguments = map[string]bool{"first": true, "last": true, "limit": true}
+)
+
+// graphQLSelection is a field, a fragment spread or an inline fragment
+type graphQLSelection struct {
+	name       string
+	spread     string
+	multiplier int64
+	children   []*graphQLSelection
+}
+
+// graphQLArgument is a string literal in arguments, name is the nearest argument or object field name
+type graphQLArgument struct {
+	name  string
+	value string
+}
+
+// GraphQLDocument is the result of parsing a GraphQL query
+type GraphQLDocument struct {
+	operations [][]*graphQLSelection
+	fragments  map[string][]*graphQLSelection
+	fields     []string
+	arguments  []*graphQLArgument
+	depths     map[string]int64
+	costs      map[string]int64
+}
+
+type graphQLToken struct {
+	kind  byte // n: name, s: string, d: number, p: punctuator
+	value string
+}
+
+type graphQLParser struct {
+	tokens  []*graphQLToken
+	pos     int
+	nesting int
+	doc     *GraphQLDocument
+}
+
+func tokenizeGraphQL(query string) ([]*graphQLToken, error) {
+	var tokens []*graphQLToken
+	query = strings.TrimPrefix(query, "\xEF\xBB\xBF")
+	i := 0
+	for i < len(query) {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(query) && query[i] != '\n' && query[i] != '\r' {
+				i++
+			}
+		case c == '.':
+			if !strings.HasPrefix(query[i:], "...") {
+				return nil, errGraphQLSyntax
+			}
+			tokens = append(tokens, &graphQLToken{kind: 'p', value: "..."})
+			i += 3
+		case strings.IndexByte("!$&(){}[]:=@|", c) >= 0:
+			tokens = append(tokens, &graphQLToken{kind: 'p', value: string(c)})
+			i++
+		case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+			start := i
+			for i < len(query) && (query[i] == '_' || (query[i] >= 'a' && query[i] <= 'z') || (query[i] >= 'A' && query[i] <= 'Z') || (query[i] >= '0' && query[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, &graphQLToken{kind: 'n', value: query[start:i]})
+		case c == '-' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(query) && strings.IndexByte("0123456789.eE+-", query[i]) >= 0 {
+				i++
+			}
+			tokens = append(tokens, &graphQLToken{kind: 'd', value: query[start:i]})
+		case c == '"':
+			if strings.HasPrefix(query[i:], `"""`) {
+				end := strings.Index(query[i+3:], `"""`)
+				for end >= 0 && query[i+3+end-1] == '\\' {
+					next := strings.Index(query[i+3+end+1:], `"""`)
+					if next < 0 {
+						end = -1
+						break
+					}
+					end += next + 1
+				}
+				if end < 0 {
+					return nil, errGraphQLSyntax
+				}
+				tokens = append(tokens, &graphQLToken{kind: 's', value: query[i+3 : i+3+end]})
+				i += end + 6
+				continue
+			}
+			var value strings.Builder
+			i++
+			for i < len(query) && query[i] != '"' {
+				if query[i] == '\\' && i+1 < len(query) {
+					i++
+					switch query[i] {
+					case 'n':
+						value.WriteByte('\n')
+					case 't':
+						value.WriteByte('\t')
+					case 'r':
+						value.WriteByte('\r')
+					case 'u':
+						if i+4 < len(query) {
+							if code, err := strconv.ParseUint(query[i+1:i+5], 16, 32); err == nil {
+								value.WriteRune(rune(code))
+								i += 4
+								break
+							}
+						}
+						value.WriteByte(query[i])
+					default:
+						value.WriteByte(query[i])
+					}
+				} else {
+					value.WriteByte(query[i])
+				}
+				i++
+			}
+			if i >= len(query) {
+				return nil, errGraphQLSyntax
+			}
+			tokens = append(tokens, &graphQLToken{kind: 's', value: value.String()})
+			i++
+		default:
+			return nil, errGraphQLSyntax
+		}
+	}
+	return tokens, nil
+}
+
+// ParseGraphQL parse the query, errGraphQLTooDeep is returned with the partial document
+func ParseGraphQL(query string) (*GraphQLDocument, error) {
+	tokens, err := tokenizeGraphQL(query)
+	if err != nil {
+		return nil, err
+	}
+	parser := &graphQLParser{tokens: tokens,
+		doc: &GraphQLDocument{
+			fragments: map[string][]*graphQLSelection{},
+			depths:    map[string]int64{},
+			costs:     map[string]int64{}}}
+	for parser.peek() != nil {
+		if err := parser.parseDefinition(); err == errGraphQLTooDeep {
+			return parser.doc, err
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if len(parser.doc.operations) == 0 {
+		return nil, errGraphQLSyntax
+	}
+	return parser.doc, nil
+}
+
+func (parser *graphQLParser) peek() *graphQLToken {
+	if parser.pos < len(parser.tokens) {
+		return parser.tokens[parser.pos]
+	}
+	return nil
+}
+
+func (parser *graphQLParser) next() *graphQLToken {
+	token := parser.peek()
+	if token != nil {
+		parser.pos++
+	}
+	return token
+}
+
+func (parser *graphQLParser) isPunctuator(value string) bool {
+	token := parser.peek()
+	return token != nil && token.kind == 'p' && token.value == value
+}
+
+func (parser *graphQLParser) expect(kind byte, value string) (*graphQLToken, error) {
+	token := parser.next()
+	if token == nil || token.kind != kind || (len(value) > 0 && token.value != value) {
+		return nil, errGraphQLSyntax
+	}
+	return token, nil
+}
+
+func (parser *graphQLParser) parseDefinition() error {
+	if parser.isPunctuator("{") {
+		selections, err := parser.parseSelectionSet()
+		parser.doc.operations = append(parser.doc.operations, selections)
+		return err
+	}
+	token, err := parser.expect('n', "")
+	if err != nil {
+		return err
+	}
+	switch token.value {
+	case "query", "mutation", "subscription":
+		if token := parser.peek(); token != nil && token.kind == 'n' {
+			parser.next()
+		}
+		if parser.isPunctuator("(") {
+			// variable definitions, default values are inspected as arguments
+			if err := parser.parseArguments(""); err != nil {
+				return err
+			}
+		}
+		if err := parser.parseDirectives(); err != nil {
+			return err
+		}
+		selections, err := parser.parseSelectionSet()
+		parser.doc.operations = append(parser.doc.operations, selections)
+		return err
+	case "fragment":
+		name, err := parser.expect('n', "")
+		if err != nil {
+			return err
+		}
+		if _, err := parser.expect('n', "on"); err != nil {
+			return err
+		}
+		if _, err := parser.expect('n', ""); err != nil {
+			return err
+		}
+		if err := parser.parseDirectives(); err != nil {
+			return err
+		}
+		selections, err := parser.parseSelectionSet()
+		parser.doc.fragments[name.value] = selections
+		return err
+	}
+	return errGraphQLSyntax
+}
+
+func (parser *graphQLParser) parseSelectionSet() ([]*graphQLSelection, error) {
+	if _, err := parser.expect('p', "{"); err != nil {
+		return nil, err
+	}
+	parser.nesting++
+	defer func() { parser.nesting-- }()
+	if parser.nesting > graphQLMaxNesting {
+		return nil, errGraphQLTooDeep
+	}
+	var selections []*graphQLSelection
+	for !parser.isPunctuator("}") {
+		selection, err := parser.parseSelection()
+		if selection != nil {
+			selections = append(selections, selection)
+		}
+		if err != nil {
+			return selections, err
+		}
+	}
+	parser.next()
+	return selections, nil
+}
+
+func (parser *graphQLParser) parseSelection() (*graphQLSelection, error) {
+	if parser.isPunctuator("...") {
+		parser.next()
+		if token := parser.peek(); token != nil && token.kind == 'n' && token.value != "on" {
+			parser.next()
+			return &graphQLSelection{spread: token.value}, parser.parseDirectives()
+		}
+		if token := parser.peek(); token != nil && token.kind == 'n' {
+			// on Type
+			parser.next()
+			if _, err := parser.expect('n', ""); err != nil {
+				return nil, err
+			}
+		}
+		if err := parser.parseDirectives(); err != nil {
+			return nil, err
+		}
+		children, err := parser.parseSelectionSet()
+		return &graphQLSelection{children: children}, err
+	}
+	token, err := parser.expect('n', "")
+	if err != nil {
+		return nil, err
+	}
+	selection := &graphQLSelection{name: token.value, multiplier: 1}
+	if parser.isPunctuator(":") {
+		// alias
+		parser.next()
+		if token, err = parser.expect('n', ""); err != nil {
+			return nil, err
+		}
+		selection.name = token.value
+	}
+	parser.doc.fields = append(parser.doc.fields, selection.name)
+	if parser.isPunctuator("(") {
+		start := len(parser.doc.arguments)
+		if err := parser.parseArguments(""); err != nil {
+			return nil, err
+		}
+		for _, arg := range parser.doc.arguments[start:] {
+			if graphQLListArguments[arg.name] {
+				if count, err := strconv.ParseInt(arg.value, 10, 64); err == nil && count > 1 {
+					selection.multiplier = count
+				}
+			}
+		}
+	}
+	if err := parser.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if parser.isPunctuator("{") {
+		selection.children, err = parser.parseSelectionSet()
+	}
+	return selection, err
+}
+
+func (parser *graphQLParser) parseDirectives() error {
+	for parser.isPunctuator("@") {
+		parser.next()
+		if _, err := parser.expect('n', ""); err != nil {
+			return err
+		}
+		if parser.isPunctuator("(") {
+			if err := parser.parseArguments(""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseArguments parse (name: value ...) or {name: value ...} and variable definitions
+func (parser *graphQLParser) parseArguments(closing string) error {
+	open := parser.next()
+	if closing == "" {
+		closing = ")"
+		if open.value == "{" {
+			closing = "}"
+		}
+	}
+	parser.nesting++
+	defer func() { parser.nesting-- }()
+	if parser.nesting > graphQLMaxNesting {
+		return errGraphQLTooDeep
+	}
+	for !parser.isPunctuator(closing) {
+		name := ""
+		if parser.isPunctuator("$") {
+			parser.next()
+		}
+		token, err := parser.expect('n', "")
+		if err != nil {
+			return err
+		}
+		name = token.value
+		if _, err := parser.expect('p', ":"); err != nil {
+			return err
+		}
+		if err := parser.parseValue(name); err != nil {
+			return err
+		}
+		if parser.isPunctuator("=") {
+			// default value of variable
+			parser.next()
+			if err := parser.parseValue(name); err != nil {
+				return err
+			}
+		}
+		if err := parser.parseDirectives(); err != nil {
+			return err
+		}
+	}
+	parser.next()
+	return nil
+}
+
+// parseValue parse a value or a variable type, string and number literals are recorded
+func (parser *graphQLParser) parseValue(name string) error {
+	token := parser.peek()
+	if token == nil {
+		return errGraphQLSyntax
+	}
+	switch {
+	case token.kind == 's' || token.kind == 'd':
+		parser.next()
+		parser.doc.arguments = append(parser.doc.arguments, &graphQLArgument{name: name, value: token.value})
+	case token.kind == 'n':
+		parser.next()
+	case token.value == "$":
+		parser.next()
+		if _, err := parser.expect('n', ""); err != nil {
+			return err
+		}
+	case token.value == "{":
+		return parser.parseArguments("}")
+	case token.value == "[":
+		parser.next()
+		parser.nesting++
+		defer func() { parser.nesting-- }()
+		if parser.nesting > graphQLMaxNesting {
+			return errGraphQLTooDeep
+		}
+		for !parser.isPunctuator("]") {
+			if err := parser.parseValue(name); err != nil {
+				return err
+			}
+		}
+		parser.next()
+	default:
+		return errGraphQLSyntax
+	}
+	if parser.isPunctuator("!") {
+		// non-null variable type
+		parser.next()
+	}
+	return nil
+}
+
+// Depth return the max nesting depth of fields, fragments are expanded
+func (doc *GraphQLDocument) Depth() int64 {
+	var maxDepth int64
+	for _, selections := range doc.operations {
+		if depth := doc.selectionsDepth(selections, 0); depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+	return maxDepth
+}
+
+func (doc *GraphQLDocument) selectionsDepth(selections []*graphQLSelection, nesting int) int64 {
+	if nesting > graphQLMaxNesting {
+		return graphQLMaxNesting
+	}
+	var maxDepth int64
+	for _, selection := range selections {
+		var depth int64
+		switch {
+		case len(selection.spread) > 0:
+			depth = doc.fragmentValue(doc.depths, selection.spread, nesting, doc.selectionsDepth)
+		case len(selection.name) > 0:
+			depth = 1 + doc.selectionsDepth(selection.children, nesting+1)
+		default:
+			depth = doc.selectionsDepth(selection.children, nesting+1)
+		}
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+	return maxDepth
+}
+
+// Complexity return the count of fields, fragments are expanded and lists are multiplied by first/last/limit
+func (doc *GraphQLDocument) Complexity() int64 {
+	var complexity int64
+	for _, selections := range doc.operations {
+		complexity = saturatingAdd(complexity, doc.selectionsCost(selections, 0))
+	}
+	return complexity
+}
+
+func (doc *GraphQLDocument) selectionsCost(selections []*graphQLSelection, nesting int) int64 {
+	if nesting > graphQLMaxNesting {
+		return graphQLMaxComplexity
+	}
+	var cost int64
+	for _, selection := range selections {
+		switch {
+		case len(selection.spread) > 0:
+			cost = saturatingAdd(cost, doc.fragmentValue(doc.costs, selection.spread, nesting, doc.selectionsCost))
+		case len(selection.name) > 0:
+			childrenCost := doc.selectionsCost(selection.children, nesting+1)
+			if childrenCost > graphQLMaxComplexity/selection.multiplier {
+				childrenCost = graphQLMaxComplexity
+			} else {
+				childrenCost *= selection.multiplier
+			}
+			cost = saturatingAdd(cost, saturatingAdd(1, childrenCost))
+		default:
+			cost = saturatingAdd(cost, doc.selectionsCost(selection.children, nesting+1))
+		}
+	}
+	return cost
+}
+
+// fragmentValue memoize the depth or cost of a fragment, a cyclic spread is counted as 0
+func (doc *GraphQLDocument) fragmentValue(cache map[string]int64, name string, nesting int,
+	compute func([]*graphQLSelection, int) int64) int64 {
+	if value, ok := cache[name]; ok {
+		return value
+	}
+	selections, ok := doc.fragments[name]
+	if !ok {
+		return 0
+	}
+	cache[name] = 0
+	value := compute(selections, nesting+1)
+	cache[name] = value
+	return value
+}
+
+func saturatingAdd(a int64, b int64) int64 {
+	if a+b > graphQLMaxComplexity || a+b < 0 {
+		return graphQLMaxComplexity
+	}
+	return a + b
+}
+
+// GetGraphQLQueries return the queries in GET query string or JSON body (including batch),
+// nil if the request is not a GraphQL request
+func GetGraphQLQueries(r *http.Request, mediaType string, jsonParams interface{}, body []byte) []string {
+	var queries []string
+	switch {
+	case mediaType == "application/graphql":
+		queries = append(queries, string(body))
+	case jsonParams != nil:
+		requests, ok := jsonParams.([]interface{})
+		if !ok {
+			requests = []interface{}{jsonParams}
+		}
+		for _, request := range requests {
+			if obj, ok := request.(map[string]interface{}); ok {
+				if query, ok := obj["query"].(string); ok {
+					queries = append(queries, query)
+				}
+			}
+		}
+	case strings.Contains(strings.ToLower(r.URL.Path), "graphql"):
+		queries = r.URL.Query()["query"]
+	}
+	return queries
+}
+
+// IsGraphQLHitPolicy check depth, complexity, field names and argument literals of a GraphQL query,
+// the query which is not valid GraphQL is ignored
+func IsGraphQLHitPolicy(ctxMap *sync.Map, appID int64, query string) (bool, *models.GroupPolicy) {
+	doc, err := ParseGraphQL(query)
+	if doc == nil {
+		return false, nil
+	}
+	// ChkPoint_GraphQLDepth
+	depth := int64(graphQLMaxNesting)
+	if err != errGraphQLTooDeep {
+		depth = doc.Depth()
+	}
+	matched, policy := IsMatchGroupPolicy(ctxMap, appID, strconv.FormatInt(depth, 10), models.ChkPointGraphQLDepth, "", false)
+	if matched == true {
+		return matched, policy
+	}
+	if err != nil {
+		return false, nil
+	}
+	// ChkPoint_GraphQLComplexity
+	complexity := strconv.FormatInt(doc.Complexity(), 10)
+	matched, policy = IsMatchGroupPolicy(ctxMap, appID, complexity, models.ChkPointGraphQLComplexity, "", false)
+	if matched == true {
+		return matched, policy
+	}
+	// ChkPoint_GraphQLField, such as __schema and __type for introspection
+	for _, field := range doc.fields {
+		matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, field, models.ChkPointGraphQLField, "", field, false)
+		if matched == true {
+			return matched, policy
+		}
+	}
+	// Arguments are inspected as GET/POST values
+	for _, arg := range doc.arguments {
+		matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, arg.value, models.ChkPointGetPostValue, "", arg.name, true)
+		if matched == true {
+			return matched, policy
+		}
+	}
+	return false, nil
+}
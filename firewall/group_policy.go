@@ -125,12 +125,73 @@ func InitGroupPolicy() {
 			_, err = data.DAL.InsertCheckItem(models.ChkPointURLQuery, models.OperationRegexMatch, "", `(?i)(onmouseover|onerror|onload|onclick)\s*=`, groupPolicyID)
 			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
 
+			// Lexical Detection
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("SQL Injection Lexical Detection", 0, 200, int64(models.ChkPointGetPostValue), models.Action_Block_100, true, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointGetPostValue, models.OperationSQLInjection, "", "", groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("XSS Lexical Detection", 0, 300, int64(models.ChkPointGetPostValue), models.Action_Block_100, true, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointGetPostValue, models.OperationXSS, "", "", groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
 			// Path Traversal
 			groupPolicyID, err = data.DAL.InsertGroupPolicy("Basic Path Traversal", 0, 400, int64(models.ChkPointURLQuery), models.Action_Block_100, true, 0, curTime)
 			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
 			_, err = data.DAL.InsertCheckItem(models.ChkPointURLQuery, models.OperationRegexMatch, "", `\.\./\.\./|/etc/passwd$`, groupPolicyID)
 			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
 
+			// Upload Inspection
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("Web Shell Upload", 0, 500, int64(models.ChkPointUploadContent), models.Action_Block_100, true, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointUploadContent, models.OperationRegexMatch, "", `(?i)(eval|assert|system|exec|passthru|shell_exec|popen|proc_open|create_function)\s*\(\s*(@?\$_(GET|POST|REQUEST|COOKIE|SERVER)|base64_decode|gzinflate|gzuncompress|str_rot13)|Runtime\.getRuntime\(\)\.exec\(|<%@\s*Page\s+Language\s*=\s*"?Jscript|<%\s*eval\s*\(?\s*request|<script\s+runat\s*=\s*"?server`, groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("Server Script Upload", 0, 510, int64(models.ChkPointUploadFileType), models.Action_Block_100, true, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointUploadFileType, models.OperationRegexMatch, "", `^text/x-(php|server-page)$`, groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
+			// Type mismatch may be caused by clients, disabled by default
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("Upload Type Mismatch", 0, 510, int64(models.ChkPointUploadTypeMismatch), models.Action_Block_100, false, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointUploadTypeMismatch, models.OperationRegexMatch, "", `.`, groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("Virus Upload", 0, 510, int64(models.ChkPointUploadVirus), models.Action_Block_100, true, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointUploadVirus, models.OperationRegexMatch, "", `.`, groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
+			// XML External Entity
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("XML External Entity", 0, 960, int64(models.ChkPointXMLDoctype), models.Action_Block_100, true, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointXMLDoctype, models.OperationRegexMatch, "", `(?i)<!ENTITY\s+(%\s+)?\S+\s+(SYSTEM|PUBLIC)\s`, groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("XML Entity Expansion", 0, 960, int64(models.ChkPointXMLEntityExpansion), models.Action_Block_100, true, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointXMLEntityExpansion, models.OperationGreaterThanInteger, "", "100000", groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
+			// GraphQL
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("GraphQL Query Depth Limit", 0, 999, int64(models.ChkPointGraphQLDepth), models.Action_Block_100, true, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointGraphQLDepth, models.OperationGreaterThanInteger, "", "15", groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("GraphQL Query Complexity Limit", 0, 999, int64(models.ChkPointGraphQLComplexity), models.Action_Block_100, true, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointGraphQLComplexity, models.OperationGreaterThanInteger, "", "10000", groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
+			// Introspection is often used by development tools, disabled by default
+			groupPolicyID, err = data.DAL.InsertGroupPolicy("GraphQL Introspection", 0, 940, int64(models.ChkPointGraphQLField), models.Action_Block_100, false, 0, curTime)
+			utils.CheckError("InitGroupPolicy InsertGroupPolicy", err)
+			_, err = data.DAL.InsertCheckItem(models.ChkPointGraphQLField, models.OperationRegexMatch, "", `^__(schema|type)$`, groupPolicyID)
+			utils.CheckError("InitGroupPolicy InsertCheckItem", err)
+
 		}
 		// Load Policies
 		dbGroupPolicies = data.DAL.SelectGroupPolicies()
@@ -237,6 +298,20 @@ func UpdateGroupPolicy(r *http.Request, userID int64) (*models.GroupPolicy, erro
 
 // IsMatchGroupPolicy ...
 func IsMatchGroupPolicy(hitValueMap *sync.Map, appID int64, value string, checkPoint models.ChkPoint, designatedKey string, needDecode bool) (bool, *models.GroupPolicy) {
+	return IsMatchGroupPolicyByParam(hitValueMap, appID, value, checkPoint, designatedKey, "", needDecode)
+}
+
+// IsMatchGroupPolicyByParam paramName is the name of parameter, cookie or header, used for policy exceptions
+func IsMatchGroupPolicyByParam(hitValueMap *sync.Map, appID int64, value string, checkPoint models.ChkPoint, designatedKey string, paramName string, needDecode bool) (bool, *models.GroupPolicy) {
+	return isMatchGroupPolicy(hitValueMap, appID, value, checkPoint, designatedKey, "", paramName, needDecode)
+}
+
+// IsMatchGroupPolicyByJSONPath the check item with KeyName is only applied to the matched JSON path or key
+func IsMatchGroupPolicyByJSONPath(hitValueMap *sync.Map, appID int64, value string, checkPoint models.ChkPoint, jsonPath string, paramName string, needDecode bool) (bool, *models.GroupPolicy) {
+	return isMatchGroupPolicy(hitValueMap, appID, value, checkPoint, "", jsonPath, paramName, needDecode)
+}
+
+func isMatchGroupPolicy(hitValueMap *sync.Map, appID int64, value string, checkPoint models.ChkPoint, designatedKey string, jsonPath string, paramName string, needDecode bool) (bool, *models.GroupPolicy) {
 	if len(value) == 0 {
 		return false, nil
 	}
@@ -246,8 +321,11 @@ func IsMatchGroupPolicy(hitValueMap *sync.Map, appID int64, value string, checkP
 	}
 	//fmt.Println("IsMatchGroupPolicy checkpoint:", check_point)
 	checkItems := checkItemsMap.([]*models.CheckItem)
+	// lexical detectors need quotes, so keep the value before PreProcessString
+	lexicalValue := value
 	if needDecode {
-		value = UnEscapeRawValue(value)
+		lexicalValue = DecodeRawValue(value)
+		value = PreProcessString(lexicalValue)
 	}
 	for _, checkItem := range checkItems {
 		groupPolicy := checkItem.GroupPolicy
@@ -258,6 +336,12 @@ func IsMatchGroupPolicy(hitValueMap *sync.Map, appID int64, value string, checkP
 			if len(designatedKey) > 0 && (checkItem.KeyName != designatedKey) {
 				continue
 			}
+			if len(jsonPath) > 0 && len(checkItem.KeyName) > 0 && !IsJSONPathMatch(checkItem.KeyName, jsonPath, paramName) {
+				continue
+			}
+			if IsPolicyExcepted(hitValueMap, groupPolicy, paramName) {
+				continue
+			}
 			matched := false
 			var err error
 			switch checkItem.Operation {
@@ -284,6 +368,10 @@ func IsMatchGroupPolicy(hitValueMap *sync.Map, appID int64, value string, checkP
 				if checkValue == policyValue {
 					matched = true
 				}
+			case models.OperationSQLInjection:
+				matched = IsSQLInjection(lexicalValue)
+			case models.OperationXSS:
+				matched = IsXSS(lexicalValue)
 			}
 			if matched == true {
 				hitValueInterface, _ := hitValueMap.LoadOrStore(groupPolicy.ID, int64(0))
@@ -8,9 +8,16 @@
 package firewall
 
 import (
+	"sync"
+
 	"github.com/Janusec/janusec/models"
 )
 
+var (
+	// tickOnce start the cleanup tickers only once, InitFirewall is called again on slave nodes after firewall updated
+	tickOnce sync.Once
+)
+
 // InitFirewall ...
 func InitFirewall() {
 	InitCCPolicy()
@@ -25,6 +32,21 @@ func InitFirewall() {
 	InitVulnType()
 	InitGroupPolicy()
 	LoadCheckItems()
+	InitPolicyException()
+	InitAPISchema()
+	InitRateLimitRule()
+	InitIPList()
+	InitGeoIP()
+	InitBot()
+	InitMaskingRule()
 	InitHitLog()
 	go RoutineTick()
+	tickOnce.Do(startTicks)
+}
+
+// startTicks start the tickers which clean up the expired states
+func startTicks() {
+	go RateLimitTick()
+	go IPListTick()
+	go BotTick()
 }
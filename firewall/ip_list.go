@@ -0,0 +1,304 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 19:52:37
+ * @Last Modified: U2, 2026-10-19 19:52:37
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+var (
+	ipListEntries []*models.IPListEntry
+	// ipTrees map[int64]*ipTreeNode, appID (0 for global), radix tree of entries
+	ipTrees sync.Map
+	// ipListMutex protects ipListEntries while reloading, importing or removing expired entries
+	ipListMutex sync.Mutex
+)
+
+// InitIPList ...
+func InitIPList() {
+	var entries []*models.IPListEntry
+	if data.IsMaster {
+		data.DAL.CreateTableIfNotExistsIPLists()
+		entries = data.DAL.SelectIPListEntries()
+	} else {
+		entries = RPCSelectIPListEntries()
+	}
+	// slave nodes reload the list while IPListTick and imports are running
+	ipListMutex.Lock()
+	defer ipListMutex.Unlock()
+	ipListEntries = entries
+	buildIPTrees()
+}
+
+// buildIPTrees rebuild all radix trees, entries with invalid IP are ignored
+func buildIPTrees() {
+	trees := map[int64]*ipTreeNode{}
+	for _, entry := range ipListEntries {
+		ipNet, err := ParseIPNet(entry.IPNet)
+		if err != nil {
+			utils.DebugPrintln("buildIPTrees", err)
+			continue
+		}
+		tree, ok := trees[entry.AppID]
+		if !ok {
+			tree = &ipTreeNode{}
+			trees[entry.AppID] = tree
+		}
+		tree.insert(ipNet, entry)
+	}
+	ipTrees.Range(func(key, value interface{}) bool {
+		if _, ok := trees[key.(int64)]; !ok {
+			ipTrees.Delete(key)
+		}
+		return true
+	})
+	for appID, tree := range trees {
+		ipTrees.Store(appID, tree)
+	}
+}
+
+// GetIPListType return IPList_Allow or IPList_Deny of the client IP, 0 if not listed,
+// the list of application takes precedence over the global list
+func GetIPListType(appID int64, srcIP string) models.IPListType {
+	ip := net.ParseIP(srcIP)
+	if ip == nil {
+		return 0
+	}
+	curTime := time.Now().Unix()
+	if treeI, ok := ipTrees.Load(appID); ok && appID != 0 {
+		if listType := treeI.(*ipTreeNode).lookup(ip, curTime); listType > 0 {
+			return listType
+		}
+	}
+	if treeI, ok := ipTrees.Load(int64(0)); ok {
+		return treeI.(*ipTreeNode).lookup(ip, curTime)
+	}
+	return 0
+}
+
+// GetIPListEntries ...
+func GetIPListEntries() ([]*models.IPListEntry, error) {
+	return ipListEntries, nil
+}
+
+// GetIPListEntryByID ...
+func GetIPListEntryByID(id int64) (*models.IPListEntry, error) {
+	for _, entry := range ipListEntries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+	return nil, errors.New("Not found")
+}
+
+// UpdateIPListEntry ...
+func UpdateIPListEntry(r *http.Request, userID int64) (*models.IPListEntry, error) {
+	var setIPListEntryRequest models.RPCSetIPListEntry
+	err := json.NewDecoder(r.Body).Decode(&setIPListEntryRequest)
+	defer r.Body.Close()
+	utils.CheckError("UpdateIPListEntry Decode", err)
+	curEntry := setIPListEntryRequest.Object
+	if curEntry == nil {
+		return nil, errors.New("UpdateIPListEntry parse body null")
+	}
+	curEntry.UserID = userID
+	ipListMutex.Lock()
+	defer ipListMutex.Unlock()
+	curEntry, err = saveIPListEntry(curEntry)
+	if err != nil {
+		return nil, err
+	}
+	buildIPTrees()
+	data.UpdateFirewallLastModified()
+	return curEntry, nil
+}
+
+func saveIPListEntry(curEntry *models.IPListEntry) (*models.IPListEntry, error) {
+	ipNet, err := ParseIPNet(curEntry.IPNet)
+	if err != nil {
+		return nil, err
+	}
+	if curEntry.ListType != models.IPList_Allow && curEntry.ListType != models.IPList_Deny {
+		return nil, errors.New("list_type should be 1 (allow) or 2 (deny)")
+	}
+	curEntry.IPNet = ipNet.String()
+	curEntry.UpdateTime = time.Now().Unix()
+	if curEntry.ID == 0 {
+		newID, err := data.DAL.InsertIPListEntry(curEntry.AppID, curEntry.IPNet, curEntry.ListType, curEntry.Comment, curEntry.ExpireTime, curEntry.UserID, curEntry.UpdateTime)
+		if err != nil {
+			return nil, err
+		}
+		curEntry.ID = newID
+		ipListEntries = append(ipListEntries, curEntry)
+		return curEntry, nil
+	}
+	entry, err := GetIPListEntryByID(curEntry.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = data.DAL.UpdateIPListEntry(curEntry.AppID, curEntry.IPNet, curEntry.ListType, curEntry.Comment, curEntry.ExpireTime, curEntry.UserID, curEntry.UpdateTime, curEntry.ID)
+	if err != nil {
+		return nil, err
+	}
+	*entry = *curEntry
+	return entry, nil
+}
+
+// DeleteIPListEntryByID ...
+func DeleteIPListEntryByID(id int64) error {
+	ipListMutex.Lock()
+	defer ipListMutex.Unlock()
+	for i, entry := range ipListEntries {
+		if entry.ID == id {
+			data.DAL.DeleteIPListEntryByID(id)
+			ipListEntries = append(ipListEntries[:i], ipListEntries[i+1:]...)
+			buildIPTrees()
+			data.UpdateFirewallLastModified()
+			return nil
+		}
+	}
+	return errors.New("Not found")
+}
+
+// ImportIPList add IPs or CIDRs separated by lines, commas or spaces, return the count of added entries,
+// invalid items are reported in error
+func ImportIPList(r *http.Request, userID int64) (int64, error) {
+	var importIPListRequest models.RPCImportIPList
+	err := json.NewDecoder(r.Body).Decode(&importIPListRequest)
+	defer r.Body.Close()
+	utils.CheckError("ImportIPList Decode", err)
+	ipListImport := importIPListRequest.Object
+	if ipListImport == nil {
+		return 0, errors.New("ImportIPList parse body null")
+	}
+	items := strings.FieldsFunc(ipListImport.IPs, func(c rune) bool {
+		return c == '\n' || c == '\r' || c == ',' || c == ';' || c == ' ' || c == '\t'
+	})
+	ipListMutex.Lock()
+	defer ipListMutex.Unlock()
+	var count int64
+	var invalidItems []string
+	for _, item := range items {
+		entry := &models.IPListEntry{
+			AppID:      ipListImport.AppID,
+			IPNet:      item,
+			ListType:   ipListImport.ListType,
+			Comment:    ipListImport.Comment,
+			ExpireTime: ipListImport.ExpireTime,
+			UserID:     userID}
+		if _, err := saveIPListEntry(entry); err != nil {
+			invalidItems = append(invalidItems, item)
+			continue
+		}
+		count++
+	}
+	if count > 0 {
+		buildIPTrees()
+		data.UpdateFirewallLastModified()
+	}
+	if len(invalidItems) > 0 {
+		return count, errors.New("Invalid: " + strings.Join(invalidItems, ","))
+	}
+	return count, nil
+}
+
+// AddIPListEntryFromLog add the client IP of a CC log or group hit log to the list,
+// param object designates log_type (cc or group), list_type, expire_seconds and global
+func AddIPListEntryFromLog(param map[string]interface{}, userID int64) (*models.IPListEntry, error) {
+	logID := int64(param["id"].(float64))
+	entry := &models.IPListEntry{
+		ListType: models.IPList_Deny,
+		UserID:   userID}
+	logType := "group"
+	isGlobal := false
+	if obj, ok := param["object"].(map[string]interface{}); ok {
+		if value, ok := obj["log_type"].(string); ok {
+			logType = value
+		}
+		if value, ok := obj["list_type"].(float64); ok {
+			entry.ListType = models.IPListType(value)
+		}
+		if value, ok := obj["expire_seconds"].(float64); ok && value > 0 {
+			entry.ExpireTime = time.Now().Unix() + int64(value)
+		}
+		if value, ok := obj["comment"].(string); ok {
+			entry.Comment = value
+		}
+		if value, ok := obj["global"].(bool); ok {
+			isGlobal = value
+		}
+	}
+	var appID int64
+	switch logType {
+	case "cc":
+		ccLog, err := data.DAL.SelectCCLogByID(logID)
+		if err != nil {
+			return nil, err
+		}
+		entry.IPNet = ccLog.ClientIP
+		appID = ccLog.AppID
+	case "group":
+		hitLog, err := data.DAL.SelectGroupHitLogByID(logID)
+		if err != nil {
+			return nil, err
+		}
+		entry.IPNet = hitLog.ClientIP
+		appID = hitLog.AppID
+	default:
+		return nil, errors.New("log_type should be cc or group")
+	}
+	if !isGlobal {
+		entry.AppID = appID
+	}
+	if len(entry.Comment) == 0 {
+		entry.Comment = "Created from " + logType + " log " + strconv.FormatInt(logID, 10)
+	}
+	ipListMutex.Lock()
+	defer ipListMutex.Unlock()
+	entry, err := saveIPListEntry(entry)
+	if err != nil {
+		return nil, err
+	}
+	buildIPTrees()
+	data.UpdateFirewallLastModified()
+	return entry, nil
+}
+
+// IPListTick remove expired entries, and delete them from database on master node
+func IPListTick() {
+	ipListTicker := time.NewTicker(time.Duration(60) * time.Second)
+	for range ipListTicker.C {
+		curTime := time.Now().Unix()
+		ipListMutex.Lock()
+		var validEntries []*models.IPListEntry
+		for _, entry := range ipListEntries {
+			if entry.ExpireTime == 0 || entry.ExpireTime >= curTime {
+				validEntries = append(validEntries, entry)
+			}
+		}
+		if len(validEntries) < len(ipListEntries) {
+			ipListEntries = validEntries
+			buildIPTrees()
+			if data.IsMaster {
+				data.DAL.DeleteExpiredIPListEntries(curTime)
+			}
+		}
+		ipListMutex.Unlock()
+	}
+}
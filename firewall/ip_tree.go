@@ -0,0 +1,84 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 19:46:02
+ * @Last Modified: U2, 2026-10-19 19:46:02
+ */
+
+package firewall
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/Janusec/janusec/models"
+)
+
+// ipTreeNode is the node of binary radix tree, IPv4 is stored as IPv4-mapped IPv6 address
+type ipTreeNode struct {
+	children [2]*ipTreeNode
+	entries  []*models.IPListEntry
+}
+
+// ParseIPNet parse IP or CIDR, an IP is regarded as /32 or /128
+func ParseIPNet(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		_, ipNet, err := net.ParseCIDR(value)
+		return ipNet, err
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, errors.New("invalid IP: " + value)
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		return &net.IPNet{IP: ipv4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+func ipBit(ip net.IP, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
+
+func (node *ipTreeNode) insert(ipNet *net.IPNet, entry *models.IPListEntry) {
+	ones, bits := ipNet.Mask.Size()
+	if bits == 32 {
+		ones += 96
+	}
+	ip := ipNet.IP.To16()
+	for i := 0; i < ones; i++ {
+		bit := ipBit(ip, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &ipTreeNode{}
+		}
+		node = node.children[bit]
+	}
+	node.entries = append(node.entries, entry)
+}
+
+// lookup return the list type of the longest matched prefix, deny list wins within the same prefix
+func (node *ipTreeNode) lookup(ip net.IP, curTime int64) models.IPListType {
+	ip = ip.To16()
+	var listType models.IPListType
+	for i := 0; node != nil; i++ {
+		var nodeListType models.IPListType
+		for _, entry := range node.entries {
+			if entry.ExpireTime > 0 && entry.ExpireTime < curTime {
+				continue
+			}
+			if entry.ListType > nodeListType {
+				nodeListType = entry.ListType
+			}
+		}
+		if nodeListType > 0 {
+			listType = nodeListType
+		}
+		if i == 128 {
+			break
+		}
+		node = node.children[ipBit(ip, i)]
+	}
+	return listType
+}
@@ -0,0 +1,129 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 15:20:41
+ * @Last Modified: U2, 2026-10-19 15:20:41
+ */
+
+package firewall
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Janusec/janusec/models"
+)
+
+// ParseJSONBody decode the body with numbers kept as json.Number
+func ParseJSONBody(body []byte) (interface{}, error) {
+	var params interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err := decoder.Decode(&params)
+	return params, err
+}
+
+// GetJSONStructure return the max nesting depth and the count of all elements
+func GetJSONStructure(value interface{}) (depth int64, count int64) {
+	count = 1
+	switch value2 := value.(type) {
+	case map[string]interface{}:
+		var maxDepth int64
+		for _, subValue := range value2 {
+			subDepth, subCount := GetJSONStructure(subValue)
+			if subDepth > maxDepth {
+				maxDepth = subDepth
+			}
+			count += subCount
+		}
+		depth = maxDepth + 1
+	case []interface{}:
+		var maxDepth int64
+		for _, subValue := range value2 {
+			subDepth, subCount := GetJSONStructure(subValue)
+			if subDepth > maxDepth {
+				maxDepth = subDepth
+			}
+			count += subCount
+		}
+		depth = maxDepth + 1
+	}
+	return depth, count
+}
+
+// IsJSONBodyHitPolicy check the size, structure, keys and values of JSON body
+func IsJSONBodyHitPolicy(ctxMap *sync.Map, appID int64, body []byte, params interface{}) (bool, *models.GroupPolicy) {
+	// ChkPoint_JSONSize
+	matched, policy := IsMatchGroupPolicy(ctxMap, appID, strconv.Itoa(len(body)), models.ChkPointJSONSize, "", false)
+	if matched == true {
+		return matched, policy
+	}
+	depth, count := GetJSONStructure(params)
+	// ChkPoint_JSONDepth
+	matched, policy = IsMatchGroupPolicy(ctxMap, appID, strconv.FormatInt(depth, 10), models.ChkPointJSONDepth, "", false)
+	if matched == true {
+		return matched, policy
+	}
+	// ChkPoint_JSONElementCount
+	matched, policy = IsMatchGroupPolicy(ctxMap, appID, strconv.FormatInt(count, 10), models.ChkPointJSONElementCount, "", false)
+	if matched == true {
+		return matched, policy
+	}
+	return IsJSONValueHitPolicy(ctxMap, appID, "$", "", params)
+}
+
+// IsJSONValueHitPolicy path is the JSON path of value like $.items[*].name,
+// key is the name of the nearest JSON object key, used for policy exceptions
+func IsJSONValueHitPolicy(ctxMap *sync.Map, appID int64, path string, key string, value interface{}) (bool, *models.GroupPolicy) {
+	switch value2 := value.(type) {
+	case string:
+		// ChkPoint_ValueLength
+		matched, policy := IsMatchGroupPolicyByJSONPath(ctxMap, appID, strconv.Itoa(len(value2)), models.ChkPointValueLength, path, key, false)
+		if matched == true {
+			return matched, policy
+		}
+		// ChkPoint_GetPostValue
+		matched, policy = IsMatchGroupPolicyByJSONPath(ctxMap, appID, value2, models.ChkPointGetPostValue, path, key, true)
+		if matched == true {
+			return matched, policy
+		}
+	case json.Number:
+		matched, policy := IsMatchGroupPolicyByJSONPath(ctxMap, appID, value2.String(), models.ChkPointGetPostValue, path, key, false)
+		if matched == true {
+			return matched, policy
+		}
+	case map[string]interface{}:
+		for subKey, subValue := range value2 {
+			subPath := path + "." + subKey
+			// ChkPoint_GetPostKey
+			matched, policy := IsMatchGroupPolicyByJSONPath(ctxMap, appID, subKey, models.ChkPointGetPostKey, subPath, subKey, false)
+			if matched == true {
+				return matched, policy
+			}
+			matched, policy = IsJSONValueHitPolicy(ctxMap, appID, subPath, subKey, subValue)
+			if matched == true {
+				return matched, policy
+			}
+		}
+	case []interface{}:
+		for _, subValue := range value2 {
+			matched, policy := IsJSONValueHitPolicy(ctxMap, appID, path+"[*]", key, subValue)
+			if matched == true {
+				return matched, policy
+			}
+		}
+	}
+	return false, nil
+}
+
+// IsJSONPathMatch keyName starts with $ is compared with the JSON path, array index is written as [*],
+// otherwise it is compared with the nearest key name
+func IsJSONPathMatch(keyName string, path string, key string) bool {
+	if strings.HasPrefix(keyName, "$") {
+		return keyName == path
+	}
+	return keyName == key
+}
@@ -12,6 +12,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httputil"
+	"strings"
 	"time"
 
 	"github.com/Janusec/janusec/data"
@@ -27,20 +28,45 @@ func InitHitLog() {
 	}
 }
 
+// dumpRequest dump the request with the buffered body only,
+// the streaming body is not consumed since it is being forwarded to backend
+func dumpRequest(r *http.Request) []byte {
+	rawRequestBytes, err := httputil.DumpRequest(r, false)
+	utils.CheckError("dumpRequest", err)
+	if bodyBuf := GetBufferedBody(r); bodyBuf != nil {
+		rawRequestBytes = append(rawRequestBytes, bodyBuf...)
+	}
+	return rawRequestBytes
+}
+
 // LogCCRequest ...
 func LogCCRequest(r *http.Request, appID int64, clientIP string, policy *models.CCPolicy) {
+	logCCRequest(r, appID, clientIP, policy.Action)
+}
+
+// LogRateLimitRequest log the request exceeding rate limit rule as CC log
+func LogRateLimitRequest(r *http.Request, appID int64, clientIP string, rule *models.RateLimitRule) {
+	logCCRequest(r, appID, clientIP, rule.Action)
+}
+
+// LogBotRequest log the request of bot as CC log
+func LogBotRequest(r *http.Request, appID int64, clientIP string, policy *models.BotPolicy) {
+	logCCRequest(r, appID, clientIP, policy.Action)
+}
+
+func logCCRequest(r *http.Request, appID int64, clientIP string, action models.PolicyAction) {
 	requestTime := time.Now().Unix()
 	contentType := r.Header.Get("Content-Type")
 	cookies := r.Header.Get("Cookie")
-	rawRequestBytes, err := httputil.DumpRequest(r, true)
-	utils.CheckError("LogGroupHitRequest DumpRequest", err)
+	rawRequestBytes := dumpRequest(r)
 	maxRawSize := len(rawRequestBytes)
 	if maxRawSize > 16384 {
 		maxRawSize = 16384
 	}
 	rawRequest := string(rawRequestBytes[:maxRawSize])
+	ja3, ja4 := GetTLSFingerprint(r)
 	if data.IsMaster {
-		data.DAL.InsertCCLog(requestTime, clientIP, r.Host, r.Method, r.URL.Path, r.URL.RawQuery, contentType, r.UserAgent(), cookies, rawRequest, int64(policy.Action), appID)
+		data.DAL.InsertCCLog(requestTime, clientIP, r.Host, r.Method, r.URL.Path, r.URL.RawQuery, contentType, r.UserAgent(), cookies, rawRequest, int64(action), appID, ja3, ja4)
 	} else {
 		ccLog := &models.CCLog{
 			RequestTime: requestTime,
@@ -53,8 +79,10 @@ func LogCCRequest(r *http.Request, appID int64, clientIP string, policy *models.
 			UserAgent:   r.UserAgent(),
 			Cookies:     cookies,
 			RawRequest:  rawRequest,
-			Action:      policy.Action,
-			AppID:       appID}
+			Action:      action,
+			AppID:       appID,
+			JA3:         ja3,
+			JA4:         ja4}
 		RPCCCLog(ccLog)
 	}
 }
@@ -64,15 +92,15 @@ func LogGroupHitRequest(r *http.Request, appID int64, clientIP string, policy *m
 	requestTime := time.Now().Unix()
 	contentType := r.Header.Get("Content-Type")
 	cookies := r.Header.Get("Cookie")
-	rawRequestBytes, err := httputil.DumpRequest(r, true)
-	utils.CheckError("LogGroupHitRequest DumpRequest", err)
+	rawRequestBytes := dumpRequest(r)
 	maxRawSize := len(rawRequestBytes)
 	if maxRawSize > 16384 {
 		maxRawSize = 16384
 	}
 	rawRequest := string(rawRequestBytes[:maxRawSize])
+	ja3, ja4 := GetTLSFingerprint(r)
 	if data.IsMaster {
-		data.DAL.InsertGroupHitLog(requestTime, clientIP, r.Host, r.Method, r.URL.Path, r.URL.RawQuery, contentType, r.UserAgent(), cookies, rawRequest, int64(policy.Action), policy.ID, policy.VulnID, appID)
+		data.DAL.InsertGroupHitLog(requestTime, clientIP, r.Host, r.Method, r.URL.Path, r.URL.RawQuery, contentType, r.UserAgent(), cookies, rawRequest, int64(policy.Action), policy.ID, policy.VulnID, appID, ja3, ja4)
 	} else {
 		regexHitLog := &models.GroupHitLog{
 			RequestTime: requestTime,
@@ -88,11 +116,98 @@ func LogGroupHitRequest(r *http.Request, appID int64, clientIP string, policy *m
 			Action:      policy.Action,
 			PolicyID:    policy.ID,
 			VulnID:      policy.VulnID,
-			AppID:       appID}
+			AppID:       appID,
+			JA3:         ja3,
+			JA4:         ja4}
 		RPCGroupHitLog(regexHitLog)
 	}
 }
 
+// LogAPIViolationRequest ...
+func LogAPIViolationRequest(r *http.Request, appID int64, clientIP string, apiSchema *models.APISchema, violation string) {
+	requestTime := time.Now().Unix()
+	contentType := r.Header.Get("Content-Type")
+	cookies := r.Header.Get("Cookie")
+	rawRequestBytes := dumpRequest(r)
+	maxRawSize := len(rawRequestBytes)
+	if maxRawSize > 16384 {
+		maxRawSize = 16384
+	}
+	rawRequest := string(rawRequestBytes[:maxRawSize])
+	if len(violation) > 1024 {
+		violation = violation[:1024]
+	}
+	if data.IsMaster {
+		data.DAL.InsertAPIViolationLog(requestTime, clientIP, r.Host, r.Method, r.URL.Path, r.URL.RawQuery, contentType, r.UserAgent(), cookies, rawRequest, int64(apiSchema.Action), apiSchema.ID, violation, appID)
+	} else {
+		violationLog := &models.APIViolationLog{
+			RequestTime: requestTime,
+			ClientIP:    clientIP,
+			Host:        r.Host,
+			Method:      r.Method,
+			UrlPath:     r.URL.Path,
+			UrlQuery:    r.URL.RawQuery,
+			ContentType: contentType,
+			UserAgent:   r.UserAgent(),
+			Cookies:     cookies,
+			RawRequest:  rawRequest,
+			Action:      apiSchema.Action,
+			SchemaID:    apiSchema.ID,
+			Violation:   violation,
+			AppID:       appID}
+		RPCAPIViolationLog(violationLog)
+	}
+}
+
+// LogMaskingResponse log the masked count of response
+func LogMaskingResponse(r *http.Request, appID int64, clientIP string, maskedCounts map[int64]int64) {
+	requestTime := time.Now().Unix()
+	maskedCount, detail := GetMaskingDetail(maskedCounts)
+	if len(detail) > 1024 {
+		detail = detail[:1024]
+	}
+	if data.IsMaster {
+		data.DAL.InsertMaskingLog(requestTime, clientIP, r.Host, r.Method, r.URL.Path, maskedCount, detail, appID)
+	} else {
+		maskingLog := &models.MaskingLog{
+			RequestTime: requestTime,
+			ClientIP:    clientIP,
+			Host:        r.Host,
+			Method:      r.Method,
+			UrlPath:     r.URL.Path,
+			MaskedCount: maskedCount,
+			Detail:      detail,
+			AppID:       appID}
+		RPCMaskingLog(maskingLog)
+	}
+}
+
+// LogMaskingResponseAPI ...
+func LogMaskingResponseAPI(r *http.Request) error {
+	var maskingLogReq models.RPCMaskingLogRequest
+	err := json.NewDecoder(r.Body).Decode(&maskingLogReq)
+	defer r.Body.Close()
+	utils.CheckError("LogMaskingResponseAPI Decode", err)
+	maskingLog := maskingLogReq.Object
+	if maskingLog == nil {
+		return errors.New("LogMaskingResponseAPI parse body null")
+	}
+	return data.DAL.InsertMaskingLog(maskingLog.RequestTime, maskingLog.ClientIP, maskingLog.Host, maskingLog.Method, maskingLog.UrlPath, maskingLog.MaskedCount, maskingLog.Detail, maskingLog.AppID)
+}
+
+// LogAPIViolationRequestAPI ...
+func LogAPIViolationRequestAPI(r *http.Request) error {
+	var violationLogReq models.RPCAPIViolationLogRequest
+	err := json.NewDecoder(r.Body).Decode(&violationLogReq)
+	defer r.Body.Close()
+	utils.CheckError("LogAPIViolationRequestAPI Decode", err)
+	violationLog := violationLogReq.Object
+	if violationLog == nil {
+		return errors.New("LogAPIViolationRequestAPI parse body null")
+	}
+	return data.DAL.InsertAPIViolationLog(violationLog.RequestTime, violationLog.ClientIP, violationLog.Host, violationLog.Method, violationLog.UrlPath, violationLog.UrlQuery, violationLog.ContentType, violationLog.UserAgent, violationLog.Cookies, violationLog.RawRequest, int64(violationLog.Action), violationLog.SchemaID, violationLog.Violation, violationLog.AppID)
+}
+
 // LogCCRequestAPI ...
 func LogCCRequestAPI(r *http.Request) error {
 	var ccLogReq models.RPCCCLogRequest
@@ -103,7 +218,7 @@ func LogCCRequestAPI(r *http.Request) error {
 	if ccLog == nil {
 		return errors.New("LogCCRequestAPI parse body null")
 	}
-	return data.DAL.InsertCCLog(ccLog.RequestTime, ccLog.ClientIP, ccLog.Host, ccLog.Method, ccLog.UrlPath, ccLog.UrlQuery, ccLog.ContentType, ccLog.UserAgent, ccLog.Cookies, ccLog.RawRequest, int64(ccLog.Action), ccLog.AppID)
+	return data.DAL.InsertCCLog(ccLog.RequestTime, ccLog.ClientIP, ccLog.Host, ccLog.Method, ccLog.UrlPath, ccLog.UrlQuery, ccLog.ContentType, ccLog.UserAgent, ccLog.Cookies, ccLog.RawRequest, int64(ccLog.Action), ccLog.AppID, ccLog.JA3, ccLog.JA4)
 }
 
 // LogGroupHitRequestAPI ...
@@ -116,7 +231,7 @@ func LogGroupHitRequestAPI(r *http.Request) error {
 	if regexHitLog == nil {
 		return errors.New("LogGroupHitRequestAPI parse body null")
 	}
-	return data.DAL.InsertGroupHitLog(regexHitLog.RequestTime, regexHitLog.ClientIP, regexHitLog.Host, regexHitLog.Method, regexHitLog.UrlPath, regexHitLog.UrlQuery, regexHitLog.ContentType, regexHitLog.UserAgent, regexHitLog.Cookies, regexHitLog.RawRequest, int64(regexHitLog.Action), regexHitLog.PolicyID, regexHitLog.VulnID, regexHitLog.AppID)
+	return data.DAL.InsertGroupHitLog(regexHitLog.RequestTime, regexHitLog.ClientIP, regexHitLog.Host, regexHitLog.Method, regexHitLog.UrlPath, regexHitLog.UrlQuery, regexHitLog.ContentType, regexHitLog.UserAgent, regexHitLog.Cookies, regexHitLog.RawRequest, int64(regexHitLog.Action), regexHitLog.PolicyID, regexHitLog.VulnID, regexHitLog.AppID, regexHitLog.JA3, regexHitLog.JA4)
 }
 
 // GetCCLogCount ...
@@ -157,10 +272,16 @@ func GetWeekStat(param map[string]interface{}) (weekStat []int64, err error) {
 	appID := int64(param["app_id"].(float64))
 	vulnID := int64(param["vuln_id"].(float64))
 	startTime := int64(param["start_time"].(float64))
+	// optional ISO country code
+	country, _ := param["country"].(string)
 	for i := int64(0); i < 7; i++ {
 		dayStartTime := startTime + 86400*i
 		dayEndTime := dayStartTime + 86400
-		if appID == 0 {
+		if len(country) > 0 {
+			countryCounts, err := getCountryCounts(appID, dayStartTime, dayEndTime)
+			utils.CheckError("GetWeekStat getCountryCounts", err)
+			weekStat = append(weekStat, countryCounts[strings.ToUpper(country)])
+		} else if appID == 0 {
 			if vulnID == 0 {
 				dayCount, err := data.DAL.SelectAllGroupHitLogsCount(dayStartTime, dayEndTime)
 				utils.CheckError("GetWeekStat SelectAllGroupHitLogsCount", err)
@@ -197,6 +318,27 @@ func GetCCLogs(param map[string]interface{}) ([]*models.SimpleCCLog, error) {
 	return simpleCCLogs, nil
 }
 
+// GetMaskingLogCount ...
+func GetMaskingLogCount(param map[string]interface{}) (*models.HitLogsCount, error) {
+	appID := int64(param["app_id"].(float64))
+	startTime := int64(param["start_time"].(float64))
+	endTime := int64(param["end_time"].(float64))
+	count, err := data.DAL.SelectMaskingLogsCount(appID, startTime, endTime)
+	logsCount := &models.HitLogsCount{AppID: appID, StartTime: startTime, EndTime: endTime, Count: count}
+	return logsCount, err
+}
+
+// GetMaskingLogs ...
+func GetMaskingLogs(param map[string]interface{}) ([]*models.MaskingLog, error) {
+	appID := int64(param["app_id"].(float64))
+	startTime := int64(param["start_time"].(float64))
+	endTime := int64(param["end_time"].(float64))
+	requestCount := int64(param["request_count"].(float64))
+	offset := int64(param["offset"].(float64))
+	maskingLogs := data.DAL.SelectMaskingLogs(appID, startTime, endTime, requestCount, offset)
+	return maskingLogs, nil
+}
+
 // GetGroupLogs ...
 func GetGroupLogs(param map[string]interface{}) ([]*models.SimpleGroupHitLog, error) {
 	appID := int64(param["app_id"].(float64))
@@ -219,3 +361,30 @@ func GetCCLogByID(id int64) (*models.CCLog, error) {
 	ccLog, err := data.DAL.SelectCCLogByID(id)
 	return ccLog, err
 }
+
+// GetAPIViolationLogCount ...
+func GetAPIViolationLogCount(param map[string]interface{}) (*models.HitLogsCount, error) {
+	appID := int64(param["app_id"].(float64))
+	startTime := int64(param["start_time"].(float64))
+	endTime := int64(param["end_time"].(float64))
+	count, err := data.DAL.SelectAPIViolationLogsCount(appID, startTime, endTime)
+	logsCount := &models.HitLogsCount{AppID: appID, StartTime: startTime, EndTime: endTime, Count: count}
+	return logsCount, err
+}
+
+// GetAPIViolationLogs ...
+func GetAPIViolationLogs(param map[string]interface{}) ([]*models.SimpleAPIViolationLog, error) {
+	appID := int64(param["app_id"].(float64))
+	startTime := int64(param["start_time"].(float64))
+	endTime := int64(param["end_time"].(float64))
+	requestCount := int64(param["request_count"].(float64))
+	offset := int64(param["offset"].(float64))
+	simpleLogs := data.DAL.SelectAPIViolationLogs(appID, startTime, endTime, requestCount, offset)
+	return simpleLogs, nil
+}
+
+// GetAPIViolationLogByID ...
+func GetAPIViolationLogByID(id int64) (*models.APIViolationLog, error) {
+	violationLog, err := data.DAL.SelectAPIViolationLogByID(id)
+	return violationLog, err
+}
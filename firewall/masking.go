@@ -0,0 +1,400 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 23:31:18
+ * @Last Modified: U2, 2026-10-19 23:31:18
+ */
+
+package firewall
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+var (
+	maskingRules []*models.MaskingRule
+	// maskingRegexps map[int64]*regexp.Regexp, compiled pattern of masking rules
+	maskingRegexps sync.Map
+	// maskingURLRegexps map[int64]*regexp.Regexp, compiled URLPath of masking rules
+	maskingURLRegexps sync.Map
+
+	maskTypePatterns = map[models.MaskType]string{
+		// mobile phone number of China
+		models.Mask_Phone: `\b1[3-9]\d{9}\b`,
+		// 18 digits resident identity card number of China
+		models.Mask_IDNumber: `\b[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`,
+		// 12 to 19 digits with optional separators, checked by Luhn algorithm,
+		// the first digit 2 to 6 covers Mastercard, Amex, JCB, Diners, Visa, UnionPay and Discover
+		models.Mask_BankCard: `\b[2-6](?:[ -]?\d){11,18}\b`,
+		models.Mask_Email:    `\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`,
+	}
+)
+
+// InitMaskingRule load masking rules and compile the patterns
+func InitMaskingRule() {
+	if data.IsMaster {
+		data.DAL.CreateTableIfNotExistsMaskingRules()
+		data.DAL.CreateTableIfNotExistsMaskingLog()
+		maskingRules = data.DAL.SelectMaskingRules()
+	} else {
+		maskingRules = RPCSelectMaskingRules()
+	}
+	for _, rule := range maskingRules {
+		_, err := compileMaskingRule(rule)
+		utils.CheckError("InitMaskingRule", err)
+		compileMaskingURLRegex(rule)
+	}
+}
+
+func compileMaskingURLRegex(rule *models.MaskingRule) error {
+	if len(rule.URLPath) == 0 {
+		maskingURLRegexps.Delete(rule.ID)
+		return nil
+	}
+	regex, err := regexp.Compile(rule.URLPath)
+	utils.CheckError("compileMaskingURLRegex", err)
+	if err != nil {
+		return err
+	}
+	maskingURLRegexps.Store(rule.ID, regex)
+	return nil
+}
+
+func compileMaskingRule(rule *models.MaskingRule) (*regexp.Regexp, error) {
+	pattern, ok := maskTypePatterns[rule.MaskType]
+	if rule.MaskType == models.Mask_Custom {
+		pattern, ok = rule.Pattern, len(rule.Pattern) > 0
+	}
+	if !ok {
+		return nil, errors.New("invalid mask_type or empty pattern")
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	maskingRegexps.Store(rule.ID, regex)
+	return regex, nil
+}
+
+// GetMaskingRules ...
+func GetMaskingRules() ([]*models.MaskingRule, error) {
+	return maskingRules, nil
+}
+
+// GetMaskingRuleByID ...
+func GetMaskingRuleByID(id int64) (*models.MaskingRule, error) {
+	for _, rule := range maskingRules {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return nil, errors.New("Not found")
+}
+
+// UpdateMaskingRule ...
+func UpdateMaskingRule(r *http.Request, userID int64) (*models.MaskingRule, error) {
+	var setMaskingRuleRequest models.RPCSetMaskingRule
+	err := json.NewDecoder(r.Body).Decode(&setMaskingRuleRequest)
+	defer r.Body.Close()
+	utils.CheckError("UpdateMaskingRule Decode", err)
+	curRule := setMaskingRuleRequest.Object
+	if curRule == nil {
+		return nil, errors.New("UpdateMaskingRule parse body null")
+	}
+	if curRule.MaskType < models.Mask_Phone || curRule.MaskType > models.Mask_Custom {
+		return nil, errors.New("mask_type should be 1 (phone), 2 (id number), 3 (bank card), 4 (email) or 5 (custom)")
+	}
+	if curRule.MaskType == models.Mask_Custom {
+		if _, err := regexp.Compile(curRule.Pattern); err != nil || len(curRule.Pattern) == 0 {
+			return nil, errors.New("invalid pattern of custom masking rule")
+		}
+	}
+	if _, err := regexp.Compile(curRule.URLPath); err != nil {
+		return nil, errors.New("invalid url_path regex of masking rule")
+	}
+	if curRule.KeepPrefix < 0 || curRule.KeepSuffix < 0 {
+		return nil, errors.New("keep_prefix and keep_suffix should not be negative")
+	}
+	if len(curRule.MaskChar) == 0 {
+		curRule.MaskChar = "*"
+	}
+	curRule.UserID = userID
+	curRule.UpdateTime = time.Now().Unix()
+	if curRule.ID == 0 {
+		newID, err := data.DAL.InsertMaskingRule(curRule)
+		if err != nil {
+			return nil, err
+		}
+		curRule.ID = newID
+		maskingRules = append(maskingRules, curRule)
+	} else {
+		rule, err := GetMaskingRuleByID(curRule.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = data.DAL.UpdateMaskingRule(curRule)
+		if err != nil {
+			return nil, err
+		}
+		*rule = *curRule
+		curRule = rule
+	}
+	compileMaskingRule(curRule)
+	compileMaskingURLRegex(curRule)
+	data.UpdateFirewallLastModified()
+	return curRule, nil
+}
+
+// DeleteMaskingRuleByID ...
+func DeleteMaskingRuleByID(id int64) error {
+	for i, rule := range maskingRules {
+		if rule.ID == id {
+			data.DAL.DeleteMaskingRuleByID(id)
+			maskingRules = append(maskingRules[:i], maskingRules[i+1:]...)
+			maskingRegexps.Delete(id)
+			maskingURLRegexps.Delete(id)
+			data.UpdateFirewallLastModified()
+			return nil
+		}
+	}
+	return errors.New("Not found")
+}
+
+// getMaskingRules return the enabled rules of application and path in order of ID
+func getMaskingRules(appID int64, urlPath string) []*models.MaskingRule {
+	var rules []*models.MaskingRule
+	for _, rule := range maskingRules {
+		if !rule.IsEnabled || (rule.AppID != 0 && rule.AppID != appID) {
+			continue
+		}
+		if len(rule.URLPath) > 0 {
+			regexI, ok := maskingURLRegexps.Load(rule.ID)
+			if !ok || !regexI.(*regexp.Regexp).MatchString(urlPath) {
+				continue
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isTextContentType return true for text content which can be masked, streaming responses are excluded,
+// buffering them would hold the events until the stream ends
+func isTextContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.HasPrefix(contentType, "text/event-stream") || strings.Contains(contentType, "ndjson") ||
+		strings.Contains(contentType, "stream+json") {
+		return false
+	}
+	return strings.HasPrefix(contentType, "text/") || strings.Contains(contentType, "json") ||
+		strings.Contains(contentType, "xml") || strings.Contains(contentType, "javascript")
+}
+
+// MaskResponseBody rewrite the sensitive data of text response by masking rules, gzip encoded body is supported,
+// return the masked count of each rule, the body exceeds maxBodySize is passed without masking
+func MaskResponseBody(resp *http.Response, appID int64, maxBodySize int64) map[int64]int64 {
+	if resp.Body == nil || resp.Body == http.NoBody || resp.StatusCode == http.StatusSwitchingProtocols {
+		return nil
+	}
+	rules := getMaskingRules(appID, resp.Request.URL.Path)
+	if len(rules) == 0 || !isTextContentType(resp.Header.Get("Content-Type")) {
+		return nil
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if len(encoding) > 0 && encoding != "identity" && encoding != "gzip" {
+		return nil
+	}
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxResponseBodySize
+	}
+	body, buf, err := readLimitedBody(resp.Body, resp.ContentLength, maxBodySize)
+	resp.Body = body
+	if err != nil {
+		return nil
+	}
+	content := buf
+	if encoding == "gzip" {
+		gzipReader, err := gzip.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			return nil
+		}
+		content, err = ioutil.ReadAll(io.LimitReader(gzipReader, maxBodySize+1))
+		if err != nil || int64(len(content)) > maxBodySize {
+			return nil
+		}
+	}
+	isJSON := strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "json")
+	maskedCounts := map[int64]int64{}
+	for _, rule := range rules {
+		var count int64
+		content, count = maskContent(content, rule, isJSON)
+		if count > 0 {
+			maskedCounts[rule.ID] = count
+		}
+	}
+	if len(maskedCounts) == 0 {
+		return nil
+	}
+	if encoding == "gzip" {
+		var gzipBuf bytes.Buffer
+		gzipWriter := gzip.NewWriter(&gzipBuf)
+		gzipWriter.Write(content)
+		gzipWriter.Close()
+		content = gzipBuf.Bytes()
+	}
+	resp.Body = newBufferedBody(content)
+	resp.ContentLength = int64(len(content))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(content)))
+	return maskedCounts
+}
+
+// maskContent replace the matched data of rule, return the new content and the masked count,
+// for JSON the built-in mask types only match in string values, a masked bare number makes the JSON invalid
+func maskContent(content []byte, rule *models.MaskingRule, isJSON bool) ([]byte, int64) {
+	regexI, ok := maskingRegexps.Load(rule.ID)
+	if !ok {
+		return content, 0
+	}
+	regex := regexI.(*regexp.Regexp)
+	var stringRanges [][2]int
+	checkInString := isJSON && rule.MaskType != models.Mask_Custom
+	if checkInString {
+		stringRanges = getJSONStringRanges(content)
+	}
+	var result []byte
+	var count int64
+	last := 0
+	for _, loc := range regex.FindAllSubmatchIndex(content, -1) {
+		match := content[loc[0]:loc[1]]
+		if checkInString {
+			// matches are in order, skip the strings before the match
+			for len(stringRanges) > 0 && stringRanges[0][1] <= loc[0] {
+				stringRanges = stringRanges[1:]
+			}
+			if len(stringRanges) == 0 || loc[0] < stringRanges[0][0] || loc[1] > stringRanges[0][1] {
+				continue
+			}
+		}
+		if rule.MaskType == models.Mask_BankCard && !isLuhnValid(match) {
+			continue
+		}
+		result = append(result, content[last:loc[0]]...)
+		if len(rule.Replacement) > 0 {
+			result = regex.Expand(result, []byte(rule.Replacement), content, loc)
+		} else {
+			result = append(result, maskValue(rule, string(match))...)
+		}
+		last = loc[1]
+		count++
+	}
+	if count == 0 {
+		return content, 0
+	}
+	return append(result, content[last:]...), count
+}
+
+// maskValue keep the prefix and suffix characters, letters and digits in the middle are masked,
+// all of them are masked if the value is too short
+func maskValue(rule *models.MaskingRule, value string) string {
+	domain := ""
+	if rule.MaskType == models.Mask_Email {
+		if index := strings.LastIndex(value, "@"); index > 0 {
+			value, domain = value[:index], value[index:]
+		}
+	}
+	maskChar := rule.MaskChar
+	if len(maskChar) == 0 {
+		maskChar = "*"
+	}
+	runeCount := int64(utf8.RuneCountInString(value))
+	keepPrefix, keepSuffix := rule.KeepPrefix, rule.KeepSuffix
+	if keepPrefix+keepSuffix >= runeCount {
+		keepPrefix, keepSuffix = 0, 0
+	}
+	var builder strings.Builder
+	var i int64
+	for _, char := range value {
+		if i < keepPrefix || i >= runeCount-keepSuffix || !(unicode.IsLetter(char) || unicode.IsDigit(char)) {
+			builder.WriteRune(char)
+		} else {
+			builder.WriteString(maskChar)
+		}
+		i++
+	}
+	return builder.String() + domain
+}
+
+// getJSONStringRanges return the start and end of the content of each string in JSON, quotes excluded
+func getJSONStringRanges(content []byte) [][2]int {
+	var ranges [][2]int
+	inString := false
+	start := 0
+	for i := 0; i < len(content); i++ {
+		switch {
+		case inString && content[i] == '\\':
+			// skip the escaped character
+			i++
+		case content[i] == '"':
+			if inString {
+				ranges = append(ranges, [2]int{start, i})
+			} else {
+				start = i + 1
+			}
+			inString = !inString
+		}
+	}
+	return ranges
+}
+
+func isLuhnValid(number []byte) bool {
+	sum, digitCount := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		if number[i] < '0' || number[i] > '9' {
+			continue
+		}
+		digit := int(number[i] - '0')
+		if digitCount%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		digitCount++
+	}
+	return digitCount > 0 && sum%10 == 0
+}
+
+// GetMaskingDetail format the masked counts like 1:3,4:1 ordered by rule ID
+func GetMaskingDetail(maskedCounts map[int64]int64) (int64, string) {
+	var total int64
+	ruleIDs := make([]int64, 0, len(maskedCounts))
+	for ruleID, count := range maskedCounts {
+		ruleIDs = append(ruleIDs, ruleID)
+		total += count
+	}
+	sort.Slice(ruleIDs, func(i, j int) bool { return ruleIDs[i] < ruleIDs[j] })
+	items := make([]string, len(ruleIDs))
+	for i, ruleID := range ruleIDs {
+		items[i] = strconv.FormatInt(ruleID, 10) + ":" + strconv.FormatInt(maskedCounts[ruleID], 10)
+	}
+	return total, strings.Join(items, ",")
+}
@@ -0,0 +1,101 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 11:52:09
+ * @Last Modified: U2, 2026-10-20 11:52:09
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/Janusec/janusec/models"
+)
+
+func TestMaskBankCard(t *testing.T) {
+	rule := &models.MaskingRule{ID: -1, MaskType: models.Mask_BankCard, KeepPrefix: 4, KeepSuffix: 4, MaskChar: "*"}
+	if _, err := compileMaskingRule(rule); err != nil {
+		t.Fatal(err)
+	}
+	defer maskingRegexps.Delete(rule.ID)
+	tests := []struct {
+		content string
+		isJSON  bool
+		want    string
+	}{
+		{`card 4111111111111111 paid`, false, `card 4111********1111 paid`},
+		{`card 4111 1111 1111 1111 paid`, false, `card 4111 **** **** 1111 paid`},
+		{`card 5555-5555-5555-4444 paid`, false, `card 5555-****-****-4444 paid`},
+		// fails Luhn check
+		{`card 4111111111111112 paid`, false, `card 4111111111111112 paid`},
+		// millisecond timestamp and ID with unknown prefix pass Luhn check
+		{`time 1700000000004 id 9123456789012348`, false, `time 1700000000004 id 9123456789012348`},
+		{`{"card":"4111111111111111","id":4065021700000003}`, true, `{"card":"4111********1111","id":4065021700000003}`},
+		{`{"list":[4065021700000003,"note: 4111 1111 1111 1111"]}`, true, `{"list":[4065021700000003,"note: 4111 **** **** 1111"]}`},
+		{`{"quote":"a\"b","card":"5555555555554444"}`, true, `{"quote":"a\"b","card":"5555********4444"}`},
+	}
+	for _, test := range tests {
+		got, _ := maskContent([]byte(test.content), rule, test.isJSON)
+		if string(got) != test.want {
+			t.Errorf("maskContent(%s) = %s, want %s", test.content, got, test.want)
+		}
+		if test.isJSON && !json.Valid(got) {
+			t.Errorf("maskContent(%s) = %s, invalid JSON", test.content, got)
+		}
+	}
+}
+
+func TestGetMaskingRulesURLPath(t *testing.T) {
+	defer func(rules []*models.MaskingRule) { maskingRules = rules }(maskingRules)
+	maskingRules = []*models.MaskingRule{
+		{ID: -1, AppID: 0, URLPath: `^/api/users/\d+$`, IsEnabled: true},
+		{ID: -2, AppID: 1, URLPath: "", IsEnabled: true},
+		{ID: -3, AppID: 2, URLPath: "", IsEnabled: true},
+		{ID: -4, AppID: 0, URLPath: "", IsEnabled: false},
+	}
+	for _, rule := range maskingRules {
+		if err := compileMaskingURLRegex(rule); err != nil {
+			t.Fatal(err)
+		}
+		defer maskingURLRegexps.Delete(rule.ID)
+	}
+	tests := []struct {
+		appID   int64
+		urlPath string
+		want    []int64
+	}{
+		{1, "/api/users/12", []int64{-1, -2}},
+		{1, "/api/users/12/orders", []int64{-2}},
+		{3, "/api/users/abc", nil},
+	}
+	for _, test := range tests {
+		var got []int64
+		for _, rule := range getMaskingRules(test.appID, test.urlPath) {
+			got = append(got, rule.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("getMaskingRules(%d, %s) = %v, want %v", test.appID, test.urlPath, got, test.want)
+		}
+	}
+}
+
+func TestIsTextContentType(t *testing.T) {
+	tests := map[string]bool{
+		"text/html; charset=utf-8": true,
+		"application/json":         true,
+		"application/xml":          true,
+		"text/event-stream":        false,
+		"application/x-ndjson":     false,
+		"application/stream+json":  false,
+		"image/png":                false,
+		"application/octet-stream": false,
+	}
+	for contentType, want := range tests {
+		if got := isTextContentType(contentType); got != want {
+			t.Errorf("isTextContentType(%s) = %v, want %v", contentType, got, want)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 16:23:48
+ * @Last Modified: U2, 2026-10-19 16:23:48
+ */
+
+package firewall
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// openAPIMaxRefDepth limits the nesting of $ref and sub schemas
+	openAPIMaxRefDepth = 64
+)
+
+var (
+	openAPIMethods     = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+	openAPINumberRegex = regexp.MustCompile(`^-?\d+(\.\d+)?([eE][+-]?\d+)?$`)
+	// openAPIPatterns map[string]*regexp.Regexp, compiled pattern of string schemas
+	openAPIPatterns sync.Map
+)
+
+type openAPIOperation struct {
+	parameters  []map[string]interface{}
+	requestBody map[string]interface{}
+}
+
+type openAPIPath struct {
+	template   string
+	regex      *regexp.Regexp
+	paramNames []string
+	literalLen int
+	operations map[string]*openAPIOperation
+}
+
+// OpenAPIDocument is the compiled OpenAPI 3 document
+type OpenAPIDocument struct {
+	root      map[string]interface{}
+	basePaths []string
+	paths     []*openAPIPath
+}
+
+// ParseOpenAPI parse OpenAPI 3 document in JSON format
+func ParseOpenAPI(content string) (*OpenAPIDocument, error) {
+	var root map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, errors.New("only OpenAPI 3 document in JSON format is supported")
+	}
+	doc := &OpenAPIDocument{root: root}
+	if servers, ok := root["servers"].([]interface{}); ok {
+		for _, serverI := range servers {
+			server, _ := serverI.(map[string]interface{})
+			serverURL, _ := server["url"].(string)
+			if parsedURL, err := url.Parse(serverURL); err == nil {
+				basePath := strings.TrimSuffix(parsedURL.Path, "/")
+				if len(basePath) > 0 {
+					doc.basePaths = append(doc.basePaths, basePath)
+				}
+			}
+		}
+	}
+	paths, _ := root["paths"].(map[string]interface{})
+	for template, pathItemI := range paths {
+		pathItem, ok := doc.resolve(pathItemI).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		apiPath, err := doc.compilePath(template, pathItem)
+		if err != nil {
+			return nil, err
+		}
+		doc.paths = append(doc.paths, apiPath)
+	}
+	// literal paths first, such as /users/me before /users/{id}
+	sort.Slice(doc.paths, func(i, j int) bool {
+		return doc.paths[i].literalLen > doc.paths[j].literalLen
+	})
+	return doc, nil
+}
+
+func (doc *OpenAPIDocument) compilePath(template string, pathItem map[string]interface{}) (*openAPIPath, error) {
+	apiPath := &openAPIPath{template: template, operations: map[string]*openAPIOperation{}}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	rest := template
+	for {
+		start := strings.Index(rest, "{")
+		end := strings.Index(rest, "}")
+		if start < 0 || end < start {
+			break
+		}
+		pattern.WriteString(regexp.QuoteMeta(rest[:start]))
+		pattern.WriteString("([^/]+)")
+		apiPath.literalLen += start
+		apiPath.paramNames = append(apiPath.paramNames, rest[start+1:end])
+		rest = rest[end+1:]
+	}
+	pattern.WriteString(regexp.QuoteMeta(rest))
+	pattern.WriteString("$")
+	apiPath.literalLen += len(rest)
+	regex, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return nil, err
+	}
+	apiPath.regex = regex
+	commonParams := doc.resolveParameters(pathItem["parameters"])
+	for _, method := range openAPIMethods {
+		operationI, ok := pathItem[method].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		operation := &openAPIOperation{}
+		// operation parameters override path item parameters with the same name and location
+		params := doc.resolveParameters(operationI["parameters"])
+		for _, commonParam := range commonParams {
+			overridden := false
+			for _, param := range params {
+				if param["name"] == commonParam["name"] && param["in"] == commonParam["in"] {
+					overridden = true
+					break
+				}
+			}
+			if !overridden {
+				params = append(params, commonParam)
+			}
+		}
+		operation.parameters = params
+		operation.requestBody, _ = doc.resolve(operationI["requestBody"]).(map[string]interface{})
+		apiPath.operations[strings.ToUpper(method)] = operation
+	}
+	return apiPath, nil
+}
+
+func (doc *OpenAPIDocument) resolveParameters(paramsI interface{}) (params []map[string]interface{}) {
+	paramList, _ := paramsI.([]interface{})
+	for _, paramI := range paramList {
+		if param, ok := doc.resolve(paramI).(map[string]interface{}); ok {
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// resolve follow local $ref like #/components/schemas/User
+func (doc *OpenAPIDocument) resolve(value interface{}) interface{} {
+	for i := 0; i < openAPIMaxRefDepth; i++ {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return value
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return nil
+		}
+		var current interface{} = doc.root
+		for _, token := range strings.Split(ref[2:], "/") {
+			token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+			currentMap, ok := current.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			current = currentMap[token]
+		}
+		value = current
+	}
+	return nil
+}
+
+// ValidateRequest return the violation, empty if the request conforms to the document
+func (doc *OpenAPIDocument) ValidateRequest(r *http.Request, body []byte) string {
+	apiPath, pathValues := doc.matchPath(r.URL.Path)
+	if apiPath == nil {
+		return "path " + r.URL.Path + " is not defined"
+	}
+	operation, ok := apiPath.operations[r.Method]
+	if !ok {
+		return "method " + r.Method + " is not defined for " + apiPath.template
+	}
+	query := r.URL.Query()
+	for _, param := range operation.parameters {
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		required, _ := param["required"].(bool)
+		var values []string
+		switch in {
+		case "path":
+			if value, ok := pathValues[name]; ok {
+				values = []string{value}
+			}
+			required = true
+		case "query":
+			values = query[name]
+		case "header":
+			if name == "Accept" || name == "Content-Type" || name == "Authorization" {
+				continue
+			}
+			values = r.Header[http.CanonicalHeaderKey(name)]
+		case "cookie":
+			if cookie, err := r.Cookie(name); err == nil {
+				values = []string{cookie.Value}
+			}
+		default:
+			continue
+		}
+		if len(values) == 0 {
+			if required {
+				return in + " parameter " + name + " is required"
+			}
+			continue
+		}
+		schema := doc.resolve(param["schema"])
+		if schema == nil {
+			continue
+		}
+		if violation := doc.validateSchema(schema, doc.coerceParameter(schema, values), name, 0); len(violation) > 0 {
+			return in + " parameter " + violation
+		}
+	}
+	if operation.requestBody != nil {
+		return doc.validateBody(r, operation.requestBody, body)
+	}
+	return ""
+}
+
+func (doc *OpenAPIDocument) matchPath(urlPath string) (*openAPIPath, map[string]string) {
+	candidates := []string{urlPath}
+	for _, basePath := range doc.basePaths {
+		if strings.HasPrefix(urlPath, basePath+"/") {
+			candidates = append(candidates, urlPath[len(basePath):])
+		}
+	}
+	for _, candidate := range candidates {
+		for _, apiPath := range doc.paths {
+			matches := apiPath.regex.FindStringSubmatch(candidate)
+			if matches == nil {
+				continue
+			}
+			pathValues := map[string]string{}
+			for i, name := range apiPath.paramNames {
+				pathValues[name] = matches[i+1]
+			}
+			return apiPath, pathValues
+		}
+	}
+	return nil, nil
+}
+
+func (doc *OpenAPIDocument) validateBody(r *http.Request, requestBody map[string]interface{}, body []byte) string {
+	required, _ := requestBody["required"].(bool)
+	if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+		if required {
+			return "request body is required"
+		}
+		return ""
+	}
+	content, _ := requestBody["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var mediaTypeObj map[string]interface{}
+	for _, contentType := range []string{mediaType, strings.Split(mediaType, "/")[0] + "/*", "*/*"} {
+		if obj, ok := content[contentType]; ok {
+			mediaTypeObj, _ = obj.(map[string]interface{})
+			break
+		}
+	}
+	if mediaTypeObj == nil {
+		return "content type " + mediaType + " is not defined"
+	}
+	schema := doc.resolve(mediaTypeObj["schema"])
+	if schema == nil || body == nil {
+		// body is not buffered, too large or not required by any check item
+		return ""
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return ""
+	}
+	value, err := ParseJSONBody(body)
+	if err != nil {
+		return "request body is not valid JSON"
+	}
+	if violation := doc.validateSchema(schema, value, "$", 0); len(violation) > 0 {
+		return "request body " + violation
+	}
+	return ""
+}
+
+// coerceParameter convert the string values of parameter to the type of schema
+func (doc *OpenAPIDocument) coerceParameter(schema interface{}, values []string) interface{} {
+	schemaMap, _ := schema.(map[string]interface{})
+	switch schemaType(schemaMap) {
+	case "array":
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		itemSchema := doc.resolve(schemaMap["items"])
+		items := []interface{}{}
+		for _, value := range values {
+			items = append(items, doc.coerceParameter(itemSchema, []string{value}))
+		}
+		return items
+	case "integer", "number":
+		if openAPINumberRegex.MatchString(values[0]) {
+			return json.Number(values[0])
+		}
+	case "boolean":
+		if value, err := strconv.ParseBool(values[0]); err == nil {
+			return value
+		}
+	}
+	return values[0]
+}
+
+func schemaType(schema map[string]interface{}) string {
+	switch typeI := schema["type"].(type) {
+	case string:
+		return typeI
+	case []interface{}:
+		// OpenAPI 3.1 type array like ["string", "null"]
+		for _, t := range typeI {
+			if t != "null" {
+				typeStr, _ := t.(string)
+				return typeStr
+			}
+		}
+	}
+	return ""
+}
+
+func isNullable(schema map[string]interface{}) bool {
+	if nullable, _ := schema["nullable"].(bool); nullable {
+		return true
+	}
+	if types, ok := schema["type"].([]interface{}); ok {
+		for _, t := range types {
+			if t == "null" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// validateSchema return the violation, path is the location of value
+func (doc *OpenAPIDocument) validateSchema(schemaI interface{}, value interface{}, path string, depth int) string {
+	if depth > openAPIMaxRefDepth {
+		return ""
+	}
+	schema, ok := doc.resolve(schemaI).(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if value == nil {
+		if isNullable(schema) || len(schemaType(schema)) == 0 {
+			return ""
+		}
+		return path + ": null is not allowed"
+	}
+	if subSchemas, ok := schema["allOf"].([]interface{}); ok {
+		for _, subSchema := range subSchemas {
+			if violation := doc.validateSchema(subSchema, value, path, depth+1); len(violation) > 0 {
+				return violation
+			}
+		}
+	}
+	if subSchemas, ok := schema["anyOf"].([]interface{}); ok {
+		var firstViolation string
+		for _, subSchema := range subSchemas {
+			violation := doc.validateSchema(subSchema, value, path, depth+1)
+			if len(violation) == 0 {
+				firstViolation = ""
+				break
+			}
+			if len(firstViolation) == 0 {
+				firstViolation = violation
+			}
+		}
+		if len(firstViolation) > 0 {
+			return firstViolation
+		}
+	}
+	if subSchemas, ok := schema["oneOf"].([]interface{}); ok {
+		matchedCount := 0
+		for _, subSchema := range subSchemas {
+			if len(doc.validateSchema(subSchema, value, path, depth+1)) == 0 {
+				matchedCount++
+			}
+		}
+		if matchedCount != 1 {
+			return path + ": should match exactly one schema of oneOf"
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		valueJSON, _ := json.Marshal(value)
+		found := false
+		for _, item := range enum {
+			itemJSON, _ := json.Marshal(item)
+			if bytes.Equal(valueJSON, itemJSON) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return path + ": value is not in enum"
+		}
+	}
+	expectType := schemaType(schema)
+	switch value2 := value.(type) {
+	case string:
+		if len(expectType) > 0 && expectType != "string" {
+			return path + ": expect type " + expectType
+		}
+		length := utf8.RuneCountInString(value2)
+		if minLength, ok := schemaNumber(schema, "minLength"); ok && float64(length) < minLength {
+			return path + ": string is too short"
+		}
+		if maxLength, ok := schemaNumber(schema, "maxLength"); ok && float64(length) > maxLength {
+			return path + ": string is too long"
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			regexI, ok := openAPIPatterns.Load(pattern)
+			if !ok {
+				regex, err := regexp.Compile(pattern)
+				if err != nil {
+					return ""
+				}
+				regexI, _ = openAPIPatterns.LoadOrStore(pattern, regex)
+			}
+			if !regexI.(*regexp.Regexp).MatchString(value2) {
+				return path + ": string does not match pattern"
+			}
+		}
+	case json.Number:
+		if expectType == "integer" {
+			if _, err := value2.Int64(); err != nil {
+				return path + ": expect type integer"
+			}
+		} else if len(expectType) > 0 && expectType != "number" {
+			return path + ": expect type " + expectType
+		}
+		number, _ := value2.Float64()
+		if minimum, ok := schemaNumber(schema, "minimum"); ok {
+			exclusive, _ := schema["exclusiveMinimum"].(bool)
+			if number < minimum || (exclusive && number == minimum) {
+				return path + ": number is less than minimum"
+			}
+		}
+		if minimum, ok := schemaNumber(schema, "exclusiveMinimum"); ok && number <= minimum {
+			return path + ": number is less than minimum"
+		}
+		if maximum, ok := schemaNumber(schema, "maximum"); ok {
+			exclusive, _ := schema["exclusiveMaximum"].(bool)
+			if number > maximum || (exclusive && number == maximum) {
+				return path + ": number is greater than maximum"
+			}
+		}
+		if maximum, ok := schemaNumber(schema, "exclusiveMaximum"); ok && number >= maximum {
+			return path + ": number is greater than maximum"
+		}
+	case bool:
+		if len(expectType) > 0 && expectType != "boolean" {
+			return path + ": expect type " + expectType
+		}
+	case []interface{}:
+		if len(expectType) > 0 && expectType != "array" {
+			return path + ": expect type " + expectType
+		}
+		if minItems, ok := schemaNumber(schema, "minItems"); ok && float64(len(value2)) < minItems {
+			return path + ": too few items"
+		}
+		if maxItems, ok := schemaNumber(schema, "maxItems"); ok && float64(len(value2)) > maxItems {
+			return path + ": too many items"
+		}
+		if items, ok := schema["items"]; ok {
+			for i, item := range value2 {
+				if violation := doc.validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i), depth+1); len(violation) > 0 {
+					return violation
+				}
+			}
+		}
+	case map[string]interface{}:
+		if len(expectType) > 0 && expectType != "object" {
+			return path + ": expect type " + expectType
+		}
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, nameI := range required {
+				name, _ := nameI.(string)
+				if _, ok := value2[name]; !ok {
+					return path + "." + name + ": property is required"
+				}
+			}
+		}
+		if minProperties, ok := schemaNumber(schema, "minProperties"); ok && float64(len(value2)) < minProperties {
+			return path + ": too few properties"
+		}
+		if maxProperties, ok := schemaNumber(schema, "maxProperties"); ok && float64(len(value2)) > maxProperties {
+			return path + ": too many properties"
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, subValue := range value2 {
+			if propertySchema, ok := properties[name]; ok {
+				if violation := doc.validateSchema(propertySchema, subValue, path+"."+name, depth+1); len(violation) > 0 {
+					return violation
+				}
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return path + "." + name + ": property is not allowed"
+				}
+			case map[string]interface{}:
+				if violation := doc.validateSchema(additional, subValue, path+"."+name, depth+1); len(violation) > 0 {
+					return violation
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	number, ok := schema[key].(json.Number)
+	if !ok {
+		return 0, false
+	}
+	value, err := number.Float64()
+	return value, err == nil
+}
@@ -0,0 +1,137 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 14:05:37
+ * @Last Modified: U2, 2026-10-20 14:05:37
+ */
+
+package firewall
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const testOpenAPIDocument = `{
+	"openapi": "3.0.3",
+	"servers": [{"url": "https://api.example.com/v1"}],
+	"paths": {
+		"/users/{id}": {
+			"parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 1}}],
+			"get": {
+				"parameters": [
+					{"name": "fields", "in": "query", "schema": {"type": "array", "items": {"type": "string", "enum": ["name", "email"]}}},
+					{"name": "X-Tenant", "in": "header", "required": true, "schema": {"type": "string", "pattern": "^[a-z]+$"}}
+				]
+			},
+			"delete": {}
+		},
+		"/users/me": {
+			"get": {}
+		},
+		"/users": {
+			"get": {
+				"parameters": [{"name": "limit", "in": "query", "required": true, "schema": {"type": "integer", "maximum": 100}}]
+			},
+			"post": {
+				"requestBody": {
+					"required": true,
+					"content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}
+				}
+			}
+		}
+	},
+	"components": {
+		"schemas": {
+			"User": {
+				"type": "object",
+				"required": ["name", "role"],
+				"additionalProperties": false,
+				"properties": {
+					"name": {"type": "string", "minLength": 1, "maxLength": 8},
+					"age": {"type": "integer", "minimum": 0},
+					"role": {"type": "string", "enum": ["admin", "user"]},
+					"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+				}
+			}
+		}
+	}
+}`
+
+func TestValidateOpenAPIRequest(t *testing.T) {
+	doc, err := ParseOpenAPI(testOpenAPIDocument)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method    string
+		url       string
+		header    map[string]string
+		body      string
+		violation string
+	}{
+		// path templating, base path of servers and literal path first
+		{"GET", "/users/12", map[string]string{"X-Tenant": "acme"}, "", ""},
+		{"GET", "/v1/users/12?fields=name,email", map[string]string{"X-Tenant": "acme"}, "", ""},
+		{"GET", "/users/me", nil, "", ""},
+		{"DELETE", "/users/12", nil, "", ""},
+		{"GET", "/users/12/orders", nil, "", "path /users/12/orders is not defined"},
+		{"GET", "/v2/users/12", nil, "", "path /v2/users/12 is not defined"},
+		{"GET", "/orders", nil, "", "path /orders is not defined"},
+		{"PUT", "/users/12", nil, "", "method PUT is not defined"},
+		// required and typed parameters
+		{"GET", "/users/abc", map[string]string{"X-Tenant": "acme"}, "", "path parameter id: expect type integer"},
+		{"GET", "/users/0", map[string]string{"X-Tenant": "acme"}, "", "path parameter id: number is less than minimum"},
+		{"GET", "/users/12", nil, "", "header parameter X-Tenant is required"},
+		{"GET", "/users/12", map[string]string{"X-Tenant": "ACME"}, "", "header parameter X-Tenant: string does not match pattern"},
+		{"GET", "/users/12?fields=name,phone", map[string]string{"X-Tenant": "acme"}, "", "query parameter fields[1]: value is not in enum"},
+		{"GET", "/users?limit=10", nil, "", ""},
+		{"GET", "/users", nil, "", "query parameter limit is required"},
+		{"GET", "/users?limit=ten", nil, "", "query parameter limit: expect type integer"},
+		{"GET", "/users?limit=101", nil, "", "query parameter limit: number is greater than maximum"},
+		// JSON body
+		{"POST", "/users", nil, `{"name":"alice","role":"admin","age":30,"tags":["a"]}`, ""},
+		{"POST", "/users", nil, `{"name":"alice","role":"root"}`, "request body $.role: value is not in enum"},
+		{"POST", "/users", nil, `{"name":"alice","role":"user","age":"30"}`, "request body $.age: expect type integer"},
+		{"POST", "/users", nil, `{"name":"alice","role":"user","age":1.5}`, "request body $.age: expect type integer"},
+		{"POST", "/users", nil, `{"name":"alice"}`, "request body $.role: property is required"},
+		{"POST", "/users", nil, `{"name":"alice","role":"user","admin":true}`, "request body $.admin: property is not allowed"},
+		{"POST", "/users", nil, `{"name":"alice-in-wonderland","role":"user"}`, "request body $.name: string is too long"},
+		{"POST", "/users", nil, `{"name":"alice","role":"user","tags":["a",1]}`, "request body $.tags[1]: expect type string"},
+		{"POST", "/users", nil, `["alice"]`, "request body $: expect type object"},
+		{"POST", "/users", nil, `{"name":`, "request body is not valid JSON"},
+		{"POST", "/users", nil, "", "request body is required"},
+		{"POST", "/users", map[string]string{"Content-Type": "text/plain"}, "alice", "content type text/plain is not defined"},
+	}
+	for _, test := range tests {
+		var r *http.Request
+		if len(test.body) > 0 {
+			r, _ = http.NewRequest(test.method, "http://api.example.com"+test.url, strings.NewReader(test.body))
+			r.Header.Set("Content-Type", "application/json")
+		} else {
+			r, _ = http.NewRequest(test.method, "http://api.example.com"+test.url, nil)
+		}
+		for name, value := range test.header {
+			r.Header.Set(name, value)
+		}
+		var body []byte
+		if len(test.body) > 0 {
+			body = []byte(test.body)
+		}
+		violation := doc.ValidateRequest(r, body)
+		if len(test.violation) == 0 && len(violation) > 0 {
+			t.Errorf("%s %s: unexpected violation %s", test.method, test.url, violation)
+		} else if !strings.HasPrefix(violation, test.violation) {
+			t.Errorf("%s %s: got violation %q, want %q", test.method, test.url, violation, test.violation)
+		}
+	}
+}
+
+func TestParseOpenAPIInvalid(t *testing.T) {
+	for _, content := range []string{`{"swagger": "2.0", "paths": {}}`, `{"openapi": "3.0.0",`, `[]`} {
+		if _, err := ParseOpenAPI(content); err == nil {
+			t.Errorf("ParseOpenAPI(%s) should fail", content)
+		}
+	}
+}
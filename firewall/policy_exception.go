@@ -0,0 +1,217 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 11:32:05
+ * @Last Modified: U2, 2026-10-19 11:32:05
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+var (
+	policyExceptions []*models.PolicyException
+	// exceptionRegexes map[int64]*regexp.Regexp, compiled URLPath of exceptions
+	exceptionRegexes sync.Map
+)
+
+// policyExceptionsKey is the key of request exceptions stored in groupPolicyHitValue map
+type policyExceptionsKey struct{}
+
+// InitPolicyException ...
+func InitPolicyException() {
+	if data.IsMaster {
+		data.DAL.CreateTableIfNotExistsPolicyExceptions()
+		policyExceptions = data.DAL.SelectPolicyExceptions()
+	} else {
+		policyExceptions = RPCSelectPolicyExceptions()
+	}
+	for _, policyException := range policyExceptions {
+		compileExceptionRegex(policyException)
+	}
+}
+
+func compileExceptionRegex(policyException *models.PolicyException) error {
+	if len(policyException.URLPath) == 0 {
+		exceptionRegexes.Delete(policyException.ID)
+		return nil
+	}
+	regex, err := regexp.Compile(policyException.URLPath)
+	utils.CheckError("compileExceptionRegex", err)
+	if err != nil {
+		return err
+	}
+	exceptionRegexes.Store(policyException.ID, regex)
+	return nil
+}
+
+// GetPolicyExceptions ...
+func GetPolicyExceptions() ([]*models.PolicyException, error) {
+	return policyExceptions, nil
+}
+
+// GetPolicyExceptionByID ...
+func GetPolicyExceptionByID(id int64) (*models.PolicyException, error) {
+	for _, policyException := range policyExceptions {
+		if policyException.ID == id {
+			return policyException, nil
+		}
+	}
+	return nil, errors.New("Not found")
+}
+
+// UpdatePolicyException ...
+func UpdatePolicyException(r *http.Request, userID int64) (*models.PolicyException, error) {
+	var setPolicyExceptionRequest models.RPCSetPolicyException
+	err := json.NewDecoder(r.Body).Decode(&setPolicyExceptionRequest)
+	defer r.Body.Close()
+	utils.CheckError("UpdatePolicyException Decode", err)
+	curException := setPolicyExceptionRequest.Object
+	if curException == nil {
+		return nil, errors.New("UpdatePolicyException parse body null")
+	}
+	curException.UserID = userID
+	return savePolicyException(curException)
+}
+
+func savePolicyException(curException *models.PolicyException) (*models.PolicyException, error) {
+	if curException.PolicyID == 0 && curException.VulnID == 0 {
+		return nil, errors.New("policy_id or vuln_id is required")
+	}
+	if _, err := regexp.Compile(curException.URLPath); err != nil {
+		return nil, err
+	}
+	curException.UpdateTime = time.Now().Unix()
+	if curException.ID == 0 {
+		newID, err := data.DAL.InsertPolicyException(curException.Description, curException.AppID, curException.PolicyID, curException.VulnID, curException.URLPath, curException.ParamName, curException.Username, curException.IsEnabled, curException.UserID, curException.UpdateTime)
+		if err != nil {
+			return nil, err
+		}
+		curException.ID = newID
+		policyExceptions = append(policyExceptions, curException)
+	} else {
+		policyException, err := GetPolicyExceptionByID(curException.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = data.DAL.UpdatePolicyException(curException.Description, curException.AppID, curException.PolicyID, curException.VulnID, curException.URLPath, curException.ParamName, curException.Username, curException.IsEnabled, curException.UserID, curException.UpdateTime, curException.ID)
+		if err != nil {
+			return nil, err
+		}
+		*policyException = *curException
+		curException = policyException
+	}
+	compileExceptionRegex(curException)
+	data.UpdateFirewallLastModified()
+	return curException, nil
+}
+
+// DeletePolicyExceptionByID ...
+func DeletePolicyExceptionByID(id int64) error {
+	for i, policyException := range policyExceptions {
+		if policyException.ID == id {
+			data.DAL.DeletePolicyExceptionByID(id)
+			policyExceptions = append(policyExceptions[:i], policyExceptions[i+1:]...)
+			exceptionRegexes.Delete(id)
+			data.UpdateFirewallLastModified()
+			return nil
+		}
+	}
+	return errors.New("Not found")
+}
+
+// AddPolicyExceptionFromHitLog create an exception for the policy and url path of a group hit log,
+// param object can designate param_name and username
+func AddPolicyExceptionFromHitLog(param map[string]interface{}, userID int64) (*models.PolicyException, error) {
+	logID := int64(param["id"].(float64))
+	hitLog, err := data.DAL.SelectGroupHitLogByID(logID)
+	if err != nil {
+		return nil, err
+	}
+	policyException := &models.PolicyException{
+		Description: "Created from hit log " + strconv.FormatInt(logID, 10),
+		AppID:       hitLog.AppID,
+		PolicyID:    hitLog.PolicyID,
+		URLPath:     "^" + regexp.QuoteMeta(hitLog.UrlPath) + "$",
+		IsEnabled:   true,
+		UserID:      userID}
+	if obj, ok := param["object"].(map[string]interface{}); ok {
+		if paramName, ok := obj["param_name"].(string); ok {
+			policyException.ParamName = paramName
+		}
+		if username, ok := obj["username"].(string); ok {
+			policyException.Username = username
+		}
+		if allPath, ok := obj["all_path"].(bool); ok && allPath {
+			policyException.URLPath = ""
+		}
+	}
+	return savePolicyException(policyException)
+}
+
+// GetAuthUsername return the authenticated user set by gateway, empty if not authenticated
+func GetAuthUsername(r *http.Request) string {
+	if username, ok := r.Context().Value("authUser").(string); ok {
+		return username
+	}
+	return ""
+}
+
+// LoadRequestPolicyExceptions filter exceptions by application, url path and user,
+// and store them in groupPolicyHitValue map for IsMatchGroupPolicy
+func LoadRequestPolicyExceptions(hitValueMap *sync.Map, r *http.Request, appID int64) {
+	var exceptions []*models.PolicyException
+	username := GetAuthUsername(r)
+	for _, policyException := range policyExceptions {
+		if !policyException.IsEnabled {
+			continue
+		}
+		if policyException.AppID != 0 && policyException.AppID != appID {
+			continue
+		}
+		if len(policyException.Username) > 0 && policyException.Username != username {
+			continue
+		}
+		if len(policyException.URLPath) > 0 {
+			regexI, ok := exceptionRegexes.Load(policyException.ID)
+			if !ok || !regexI.(*regexp.Regexp).MatchString(r.URL.Path) {
+				continue
+			}
+		}
+		exceptions = append(exceptions, policyException)
+	}
+	hitValueMap.Store(policyExceptionsKey{}, exceptions)
+}
+
+// IsPolicyExcepted check whether the group policy is skipped for the parameter
+func IsPolicyExcepted(hitValueMap *sync.Map, groupPolicy *models.GroupPolicy, paramName string) bool {
+	exceptionsI, ok := hitValueMap.Load(policyExceptionsKey{})
+	if !ok {
+		return false
+	}
+	for _, policyException := range exceptionsI.([]*models.PolicyException) {
+		if policyException.PolicyID != 0 && policyException.PolicyID != groupPolicy.ID {
+			continue
+		}
+		if policyException.VulnID != 0 && policyException.VulnID != groupPolicy.VulnID {
+			continue
+		}
+		if len(policyException.ParamName) > 0 && policyException.ParamName != paramName {
+			continue
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,370 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 19:10:26
+ * @Last Modified: U2, 2026-10-19 19:10:26
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+var (
+	rateLimitRules []*models.RateLimitRule
+	// rateLimitRegexes map[int64]*regexp.Regexp, compiled URLPath of rules
+	rateLimitRegexes sync.Map
+	// rateLimitStates map[int64]*sync.Map, rule ID, key, *rateLimitState
+	rateLimitStates sync.Map
+)
+
+// rateLimitState is the token bucket or the counts of sliding window
+type rateLimitState struct {
+	mutex      sync.Mutex
+	tokens     float64
+	prevCount  int64
+	curCount   int64
+	updateTime time.Time
+	// windowStart is the start time of current window of sliding window
+	windowStart time.Time
+	// isLogged avoid logging each request after limited
+	isLogged bool
+}
+
+// RateLimitStatus is used for RateLimit-* and Retry-After headers
+type RateLimitStatus struct {
+	Limit     int64
+	Remaining int64
+	// ResetSeconds is the time until the quota is fully restored
+	ResetSeconds int64
+	// RetryAfter is the time until next request is allowed, 0 if allowed
+	RetryAfter int64
+	NeedLog    bool
+}
+
+// InitRateLimitRule ...
+func InitRateLimitRule() {
+	if data.IsMaster {
+		data.DAL.CreateTableIfNotExistsRateLimitRules()
+		rateLimitRules = data.DAL.SelectRateLimitRules()
+	} else {
+		rateLimitRules = RPCSelectRateLimitRules()
+	}
+	for _, rule := range rateLimitRules {
+		compileRateLimitRegex(rule)
+	}
+}
+
+func compileRateLimitRegex(rule *models.RateLimitRule) error {
+	if len(rule.URLPath) == 0 {
+		rateLimitRegexes.Delete(rule.ID)
+		return nil
+	}
+	regex, err := regexp.Compile(rule.URLPath)
+	utils.CheckError("compileRateLimitRegex", err)
+	if err != nil {
+		return err
+	}
+	rateLimitRegexes.Store(rule.ID, regex)
+	return nil
+}
+
+// GetRateLimitRules ...
+func GetRateLimitRules() ([]*models.RateLimitRule, error) {
+	return rateLimitRules, nil
+}
+
+// GetRateLimitRuleByID ...
+func GetRateLimitRuleByID(id int64) (*models.RateLimitRule, error) {
+	for _, rule := range rateLimitRules {
+		if rule.ID == id {
+			return rule, nil
+		}
+	}
+	return nil, errors.New("Not found")
+}
+
+// UpdateRateLimitRule ...
+func UpdateRateLimitRule(r *http.Request, userID int64) (*models.RateLimitRule, error) {
+	var setRateLimitRuleRequest models.RPCSetRateLimitRule
+	err := json.NewDecoder(r.Body).Decode(&setRateLimitRuleRequest)
+	defer r.Body.Close()
+	utils.CheckError("UpdateRateLimitRule Decode", err)
+	curRule := setRateLimitRuleRequest.Object
+	if curRule == nil {
+		return nil, errors.New("UpdateRateLimitRule parse body null")
+	}
+	if curRule.Limit <= 0 || curRule.PeriodSeconds <= 0 {
+		return nil, errors.New("limit and period_seconds should be greater than 0")
+	}
+	if curRule.KeyType&(models.RateLimitKey_Header|models.RateLimitKey_Cookie) > 0 && len(curRule.KeyName) == 0 {
+		return nil, errors.New("key_name is required for header or cookie")
+	}
+	if _, err := regexp.Compile(curRule.URLPath); err != nil {
+		return nil, err
+	}
+	curRule.UserID = userID
+	curRule.UpdateTime = time.Now().Unix()
+	if curRule.ID == 0 {
+		newID, err := data.DAL.InsertRateLimitRule(curRule)
+		if err != nil {
+			return nil, err
+		}
+		curRule.ID = newID
+		rateLimitRules = append(rateLimitRules, curRule)
+	} else {
+		rule, err := GetRateLimitRuleByID(curRule.ID)
+		if err != nil {
+			return nil, err
+		}
+		err = data.DAL.UpdateRateLimitRule(curRule)
+		if err != nil {
+			return nil, err
+		}
+		*rule = *curRule
+		curRule = rule
+	}
+	compileRateLimitRegex(curRule)
+	rateLimitStates.Delete(curRule.ID)
+	data.UpdateFirewallLastModified()
+	return curRule, nil
+}
+
+// DeleteRateLimitRuleByID ...
+func DeleteRateLimitRuleByID(id int64) error {
+	for i, rule := range rateLimitRules {
+		if rule.ID == id {
+			data.DAL.DeleteRateLimitRuleByID(id)
+			rateLimitRules = append(rateLimitRules[:i], rateLimitRules[i+1:]...)
+			rateLimitRegexes.Delete(id)
+			rateLimitStates.Delete(id)
+			data.UpdateFirewallLastModified()
+			return nil
+		}
+	}
+	return errors.New("Not found")
+}
+
+// GetRateLimitKey compose the key of request by the key type of rule,
+// return false if the designated attribute is absent
+func GetRateLimitKey(r *http.Request, rule *models.RateLimitRule, srcIP string) (string, bool) {
+	keyType := rule.KeyType
+	if keyType == 0 {
+		keyType = models.RateLimitKey_IP
+	}
+	var parts []string
+	if keyType&models.RateLimitKey_IP > 0 {
+		parts = append(parts, srcIP)
+	}
+	if keyType&models.RateLimitKey_CIDR > 0 {
+		parts = append(parts, getIPNetwork(srcIP, rule.CIDRPrefix))
+	}
+	if keyType&models.RateLimitKey_Header > 0 {
+		value := r.Header.Get(rule.KeyName)
+		if len(value) == 0 {
+			return "", false
+		}
+		parts = append(parts, value)
+	}
+	if keyType&models.RateLimitKey_Cookie > 0 {
+		cookie, err := r.Cookie(rule.KeyName)
+		if err != nil || len(cookie.Value) == 0 {
+			return "", false
+		}
+		parts = append(parts, cookie.Value)
+	}
+	if keyType&models.RateLimitKey_User > 0 {
+		username := GetAuthUsername(r)
+		if len(username) == 0 {
+			return "", false
+		}
+		parts = append(parts, username)
+	}
+	if keyType&models.RateLimitKey_APIKey > 0 {
+		apiKey := getAPIKey(r)
+		if len(apiKey) == 0 {
+			return "", false
+		}
+		parts = append(parts, apiKey)
+	}
+	if keyType&models.RateLimitKey_Path > 0 {
+		parts = append(parts, r.URL.Path)
+	}
+	return data.SHA256Hash(strings.Join(parts, "\n")), true
+}
+
+func getIPNetwork(srcIP string, prefix int64) string {
+	ip := net.ParseIP(srcIP)
+	if ip == nil {
+		return srcIP
+	}
+	if ipv4 := ip.To4(); ipv4 != nil {
+		if prefix <= 0 || prefix > 32 {
+			prefix = 24
+		}
+		return ipv4.Mask(net.CIDRMask(int(prefix), 32)).String() + "/" + strconv.FormatInt(prefix, 10)
+	}
+	return ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
+
+func getAPIKey(r *http.Request) string {
+	if apiKey := r.Header.Get("X-API-Key"); len(apiKey) > 0 {
+		return apiKey
+	}
+	if authorization := r.Header.Get("Authorization"); len(authorization) > 0 {
+		return authorization
+	}
+	return r.URL.Query().Get("api_key")
+}
+
+// IsRateLimited check the request against all rate limit rules of the application,
+// return the exceeded rule, or the status of the strictest rule for response headers
+func IsRateLimited(r *http.Request, appID int64, srcIP string) (bool, *models.RateLimitRule, *RateLimitStatus) {
+	var strictestStatus *RateLimitStatus
+	var strictestRule *models.RateLimitRule
+	now := time.Now()
+	for _, rule := range rateLimitRules {
+		if !rule.IsEnabled || (rule.AppID != 0 && rule.AppID != appID) {
+			continue
+		}
+		if len(rule.URLPath) > 0 {
+			regexI, ok := rateLimitRegexes.Load(rule.ID)
+			if !ok || !regexI.(*regexp.Regexp).MatchString(r.URL.Path) {
+				continue
+			}
+		}
+		key, ok := GetRateLimitKey(r, rule, srcIP)
+		if !ok {
+			continue
+		}
+		if rule.AppID == 0 {
+			// Global rule is counted by application
+			key = strconv.FormatInt(appID, 10) + ":" + key
+		}
+		ruleStatesI, _ := rateLimitStates.LoadOrStore(rule.ID, &sync.Map{})
+		stateI, _ := ruleStatesI.(*sync.Map).LoadOrStore(key, &rateLimitState{updateTime: now, windowStart: now, tokens: -1})
+		status := stateI.(*rateLimitState).take(rule, now)
+		if status.RetryAfter > 0 {
+			return true, rule, status
+		}
+		if strictestStatus == nil || status.Remaining < strictestStatus.Remaining {
+			strictestStatus = status
+			strictestRule = rule
+		}
+	}
+	return false, strictestRule, strictestStatus
+}
+
+// take consume one request from the state
+func (state *rateLimitState) take(rule *models.RateLimitRule, now time.Time) *RateLimitStatus {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	period := float64(rule.PeriodSeconds)
+	status := &RateLimitStatus{Limit: rule.Limit}
+	if rule.Algorithm == models.RateLimit_SlidingWindow {
+		// weighted count of previous window and current window
+		elapsed := now.Sub(state.windowStart).Seconds()
+		if elapsed >= 2*period {
+			state.prevCount, state.curCount = 0, 0
+			state.windowStart = now
+			elapsed = 0
+		} else if elapsed >= period {
+			state.prevCount, state.curCount = state.curCount, 0
+			state.windowStart = state.windowStart.Add(time.Duration(rule.PeriodSeconds) * time.Second)
+			elapsed -= period
+		}
+		estimated := float64(state.prevCount)*(1-elapsed/period) + float64(state.curCount)
+		status.ResetSeconds = int64(math.Ceil(2*period - elapsed))
+		if estimated+1 > float64(rule.Limit) {
+			retryAfter := period - elapsed
+			if state.prevCount > 0 && state.curCount+1 <= rule.Limit {
+				// until the weight of previous window decreases enough
+				retryAfter = period*(1-float64(rule.Limit-state.curCount-1)/float64(state.prevCount)) - elapsed
+			}
+			status.RetryAfter = int64(math.Max(1, math.Ceil(retryAfter)))
+		} else {
+			state.curCount++
+			status.Remaining = int64(float64(rule.Limit) - estimated - 1)
+		}
+	} else {
+		capacity := float64(rule.Burst)
+		if capacity <= 0 {
+			capacity = float64(rule.Limit)
+		}
+		rate := float64(rule.Limit) / period
+		if state.tokens < 0 {
+			state.tokens = capacity
+		} else {
+			state.tokens = math.Min(capacity, state.tokens+now.Sub(state.updateTime).Seconds()*rate)
+		}
+		state.updateTime = now
+		if state.tokens < 1 {
+			status.RetryAfter = int64(math.Max(1, math.Ceil((1-state.tokens)/rate)))
+		} else {
+			state.tokens--
+			status.Remaining = int64(state.tokens)
+		}
+		status.Limit = int64(capacity)
+		status.ResetSeconds = int64(math.Ceil((capacity - state.tokens) / rate))
+	}
+	if status.RetryAfter > 0 {
+		status.NeedLog = !state.isLogged
+		state.isLogged = true
+	} else {
+		state.isLogged = false
+	}
+	state.updateTime = now
+	return status
+}
+
+// SetRateLimitHeaders set RateLimit-* headers of the strictest rule
+func SetRateLimitHeaders(header http.Header, status *RateLimitStatus) {
+	header.Set("RateLimit-Limit", strconv.FormatInt(status.Limit, 10))
+	header.Set("RateLimit-Remaining", strconv.FormatInt(status.Remaining, 10))
+	header.Set("RateLimit-Reset", strconv.FormatInt(status.ResetSeconds, 10))
+}
+
+// SetRetryAfterHeader set Retry-After for the request rejected with 429
+func SetRetryAfterHeader(header http.Header, status *RateLimitStatus) {
+	if status.RetryAfter > 0 {
+		header.Set("Retry-After", strconv.FormatInt(status.RetryAfter, 10))
+	}
+}
+
+// RateLimitTick remove idle states of rate limit rules
+func RateLimitTick() {
+	rateLimitTicker := time.NewTicker(time.Duration(60) * time.Second)
+	for range rateLimitTicker.C {
+		now := time.Now()
+		for _, rule := range rateLimitRules {
+			ruleStatesI, ok := rateLimitStates.Load(rule.ID)
+			if !ok {
+				continue
+			}
+			idleTime := 2 * time.Duration(rule.PeriodSeconds) * time.Second
+			ruleStatesI.(*sync.Map).Range(func(key, value interface{}) bool {
+				state := value.(*rateLimitState)
+				state.mutex.Lock()
+				if now.Sub(state.updateTime) > idleTime {
+					ruleStatesI.(*sync.Map).Delete(key)
+				}
+				state.mutex.Unlock()
+				return true
+			})
+		}
+	}
+}
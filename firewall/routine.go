@@ -26,6 +26,8 @@ func RoutineTick() {
 			expiredTime := time.Now().Unix() - logExpireSeconds
 			data.DAL.DeleteHitLogsBeforeTime(expiredTime)
 			data.DAL.DeleteCCLogsBeforeTime(expiredTime)
+			data.DAL.DeleteAPIViolationLogsBeforeTime(expiredTime)
+			data.DAL.DeleteMaskingLogsBeforeTime(expiredTime)
 		}
 	}
 }
@@ -0,0 +1,34 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 17:12:04
+ * @Last Modified: U2, 2026-10-19 17:12:04
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// RPCSelectAPISchemas ...
+func RPCSelectAPISchemas() (apiSchemas []*models.APISchema) {
+	rpcRequest := &models.RPCRequest{
+		Action: "getapischemas", Object: nil}
+	resp, err := data.GetRPCResponse(rpcRequest)
+	if err != nil {
+		utils.CheckError("RPCSelectAPISchemas GetResponse", err)
+		return nil
+	}
+	rpcAPISchemas := new(models.RPCAPISchemas)
+	if err := json.Unmarshal(resp, rpcAPISchemas); err != nil {
+		utils.CheckError("RPCSelectAPISchemas Unmarshal", err)
+		return nil
+	}
+	apiSchemas = rpcAPISchemas.Object
+	return apiSchemas
+}
@@ -0,0 +1,34 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 22:19:05
+ * @Last Modified: U2, 2026-10-19 22:19:05
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// RPCSelectBotPolicies ...
+func RPCSelectBotPolicies() (botPolicies []*models.BotPolicy) {
+	rpcRequest := &models.RPCRequest{
+		Action: "getbotpolicies", Object: nil}
+	resp, err := data.GetRPCResponse(rpcRequest)
+	if err != nil {
+		utils.CheckError("RPCSelectBotPolicies GetResponse", err)
+		return nil
+	}
+	rpcBotPolicies := new(models.RPCBotPolicies)
+	if err := json.Unmarshal(resp, rpcBotPolicies); err != nil {
+		utils.CheckError("RPCSelectBotPolicies Unmarshal", err)
+		return nil
+	}
+	botPolicies = rpcBotPolicies.Object
+	return botPolicies
+}
@@ -0,0 +1,34 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 20:46:19
+ * @Last Modified: U2, 2026-10-19 20:46:19
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// RPCSelectCountryPolicies ...
+func RPCSelectCountryPolicies() (countryPolicies []*models.CountryPolicy) {
+	rpcRequest := &models.RPCRequest{
+		Action: "getcountrypolicies", Object: nil}
+	resp, err := data.GetRPCResponse(rpcRequest)
+	if err != nil {
+		utils.CheckError("RPCSelectCountryPolicies GetResponse", err)
+		return nil
+	}
+	rpcCountryPolicies := new(models.RPCCountryPolicies)
+	if err := json.Unmarshal(resp, rpcCountryPolicies); err != nil {
+		utils.CheckError("RPCSelectCountryPolicies Unmarshal", err)
+		return nil
+	}
+	countryPolicies = rpcCountryPolicies.Object
+	return countryPolicies
+}
@@ -0,0 +1,34 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 19:58:05
+ * @Last Modified: U2, 2026-10-19 19:58:05
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// RPCSelectIPListEntries ...
+func RPCSelectIPListEntries() (ipListEntries []*models.IPListEntry) {
+	rpcRequest := &models.RPCRequest{
+		Action: "getiplistentries", Object: nil}
+	resp, err := data.GetRPCResponse(rpcRequest)
+	if err != nil {
+		utils.CheckError("RPCSelectIPListEntries GetResponse", err)
+		return nil
+	}
+	rpcIPListEntries := new(models.RPCIPListEntries)
+	if err := json.Unmarshal(resp, rpcIPListEntries); err != nil {
+		utils.CheckError("RPCSelectIPListEntries Unmarshal", err)
+		return nil
+	}
+	ipListEntries = rpcIPListEntries.Object
+	return ipListEntries
+}
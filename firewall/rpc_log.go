@@ -28,3 +28,19 @@ func RPCCCLog(ccLog *models.CCLog) {
 	_, err := data.GetRPCResponse(rpcRequest)
 	utils.CheckError("RPCCCLog", err)
 }
+
+// RPCAPIViolationLog ...
+func RPCAPIViolationLog(violationLog *models.APIViolationLog) {
+	rpcRequest := &models.RPCRequest{
+		Action: "log_api_violation", Object: violationLog}
+	_, err := data.GetRPCResponse(rpcRequest)
+	utils.CheckError("RPCAPIViolationLog", err)
+}
+
+// RPCMaskingLog ...
+func RPCMaskingLog(maskingLog *models.MaskingLog) {
+	rpcRequest := &models.RPCRequest{
+		Action: "log_masking", Object: maskingLog}
+	_, err := data.GetRPCResponse(rpcRequest)
+	utils.CheckError("RPCMaskingLog", err)
+}
@@ -0,0 +1,34 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 23:27:44
+ * @Last Modified: U2, 2026-10-19 23:27:44
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// RPCSelectMaskingRules ...
+func RPCSelectMaskingRules() (maskingRules []*models.MaskingRule) {
+	rpcRequest := &models.RPCRequest{
+		Action: "getmaskingrules", Object: nil}
+	resp, err := data.GetRPCResponse(rpcRequest)
+	if err != nil {
+		utils.CheckError("RPCSelectMaskingRules GetResponse", err)
+		return nil
+	}
+	rpcMaskingRules := new(models.RPCMaskingRules)
+	if err := json.Unmarshal(resp, rpcMaskingRules); err != nil {
+		utils.CheckError("RPCSelectMaskingRules Unmarshal", err)
+		return nil
+	}
+	maskingRules = rpcMaskingRules.Object
+	return maskingRules
+}
@@ -0,0 +1,34 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 11:48:12
+ * @Last Modified: U2, 2026-10-19 11:48:12
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// RPCSelectPolicyExceptions ...
+func RPCSelectPolicyExceptions() (policyExceptions []*models.PolicyException) {
+	rpcRequest := &models.RPCRequest{
+		Action: "getpolicyexceptions", Object: nil}
+	resp, err := data.GetRPCResponse(rpcRequest)
+	if err != nil {
+		utils.CheckError("RPCSelectPolicyExceptions GetResponse", err)
+		return nil
+	}
+	rpcPolicyExceptions := new(models.RPCPolicyExceptions)
+	if err := json.Unmarshal(resp, rpcPolicyExceptions); err != nil {
+		utils.CheckError("RPCSelectPolicyExceptions Unmarshal", err)
+		return nil
+	}
+	policyExceptions = rpcPolicyExceptions.Object
+	return policyExceptions
+}
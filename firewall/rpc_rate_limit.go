@@ -0,0 +1,34 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 19:16:40
+ * @Last Modified: U2, 2026-10-19 19:16:40
+ */
+
+package firewall
+
+import (
+	"encoding/json"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// RPCSelectRateLimitRules ...
+func RPCSelectRateLimitRules() (rateLimitRules []*models.RateLimitRule) {
+	rpcRequest := &models.RPCRequest{
+		Action: "getratelimitrules", Object: nil}
+	resp, err := data.GetRPCResponse(rpcRequest)
+	if err != nil {
+		utils.CheckError("RPCSelectRateLimitRules GetResponse", err)
+		return nil
+	}
+	rpcRateLimitRules := new(models.RPCRateLimitRules)
+	if err := json.Unmarshal(resp, rpcRateLimitRules); err != nil {
+		utils.CheckError("RPCSelectRateLimitRules Unmarshal", err)
+		return nil
+	}
+	rateLimitRules = rpcRateLimitRules.Object
+	return rateLimitRules
+}
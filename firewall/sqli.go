@@ -0,0 +1,405 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 09:12:40
+ * @Last Modified: U2, 2026-10-20 09:06:13
+ */
+
+package firewall
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SQL token types, similar to libinjection fingerprints
+const (
+	sqliTypeString    byte = 's'
+	sqliTypeNumber    byte = '1'
+	sqliTypeBareword  byte = 'n'
+	sqliTypeKeyword   byte = 'k'
+	sqliTypeUnion     byte = 'U'
+	sqliTypeStatement byte = 'E'
+	sqliTypeGroup     byte = 'B'
+	sqliTypeTSQL      byte = 'T'
+	sqliTypeFunction  byte = 'f'
+	sqliTypeLogic     byte = '&'
+	sqliTypeOperator  byte = 'o'
+	sqliTypeVariable  byte = 'v'
+	sqliTypeComment   byte = 'c'
+	sqliTypeLeftPar   byte = '('
+	sqliTypeRightPar  byte = ')'
+	sqliTypeComma     byte = ','
+	sqliTypeSemicolon byte = ';'
+
+	// sqliMaxTokens is the length of fingerprint
+	sqliMaxTokens = 8
+)
+
+type sqliToken struct {
+	Type  byte
+	Value string
+}
+
+var sqliKeywords = map[string]byte{
+	"and": sqliTypeLogic, "or": sqliTypeLogic, "xor": sqliTypeLogic,
+	"like": sqliTypeOperator, "rlike": sqliTypeOperator, "regexp": sqliTypeOperator,
+	"between": sqliTypeOperator, "is": sqliTypeOperator, "not": sqliTypeOperator,
+	"in": sqliTypeOperator, "div": sqliTypeOperator, "mod": sqliTypeOperator,
+	"sounds": sqliTypeOperator, "collate": sqliTypeOperator,
+	"union":  sqliTypeUnion,
+	"select": sqliTypeStatement, "insert": sqliTypeStatement, "update": sqliTypeStatement,
+	"delete": sqliTypeStatement, "drop": sqliTypeStatement, "create": sqliTypeStatement,
+	"alter": sqliTypeStatement, "exec": sqliTypeStatement, "execute": sqliTypeStatement,
+	"declare": sqliTypeStatement, "truncate": sqliTypeStatement, "shutdown": sqliTypeStatement,
+	"rename": sqliTypeStatement, "grant": sqliTypeStatement, "revoke": sqliTypeStatement,
+	"from": sqliTypeKeyword, "where": sqliTypeKeyword, "into": sqliTypeKeyword,
+	"table": sqliTypeKeyword, "database": sqliTypeKeyword, "values": sqliTypeKeyword,
+	"as": sqliTypeKeyword, "case": sqliTypeKeyword, "when": sqliTypeKeyword,
+	"then": sqliTypeKeyword, "else": sqliTypeKeyword, "end": sqliTypeKeyword,
+	"distinct": sqliTypeKeyword, "procedure": sqliTypeKeyword, "outfile": sqliTypeKeyword,
+	"dumpfile": sqliTypeKeyword, "master": sqliTypeKeyword, "top": sqliTypeKeyword,
+	"having": sqliTypeGroup, "limit": sqliTypeGroup, "offset": sqliTypeGroup,
+	"waitfor": sqliTypeTSQL,
+	"null":    sqliTypeNumber, "true": sqliTypeNumber, "false": sqliTypeNumber,
+}
+
+// sqliFunctions are only recognized as functions when followed by `(`
+var sqliFunctions = map[string]bool{
+	"sleep": true, "benchmark": true, "pg_sleep": true, "load_file": true, "updatexml": true,
+	"extractvalue": true, "concat": true, "concat_ws": true, "group_concat": true,
+	"char": true, "chr": true, "ascii": true, "ord": true, "substr": true, "substring": true,
+	"mid": true, "left": true, "right": true, "length": true, "len": true, "count": true,
+	"user": true, "database": true, "version": true, "current_user": true, "system_user": true,
+	"session_user": true, "schema": true, "hex": true, "unhex": true, "if": true, "ifnull": true,
+	"iif": true, "rand": true, "floor": true, "exp": true, "cast": true, "convert": true,
+	"md5": true, "sha1": true, "row_count": true, "name_const": true, "json_extract": true,
+	"xp_cmdshell": true, "randomblob": true, "sqlite_version": true, "pg_read_file": true,
+	"lo_import": true, "elt": true, "make_set": true, "geometrycollection": true,
+	"utl_inaddr.get_host_address": true, "dbms_pipe.receive_message": true, "db_name": true,
+}
+
+// sqliCriticalFunctions are rarely seen in benign input following an operator
+var sqliCriticalFunctions = map[string]bool{
+	"sleep": true, "benchmark": true, "pg_sleep": true, "load_file": true, "updatexml": true,
+	"extractvalue": true, "xp_cmdshell": true, "name_const": true, "randomblob": true,
+	"pg_read_file": true, "lo_import": true, "geometrycollection": true,
+	"utl_inaddr.get_host_address": true, "dbms_pipe.receive_message": true,
+}
+
+// IsSQLInjection detects SQL injection by tokenizing the value in three contexts
+// (as is, inside single quotes, inside double quotes) and checking the fingerprints
+func IsSQLInjection(value string) bool {
+	if len(value) == 0 {
+		return false
+	}
+	if isSQLiFingerprint(sqliTokenize(value, 0), false) {
+		return true
+	}
+	if strings.ContainsRune(value, '\'') && isSQLiFingerprint(sqliTokenize(value, '\''), true) {
+		return true
+	}
+	if strings.ContainsRune(value, '"') && isSQLiFingerprint(sqliTokenize(value, '"'), true) {
+		return true
+	}
+	return false
+}
+
+// GetSQLiFingerprint return fingerprint of value in designated quote context, used for debug
+func GetSQLiFingerprint(value string, quote byte) string {
+	tokens := sqliTokenize(value, quote)
+	fingerprint := make([]byte, len(tokens))
+	for i, token := range tokens {
+		fingerprint[i] = token.Type
+	}
+	return string(fingerprint)
+}
+
+func isSQLiFingerprint(tokens []sqliToken, quoted bool) bool {
+	count := len(tokens)
+	if count < 2 {
+		return false
+	}
+	typeAt := func(i int) byte {
+		if i < 0 || i >= count {
+			return 0
+		}
+		return tokens[i].Type
+	}
+	isValue := func(t byte) bool {
+		return t == sqliTypeString || t == sqliTypeNumber
+	}
+	// Breakout by quote and truncate the rest by comment, such as admin'-- or admin')#
+	if quoted {
+		i := 1
+		for typeAt(i) == sqliTypeRightPar {
+			i++
+		}
+		if typeAt(i) == sqliTypeComment && i == count-1 {
+			return true
+		}
+	}
+	for i := 0; i < count; i++ {
+		t := tokens[i].Type
+		switch t {
+		case sqliTypeUnion:
+			// union select, union (select
+			next := typeAt(i + 1)
+			if next == sqliTypeStatement || (next == sqliTypeLeftPar && typeAt(i+2) == sqliTypeStatement) {
+				return true
+			}
+		case sqliTypeSemicolon:
+			// stacked queries: 1; drop table users, but not a call like Update(2024) in text
+			isStatement := typeAt(i+1) == sqliTypeStatement && (typeAt(i+2) != sqliTypeLeftPar || tokens[i+1].Value == "select")
+			if isStatement || typeAt(i+1) == sqliTypeTSQL {
+				for j := i + 2; j < count; j++ {
+					switch typeAt(j) {
+					case sqliTypeKeyword, sqliTypeNumber, sqliTypeString, sqliTypeVariable,
+						sqliTypeFunction, sqliTypeLeftPar, sqliTypeOperator, sqliTypeComment:
+						return true
+					}
+				}
+				if typeAt(i+1) == sqliTypeTSQL && i+2 < count {
+					return true
+				}
+			}
+		case sqliTypeTSQL:
+			// waitfor delay '0:0:5'
+			if typeAt(i+1) == sqliTypeString {
+				return true
+			}
+		case sqliTypeFunction:
+			prev := typeAt(i - 1)
+			if sqliCriticalFunctions[tokens[i].Value] && typeAt(i+1) == sqliTypeLeftPar {
+				switch prev {
+				case sqliTypeLogic, sqliTypeOperator, sqliTypeSemicolon, sqliTypeComma, sqliTypeLeftPar:
+					return true
+				}
+			}
+		case sqliTypeStatement:
+			// 1'=(select ...) or 1 and (select ...)
+			if typeAt(i-1) == sqliTypeLeftPar && tokens[i].Value == "select" {
+				prev := typeAt(i - 2)
+				if prev == sqliTypeOperator || prev == sqliTypeLogic {
+					return true
+				}
+			}
+		}
+	}
+	// Only values at the beginning are meaningful for boolean based injection
+	first := typeAt(0)
+	if !isValue(first) {
+		return false
+	}
+	i := 1
+	for typeAt(i) == sqliTypeRightPar {
+		i++
+	}
+	switch typeAt(i) {
+	case sqliTypeLogic:
+		second := typeAt(i + 1)
+		third := typeAt(i + 2)
+		switch second {
+		case sqliTypeFunction:
+			// ' and sleep(5)
+			return third == sqliTypeLeftPar
+		case sqliTypeLeftPar:
+			// 1 or (1=1)
+			return true
+		case sqliTypeVariable:
+			// ' or @@version
+			return true
+		case sqliTypeString, sqliTypeNumber, sqliTypeBareword:
+			// 1 or 1=1, ' or 'a'='a
+			if third == sqliTypeOperator {
+				fourth := typeAt(i + 3)
+				switch fourth {
+				case sqliTypeString, sqliTypeNumber, sqliTypeBareword, sqliTypeVariable,
+					sqliTypeFunction, sqliTypeLeftPar:
+					return true
+				}
+			}
+			// ' or 1-- , 1 and 1#
+			if third == sqliTypeComment && second != sqliTypeBareword {
+				return true
+			}
+		}
+	case sqliTypeGroup:
+		// ' order by 3-- , 1 having 1=1
+		return typeAt(i+1) == sqliTypeNumber
+	case sqliTypeOperator:
+		// 'x'='x' or 1 || 1 in quote context
+		if quoted && isValue(typeAt(i+1)) && typeAt(i+2) == sqliTypeLogic {
+			return true
+		}
+	}
+	return false
+}
+
+func isSQLiSpace(ch rune) bool {
+	return unicode.IsSpace(ch) || ch == 0 || ch == ' '
+}
+
+func isSQLiWordChar(ch byte) bool {
+	return ch == '_' || ch == '$' || ch == '.' || ch >= 0x80 ||
+		(ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+// sqliTokenize split value into SQL tokens, quote != 0 means value is placed within quote
+func sqliTokenize(value string, quote byte) []sqliToken {
+	if quote != 0 {
+		value = string(quote) + value
+	}
+	var tokens []sqliToken
+	n := len(value)
+	pos := 0
+	for pos < n && len(tokens) < sqliMaxTokens {
+		ch := value[pos]
+		switch {
+		case ch < 0x80 && isSQLiSpace(rune(ch)):
+			pos++
+		case ch == '\'' || ch == '"':
+			end := sqliStringEnd(value, pos+1, ch)
+			tokens = append(tokens, sqliToken{Type: sqliTypeString, Value: value[pos:end]})
+			pos = end
+		case ch == '`':
+			// MySQL quoted identifier
+			end := strings.IndexByte(value[pos+1:], '`')
+			if end < 0 {
+				end = n
+			} else {
+				end += pos + 2
+			}
+			tokens = append(tokens, sqliToken{Type: sqliTypeBareword, Value: value[pos:end]})
+			pos = end
+		case ch == '#':
+			tokens = append(tokens, sqliToken{Type: sqliTypeComment, Value: value[pos:]})
+			pos = n
+		case ch == '-' && pos+1 < n && value[pos+1] == '-':
+			tokens = append(tokens, sqliToken{Type: sqliTypeComment, Value: value[pos:]})
+			pos = n
+		case ch == '/' && pos+1 < n && value[pos+1] == '*':
+			if pos+2 < n && value[pos+2] == '!' {
+				// MySQL executable comment /*!50000select*/, treat content as code
+				pos += 3
+				for pos < n && value[pos] >= '0' && value[pos] <= '9' {
+					pos++
+				}
+				continue
+			}
+			end := strings.Index(value[pos+2:], "*/")
+			if end < 0 {
+				tokens = append(tokens, sqliToken{Type: sqliTypeComment, Value: value[pos:]})
+				pos = n
+			} else {
+				// inline comment is the same as whitespace
+				pos += end + 4
+			}
+		case ch == '*' && pos+1 < n && value[pos+1] == '/':
+			// closing of MySQL executable comment
+			pos += 2
+		case ch == '(':
+			tokens = append(tokens, sqliToken{Type: sqliTypeLeftPar, Value: "("})
+			pos++
+		case ch == ')':
+			tokens = append(tokens, sqliToken{Type: sqliTypeRightPar, Value: ")"})
+			pos++
+		case ch == ',':
+			tokens = append(tokens, sqliToken{Type: sqliTypeComma, Value: ","})
+			pos++
+		case ch == ';':
+			tokens = append(tokens, sqliToken{Type: sqliTypeSemicolon, Value: ";"})
+			pos++
+		case ch == '@':
+			end := pos + 1
+			for end < n && (value[end] == '@' || isSQLiWordChar(value[end])) {
+				end++
+			}
+			tokens = append(tokens, sqliToken{Type: sqliTypeVariable, Value: value[pos:end]})
+			pos = end
+		case (ch >= '0' && ch <= '9') || (ch == '.' && pos+1 < n && value[pos+1] >= '0' && value[pos+1] <= '9'):
+			end := pos + 1
+			if ch == '0' && end < n && (value[end] == 'x' || value[end] == 'X' || value[end] == 'b' || value[end] == 'B') {
+				end++
+			}
+			for end < n && (isSQLiWordChar(value[end]) && value[end] < 0x80) {
+				end++
+			}
+			tokens = append(tokens, sqliToken{Type: sqliTypeNumber, Value: value[pos:end]})
+			pos = end
+		case ch == '&' || ch == '|':
+			if pos+1 < n && value[pos+1] == ch {
+				tokens = append(tokens, sqliToken{Type: sqliTypeLogic, Value: value[pos : pos+2]})
+				pos += 2
+			} else {
+				tokens = append(tokens, sqliToken{Type: sqliTypeOperator, Value: value[pos : pos+1]})
+				pos++
+			}
+		case strings.IndexByte("=<>!+-*/%^~:", ch) >= 0:
+			end := pos + 1
+			for end < n && strings.IndexByte("=<>!:", value[end]) >= 0 {
+				end++
+			}
+			tokens = append(tokens, sqliToken{Type: sqliTypeOperator, Value: value[pos:end]})
+			pos = end
+		case isSQLiWordChar(ch):
+			end := pos + 1
+			for end < n && isSQLiWordChar(value[end]) {
+				end++
+			}
+			word := strings.ToLower(value[pos:end])
+			pos = end
+			tokens = sqliAppendWord(tokens, word, value[pos:])
+		default:
+			// unknown punctuation, such as unicode characters
+			pos++
+		}
+	}
+	return tokens
+}
+
+func sqliStringEnd(value string, pos int, quote byte) int {
+	n := len(value)
+	for pos < n {
+		ch := value[pos]
+		if ch == '\\' {
+			pos += 2
+			continue
+		}
+		if ch == quote {
+			if pos+1 < n && value[pos+1] == quote {
+				// doubled quote
+				pos += 2
+				continue
+			}
+			return pos + 1
+		}
+		pos++
+	}
+	return n
+}
+
+// sqliAppendWord classify a word and merge multiple-word keywords
+func sqliAppendWord(tokens []sqliToken, word string, rest string) []sqliToken {
+	last := len(tokens) - 1
+	if last >= 0 {
+		prev := tokens[last]
+		switch {
+		case prev.Type == sqliTypeUnion && (word == "all" || word == "distinct"):
+			return tokens
+		case word == "by" && (prev.Value == "group" || prev.Value == "order"):
+			tokens[last] = sqliToken{Type: sqliTypeGroup, Value: prev.Value + " by"}
+			return tokens
+		case prev.Type == sqliTypeTSQL && (word == "delay" || word == "time"):
+			tokens[last] = sqliToken{Type: sqliTypeTSQL, Value: prev.Value + " " + word}
+			return tokens
+		}
+	}
+	if sqliFunctions[word] && strings.HasPrefix(strings.TrimLeftFunc(rest, isSQLiSpace), "(") {
+		return append(tokens, sqliToken{Type: sqliTypeFunction, Value: word})
+	}
+	if tokenType, ok := sqliKeywords[word]; ok {
+		return append(tokens, sqliToken{Type: tokenType, Value: word})
+	}
+	return append(tokens, sqliToken{Type: sqliTypeBareword, Value: word})
+}
@@ -0,0 +1,85 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 09:10:24
+ * @Last Modified: U2, 2026-10-20 09:10:24
+ */
+
+package firewall
+
+import "testing"
+
+func TestIsSQLInjection(t *testing.T) {
+	attacks := []string{
+		`1' or '1'='1`,
+		`1 or 1=1`,
+		`' or 'a'='a`,
+		`admin'--`,
+		`admin')#`,
+		`' union select username, password from users--`,
+		`-1 union all select 1,2,3`,
+		`1 union (select 1,2)`,
+		`1/*!50000union*/select 1`,
+		`1; drop table users`,
+		`1'; delete from users where 1=1--`,
+		`1; update users set role='admin'`,
+		`1; insert into users values(1,'x')`,
+		`'; waitfor delay '0:0:5'--`,
+		`1' and sleep(5)#`,
+		`1 and benchmark(1000000,md5(1))`,
+		`1' and extractvalue(1,concat(0x7e,version()))--`,
+		`1 and (select count(*) from users)>0`,
+		`' or @@version--`,
+		`1' order by 3--`,
+		`1 having 1=1`,
+		`') or ('a'='a`,
+		`1' or 1--`,
+		`" or ""="`,
+		`1 || 1=1`,
+	}
+	for _, value := range attacks {
+		if !IsSQLInjection(value) {
+			t.Errorf("IsSQLInjection(%q) = false, want true, fingerprint %q", value, GetSQLiFingerprint(value, '\''))
+		}
+	}
+
+	benign := []string{
+		`O'Neil & Sons; Update(2024)`,
+		`it's 5 o'clock; select one`,
+		`I have only one question`,
+		`status online`,
+		`Mr. O'Brien; Delete account request`,
+		`Tom & Jerry`,
+		`Don't stop me now`,
+		`rock'n'roll`,
+		`O'Reilly & Associates, Inc.`,
+		`1 or 2 apples`,
+		`Please select one or more options`,
+		`select your size`,
+		`drop me a line; update later`,
+		`Let's meet at 10:30; bring the union card`,
+		`union station`,
+		`order by price`,
+		`sort by date, then by name`,
+		`John's car (red)`,
+		`He said "hello" and left`,
+		`50% off - today only!`,
+		`x=1; y=2`,
+		`email@example.com`,
+		`https://example.com/search?q=union&page=2`,
+		`C:\Program Files\App`,
+		`The meeting is at 3pm -- don't be late`,
+		`#hashtag`,
+		`she said 'yes' or 'no'`,
+		`Q&A session (2024)`,
+		`Sleep (8 hours) is important`,
+		`1-800-555-0199`,
+		`12345`,
+		`{"name": "value"}`,
+	}
+	for _, value := range benign {
+		if IsSQLInjection(value) {
+			t.Errorf("IsSQLInjection(%q) = true, want false, fingerprint %q", value, GetSQLiFingerprint(value, '\''))
+		}
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 22:58:47
+ * @Last Modified: U2, 2026-10-19 22:58:47
+ */
+
+package firewall
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Janusec/janusec/models"
+)
+
+// SetTLSFingerprint compute the JA3 and JA4 fingerprints of ClientHello, GREASE values are ignored
+func SetTLSFingerprint(clientHello *models.ClientHello) {
+	clientHello.JA3 = getJA3(clientHello)
+	clientHello.JA4 = getJA4(clientHello)
+}
+
+// GetTLSFingerprint return the JA3 and JA4 fingerprints of the connection, empty for plain HTTP
+func GetTLSFingerprint(r *http.Request) (ja3 string, ja4 string) {
+	if clientHello, ok := r.Context().Value("clientHello").(*models.ClientHello); ok {
+		return clientHello.JA3, clientHello.JA4
+	}
+	return "", ""
+}
+
+func removeGREASE(values []uint16) []uint16 {
+	result := make([]uint16, 0, len(values))
+	for _, value := range values {
+		if !IsGREASE(value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func joinUint16(values []uint16, format string) string {
+	items := make([]string, len(values))
+	for i, value := range values {
+		items[i] = fmt.Sprintf(format, value)
+	}
+	return strings.Join(items, ",")
+}
+
+// getMaxVersion return the highest TLS version supported by client
+func getMaxVersion(clientHello *models.ClientHello) uint16 {
+	maxVersion := uint16(0)
+	for _, version := range removeGREASE(clientHello.SupportedVersions) {
+		if version > maxVersion {
+			maxVersion = version
+		}
+	}
+	return maxVersion
+}
+
+// getJA3 return md5 of SSLVersion,Ciphers,Extensions,EllipticCurves,EllipticCurvePointFormats
+func getJA3(clientHello *models.ClientHello) string {
+	// the legacy version field of TLS 1.3 ClientHello is TLS 1.2
+	version := getMaxVersion(clientHello)
+	if version > tls.VersionTLS12 {
+		version = tls.VersionTLS12
+	}
+	points := make([]string, len(clientHello.SupportedPoints))
+	for i, point := range clientHello.SupportedPoints {
+		points[i] = strconv.Itoa(int(point))
+	}
+	ja3 := strings.Join([]string{
+		strconv.Itoa(int(version)),
+		strings.Replace(joinUint16(removeGREASE(clientHello.CipherSuites), "%d"), ",", "-", -1),
+		strings.Replace(joinUint16(removeGREASE(clientHello.Extensions), "%d"), ",", "-", -1),
+		strings.Replace(joinUint16(removeGREASE(clientHello.SupportedCurves), "%d"), ",", "-", -1),
+		strings.Join(points, "-")}, ",")
+	hash := md5.Sum([]byte(ja3))
+	return hex.EncodeToString(hash[:])
+}
+
+func truncatedSHA256(content string) string {
+	if len(content) == 0 {
+		return "000000000000"
+	}
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])[:12]
+}
+
+func isAlphanumeric(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+}
+
+// getJA4 return the JA4 fingerprint like t13d1516h2_8daaf6152771_e5627efa2ab1,
+// which sorts ciphers and extensions and is stable under extension order randomization
+func getJA4(clientHello *models.ClientHello) string {
+	var version string
+	switch getMaxVersion(clientHello) {
+	case tls.VersionTLS13:
+		version = "13"
+	case tls.VersionTLS12:
+		version = "12"
+	case tls.VersionTLS11:
+		version = "11"
+	case tls.VersionTLS10:
+		version = "10"
+	case tls.VersionSSL30:
+		version = "s3"
+	default:
+		version = "00"
+	}
+	sni := "i"
+	if len(clientHello.ServerName) > 0 {
+		sni = "d"
+	}
+	ciphers := removeGREASE(clientHello.CipherSuites)
+	extensions := removeGREASE(clientHello.Extensions)
+	cipherCount, extensionCount := len(ciphers), len(extensions)
+	if cipherCount > 99 {
+		cipherCount = 99
+	}
+	if extensionCount > 99 {
+		extensionCount = 99
+	}
+	alpn := "00"
+	if len(clientHello.SupportedProtos) > 0 && len(clientHello.SupportedProtos[0]) > 0 {
+		firstProto := clientHello.SupportedProtos[0]
+		first, last := firstProto[0], firstProto[len(firstProto)-1]
+		if isAlphanumeric(first) && isAlphanumeric(last) {
+			alpn = string([]byte{first, last})
+		} else {
+			alpn = fmt.Sprintf("%x%x", first>>4, last&0x0F)
+		}
+	}
+	sort.Slice(ciphers, func(i, j int) bool { return ciphers[i] < ciphers[j] })
+	// SNI and ALPN are excluded from the hash of extensions
+	var hashExtensions []uint16
+	for _, extension := range extensions {
+		if extension != 0x0000 && extension != 0x0010 {
+			hashExtensions = append(hashExtensions, extension)
+		}
+	}
+	sort.Slice(hashExtensions, func(i, j int) bool { return hashExtensions[i] < hashExtensions[j] })
+	extensionContent := joinUint16(hashExtensions, "%04x")
+	if signatureSchemes := removeGREASE(clientHello.SignatureSchemes); len(extensionContent) > 0 && len(signatureSchemes) > 0 {
+		extensionContent += "_" + joinUint16(signatureSchemes, "%04x")
+	}
+	return fmt.Sprintf("t%s%s%02d%02d%s_%s_%s", version, sni, cipherCount, extensionCount, alpn,
+		truncatedSHA256(joinUint16(ciphers, "%04x")), truncatedSHA256(extensionContent))
+}
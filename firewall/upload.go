@@ -0,0 +1,306 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 17:46:15
+ * @Last Modified: U2, 2026-10-19 17:46:15
+ */
+
+package firewall
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	// uploadMaxArchiveDepth is the max nesting of archives to be scanned
+	uploadMaxArchiveDepth = 2
+	// uploadMaxArchiveEntries is the max count of entries scanned in all archives of an uploaded file
+	uploadMaxArchiveEntries = 1000
+	// uploadMaxEntrySize is the max decompressed size read from an archive entry
+	uploadMaxEntrySize int64 = 8 << 20
+	// uploadMaxArchiveSize is the max total decompressed size read from all archives of an uploaded file
+	uploadMaxArchiveSize int64 = 64 << 20
+)
+
+type fileSignature struct {
+	offset   int
+	magic    []byte
+	mimeType string
+	// verify check the structure after magic, for short magic bytes which text may start with
+	verify func(content []byte) bool
+}
+
+// uploadBudget is shared by the nested archives of an uploaded file
+type uploadBudget struct {
+	remainSize    int64
+	remainEntries int
+}
+
+var fileSignatures = []*fileSignature{
+	{0, []byte("PK\x03\x04"), "application/zip", nil},
+	{0, []byte("PK\x05\x06"), "application/zip", nil},
+	{0, []byte("\x1F\x8B"), "application/gzip", nil},
+	{257, []byte("ustar"), "application/x-tar", nil},
+	{0, []byte("Rar!\x1A\x07"), "application/vnd.rar", nil},
+	{0, []byte("7z\xBC\xAF\x27\x1C"), "application/x-7z-compressed", nil},
+	{0, []byte("BZh"), "application/x-bzip2", nil},
+	{0, []byte("\xFD7zXZ\x00"), "application/x-xz", nil},
+	{0, []byte("%PDF-"), "application/pdf", nil},
+	{0, []byte("\x89PNG\r\n\x1A\n"), "image/png", nil},
+	{0, []byte("\xFF\xD8\xFF"), "image/jpeg", nil},
+	{0, []byte("GIF87a"), "image/gif", nil},
+	{0, []byte("GIF89a"), "image/gif", nil},
+	{0, []byte("BM"), "image/bmp", isBMPHeader},
+	{0, []byte("\x7FELF"), "application/x-executable", nil},
+	{0, []byte("MZ"), "application/x-msdownload", isPEHeader},
+	{0, []byte("\xCA\xFE\xBA\xBE"), "application/java-vm", nil},
+	{0, []byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"), "application/x-ole-storage", nil},
+}
+
+var scriptSignatures = []*fileSignature{
+	{0, []byte("<?php"), "text/x-php", nil},
+	{0, []byte("<?="), "text/x-php", nil},
+	{0, []byte("<%@"), "text/x-server-page", nil},
+	{0, []byte("<%"), "text/x-server-page", nil},
+	{0, []byte("<jsp:"), "text/x-server-page", nil},
+	{0, []byte("#!"), "text/x-shellscript", nil},
+}
+
+// DetectFileType sniff the MIME type by magic bytes, server side scripts are detected before
+// http.DetectContentType which regards them as text/plain
+func DetectFileType(content []byte) string {
+	for _, signature := range fileSignatures {
+		if len(content) >= signature.offset+len(signature.magic) &&
+			bytes.Equal(content[signature.offset:signature.offset+len(signature.magic)], signature.magic) &&
+			(signature.verify == nil || signature.verify(content)) {
+			return signature.mimeType
+		}
+	}
+	head := bytes.TrimLeft(content, "\xEF\xBB\xBF \t\r\n")
+	for _, signature := range scriptSignatures {
+		if bytes.HasPrefix(bytes.ToLower(head[:minInt(len(head), 8)]), signature.magic) {
+			return signature.mimeType
+		}
+	}
+	if len(content) >= 12 && bytes.Equal(content[0:4], []byte("RIFF")) && bytes.Equal(content[8:12], []byte("WEBP")) {
+		return "image/webp"
+	}
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(content))
+	if mimeType == "image/bmp" && !isBMPHeader(content) {
+		// http.DetectContentType regards any content starting with BM as bmp
+		return detectTextOrBinary(content)
+	}
+	return mimeType
+}
+
+// detectTextOrBinary return text/plain if no binary bytes in the first 512 bytes, like http.DetectContentType
+func detectTextOrBinary(content []byte) string {
+	for _, b := range content[:minInt(len(content), 512)] {
+		if b <= 0x08 || b == 0x0B || (0x0E <= b && b <= 0x1A) || (0x1C <= b && b <= 0x1F) {
+			return "application/octet-stream"
+		}
+	}
+	return "text/plain"
+}
+
+// isBMPHeader check the size of DIB header following the 14 bytes file header
+func isBMPHeader(content []byte) bool {
+	if len(content) < 18 {
+		return false
+	}
+	switch binary.LittleEndian.Uint32(content[14:18]) {
+	case 12, 16, 40, 52, 56, 64, 108, 124:
+		return true
+	}
+	return false
+}
+
+// isPEHeader check the PE signature at the offset e_lfanew of DOS header, DOS only executables are ignored
+func isPEHeader(content []byte) bool {
+	if len(content) < 64 {
+		return false
+	}
+	offset := int64(binary.LittleEndian.Uint32(content[60:64]))
+	return offset >= 64 && offset+4 <= int64(len(content)) && bytes.Equal(content[offset:offset+4], []byte("PE\x00\x00"))
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// IsUploadTypeCompatible compare the declared type (Content-Type of part or by extension) with the detected type
+func IsUploadTypeCompatible(declaredType string, detectedType string) bool {
+	declaredType, _, _ = mime.ParseMediaType(declaredType)
+	if len(declaredType) == 0 || declaredType == "application/octet-stream" || detectedType == "application/octet-stream" {
+		return true
+	}
+	if declaredType == detectedType {
+		return true
+	}
+	declaredMajor := strings.Split(declaredType, "/")[0]
+	detectedMajor := strings.Split(detectedType, "/")[0]
+	switch detectedType {
+	case "text/plain":
+		// json, xml, csv, javascript and so on
+		return declaredMajor == "text" || strings.Contains(declaredType, "json") ||
+			strings.Contains(declaredType, "xml") || strings.Contains(declaredType, "javascript")
+	case "application/zip":
+		// office documents, jar, apk and epub are zip files
+		return strings.Contains(declaredType, "openxmlformats") || strings.Contains(declaredType, "opendocument") ||
+			strings.Contains(declaredType, "java-archive") || strings.Contains(declaredType, "android") ||
+			strings.Contains(declaredType, "epub") || strings.Contains(declaredType, "zip")
+	case "application/x-ole-storage":
+		// legacy office documents
+		return strings.Contains(declaredType, "ms") || strings.Contains(declaredType, "office")
+	}
+	if declaredMajor == detectedMajor && (declaredMajor == "image" || declaredMajor == "audio" || declaredMajor == "video") {
+		return true
+	}
+	return false
+}
+
+// IsUploadFileHitPolicy inspect the type and content of uploaded file, archive entries and clamd result
+func IsUploadFileHitPolicy(ctxMap *sync.Map, appID int64, fileHeader *multipart.FileHeader) (bool, *models.GroupPolicy) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return false, nil
+	}
+	defer file.Close()
+	content, err := ioutil.ReadAll(file)
+	utils.CheckError("IsUploadFileHitPolicy ReadAll", err)
+	if HasCheckItems(models.ChkPointUploadFileExt, models.ChkPointUploadFileType, models.ChkPointUploadTypeMismatch, models.ChkPointUploadContent) {
+		declaredType := fileHeader.Header.Get("Content-Type")
+		budget := &uploadBudget{remainSize: uploadMaxArchiveSize, remainEntries: uploadMaxArchiveEntries}
+		matched, policy := isUploadContentHitPolicy(ctxMap, appID, fileHeader.Filename, declaredType, content, budget, 0)
+		if matched == true {
+			return matched, policy
+		}
+	}
+	// ChkPoint_UploadVirus
+	if !HasCheckItems(models.ChkPointUploadVirus) {
+		return false, nil
+	}
+	if virusName, err := ScanByClamAV(content); err == nil && len(virusName) > 0 {
+		matched, policy := IsMatchGroupPolicyByParam(ctxMap, appID, virusName, models.ChkPointUploadVirus, "", fileHeader.Filename, false)
+		if matched == true {
+			return matched, policy
+		}
+	}
+	return false, nil
+}
+
+func isUploadContentHitPolicy(ctxMap *sync.Map, appID int64, filename string, declaredType string, content []byte, budget *uploadBudget, depth int) (bool, *models.GroupPolicy) {
+	detectedType := DetectFileType(content)
+	// ChkPoint_UploadFileType
+	matched, policy := IsMatchGroupPolicyByParam(ctxMap, appID, detectedType, models.ChkPointUploadFileType, "", filename, false)
+	if matched == true {
+		return matched, policy
+	}
+	// ChkPoint_UploadTypeMismatch
+	if len(declaredType) == 0 || declaredType == "application/octet-stream" {
+		declaredType = mime.TypeByExtension(strings.ToLower(filepath.Ext(filename)))
+	}
+	if !IsUploadTypeCompatible(declaredType, detectedType) {
+		mismatch := declaredType + ":" + detectedType
+		matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, mismatch, models.ChkPointUploadTypeMismatch, "", filename, false)
+		if matched == true {
+			return matched, policy
+		}
+	}
+	// ChkPoint_UploadContent, such as web shell signatures
+	matched, policy = IsMatchGroupPolicyByParam(ctxMap, appID, string(content), models.ChkPointUploadContent, "", filename, false)
+	if matched == true {
+		return matched, policy
+	}
+	if depth >= uploadMaxArchiveDepth {
+		return false, nil
+	}
+	// Archive entries are inspected as uploaded files, the budget is shared by all nested archives
+	return walkArchive(content, detectedType, func(name string, entry io.Reader) (bool, *models.GroupPolicy) {
+		if budget.remainSize <= 0 || budget.remainEntries <= 0 {
+			return false, nil
+		}
+		budget.remainEntries--
+		// ChkPoint_UploadFileExt
+		matched, policy := IsMatchGroupPolicyByParam(ctxMap, appID, filepath.Ext(name), models.ChkPointUploadFileExt, "", name, false)
+		if matched == true {
+			return matched, policy
+		}
+		maxSize := uploadMaxEntrySize
+		if maxSize > budget.remainSize {
+			maxSize = budget.remainSize
+		}
+		entryContent, _ := ioutil.ReadAll(io.LimitReader(entry, maxSize))
+		budget.remainSize -= int64(len(entryContent))
+		return isUploadContentHitPolicy(ctxMap, appID, name, "", entryContent, budget, depth+1)
+	})
+}
+
+// walkArchive call fn for each regular file in zip, tar or tar.gz, until fn returns matched
+func walkArchive(content []byte, detectedType string, fn func(name string, entry io.Reader) (bool, *models.GroupPolicy)) (bool, *models.GroupPolicy) {
+	switch detectedType {
+	case "application/zip":
+		zipReader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			return false, nil
+		}
+		for i, zipFile := range zipReader.File {
+			if i >= uploadMaxArchiveEntries {
+				break
+			}
+			if zipFile.FileInfo().IsDir() {
+				continue
+			}
+			entry, err := zipFile.Open()
+			if err != nil {
+				continue
+			}
+			matched, policy := fn(zipFile.Name, entry)
+			entry.Close()
+			if matched == true {
+				return matched, policy
+			}
+		}
+	case "application/gzip":
+		gzipReader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return false, nil
+		}
+		defer gzipReader.Close()
+		return fn(strings.TrimSuffix(gzipReader.Name, ".gz"), gzipReader)
+	case "application/x-tar":
+		tarReader := tar.NewReader(bytes.NewReader(content))
+		for i := 0; i < uploadMaxArchiveEntries; i++ {
+			header, err := tarReader.Next()
+			if err != nil {
+				break
+			}
+			if header.Typeflag != tar.TypeReg {
+				continue
+			}
+			matched, policy := fn(header.Name, tarReader)
+			if matched == true {
+				return matched, policy
+			}
+		}
+	}
+	return false, nil
+}
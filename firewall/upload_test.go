@@ -0,0 +1,89 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 14:38:52
+ * @Last Modified: U2, 2026-10-20 14:38:52
+ */
+
+package firewall
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestDetectFileType(t *testing.T) {
+	bmp := make([]byte, 54)
+	copy(bmp, "BM")
+	binary.LittleEndian.PutUint32(bmp[14:18], 40)
+	pe := make([]byte, 132)
+	copy(pe, "MZ")
+	binary.LittleEndian.PutUint32(pe[60:64], 128)
+	copy(pe[128:], "PE\x00\x00")
+	tests := []struct {
+		content []byte
+		want    string
+	}{
+		{bmp, "image/bmp"},
+		{pe, "application/x-msdownload"},
+		{[]byte("BMW 320i, BMW X5 and other models in stock"), "text/plain"},
+		{[]byte("MZ-80K is a personal computer made by Sharp in 1978"), "text/plain"},
+		{[]byte("\x89PNG\r\n\x1A\n0000"), "image/png"},
+		{[]byte("<?php eval($_POST['x']); ?>"), "text/x-php"},
+	}
+	for _, test := range tests {
+		if got := DetectFileType(test.content); got != test.want {
+			t.Errorf("DetectFileType(%q) = %s, want %s", test.content[:minInt(len(test.content), 16)], got, test.want)
+		}
+	}
+}
+
+func newTestZip(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for name, content := range files {
+		writer, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer.Write(content)
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadBudgetSharedByNestedArchives(t *testing.T) {
+	innerFiles := map[string][]byte{}
+	for i := 0; i < 10; i++ {
+		innerFiles["file"+strconv.Itoa(i)+".txt"] = bytes.Repeat([]byte("a"), 1024)
+	}
+	inner := newTestZip(t, innerFiles)
+	outerFiles := map[string][]byte{}
+	for i := 0; i < 10; i++ {
+		outerFiles["inner"+strconv.Itoa(i)+".zip"] = inner
+	}
+	outer := newTestZip(t, outerFiles)
+
+	// 10 inner archives and 100 entries in them exceed the entries of budget
+	budget := &uploadBudget{remainSize: 1 << 20, remainEntries: 30}
+	isUploadContentHitPolicy(&sync.Map{}, 0, "outer.zip", "application/zip", outer, budget, 0)
+	if budget.remainEntries != 0 {
+		t.Errorf("got %d remain entries, want 0", budget.remainEntries)
+	}
+
+	// 100 KiB of inner entries exceed the size of budget
+	budget = &uploadBudget{remainSize: 8 << 10, remainEntries: 1000}
+	isUploadContentHitPolicy(&sync.Map{}, 0, "outer.zip", "application/zip", outer, budget, 0)
+	if budget.remainSize != 0 {
+		t.Errorf("got %d remain bytes, want 0", budget.remainSize)
+	}
+	if budget.remainEntries < 1000-20 {
+		t.Errorf("got %d remain entries, the walk should stop after the size is used up", budget.remainEntries)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 14:10:26
+ * @Last Modified: U2, 2026-10-19 14:10:26
+ */
+
+package firewall
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Janusec/janusec/models"
+)
+
+const (
+	// xmlMaxExpansion is the cap of computed entity expansion size
+	xmlMaxExpansion int64 = 1 << 40
+)
+
+var (
+	xmlEntityRegex    = regexp.MustCompile(`<!ENTITY\s+(%\s+)?([^\s]+)\s+(?:"([^"]*)"|'([^']*)')`)
+	xmlEntityRefRegex = regexp.MustCompile(`[&%]([^\s;&%]+);`)
+)
+
+// IsXMLMediaType include text/xml, application/xml, application/soap+xml and other +xml types
+func IsXMLMediaType(mediaType string) bool {
+	return mediaType == "text/xml" || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+// IsXMLBodyHitPolicy check DOCTYPE, entity expansion, element text and attribute values of XML or SOAP body
+func IsXMLBodyHitPolicy(ctxMap *sync.Map, appID int64, body []byte) (bool, *models.GroupPolicy) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	// undefined entities are kept as they are, the gateway never expands external entities
+	decoder.Strict = false
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	var elementNames []string
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.Directive:
+			directive := string(t)
+			// ChkPoint_XMLDoctype
+			matched, policy := IsMatchGroupPolicy(ctxMap, appID, directive, models.ChkPointXMLDoctype, "", false)
+			if matched == true {
+				return matched, policy
+			}
+			// ChkPoint_XMLEntityExpansion
+			expansion := strconv.FormatInt(GetXMLEntityExpansion(directive), 10)
+			matched, policy = IsMatchGroupPolicy(ctxMap, appID, expansion, models.ChkPointXMLEntityExpansion, "", false)
+			if matched == true {
+				return matched, policy
+			}
+		case xml.StartElement:
+			elementNames = append(elementNames, t.Name.Local)
+			for _, attr := range t.Attr {
+				matched, policy := IsMatchGroupPolicyByParam(ctxMap, appID, attr.Value, models.ChkPointGetPostValue, "", attr.Name.Local, true)
+				if matched == true {
+					return matched, policy
+				}
+			}
+		case xml.EndElement:
+			if len(elementNames) > 0 {
+				elementNames = elementNames[:len(elementNames)-1]
+			}
+		case xml.CharData:
+			value := strings.TrimSpace(string(t))
+			if len(value) == 0 || len(elementNames) == 0 {
+				continue
+			}
+			matched, policy := IsMatchGroupPolicyByParam(ctxMap, appID, value, models.ChkPointGetPostValue, "", elementNames[len(elementNames)-1], true)
+			if matched == true {
+				return matched, policy
+			}
+		}
+	}
+	return false, nil
+}
+
+// GetXMLEntityExpansion return the max expanded size of internal entities declared in DOCTYPE,
+// nested references like billion laughs make it grow exponentially
+func GetXMLEntityExpansion(directive string) int64 {
+	entities := map[string]string{}
+	for _, match := range xmlEntityRegex.FindAllStringSubmatch(directive, -1) {
+		entities[match[2]] = match[3] + match[4]
+	}
+	sizes := map[string]int64{}
+	var expand func(name string, depth int) int64
+	expand = func(name string, depth int) int64 {
+		if size, ok := sizes[name]; ok {
+			return size
+		}
+		value, ok := entities[name]
+		if !ok {
+			return int64(len(name) + 2)
+		}
+		if depth > len(entities) {
+			// recursive reference
+			return xmlMaxExpansion
+		}
+		size := int64(len(value))
+		for _, ref := range xmlEntityRefRegex.FindAllStringSubmatch(value, -1) {
+			size += expand(ref[1], depth+1) - int64(len(ref[0]))
+			if size >= xmlMaxExpansion {
+				size = xmlMaxExpansion
+				break
+			}
+		}
+		sizes[name] = size
+		return size
+	}
+	var maxSize int64
+	for name := range entities {
+		if size := expand(name, 0); size > maxSize {
+			maxSize = size
+		}
+	}
+	return maxSize
+}
@@ -0,0 +1,433 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 10:05:18
+ * @Last Modified: U2, 2026-10-20 09:02:47
+ */
+
+package firewall
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// HTML tokenizer states, the same value may be injected into different contexts,
+// attributes are only parsed after a tag, or after breaking out of a quoted attribute value
+type htmlState int
+
+const (
+	htmlStateData htmlState = iota
+	htmlStateValueSingleQuote
+	htmlStateValueDoubleQuote
+	htmlStateValueBackQuote
+)
+
+type htmlTokenType int
+
+const (
+	htmlTokenTagOpen htmlTokenType = iota
+	htmlTokenTagClose
+	htmlTokenAttrName
+	htmlTokenAttrValue
+	htmlTokenComment
+)
+
+type htmlToken struct {
+	Type  htmlTokenType
+	Value string
+}
+
+var xssBlackTags = map[string]bool{
+	"script": true, "iframe": true, "frame": true, "frameset": true, "object": true,
+	"embed": true, "applet": true, "base": true, "link": true, "style": true, "meta": true,
+	"math": true, "xml": true, "import": true, "isindex": true, "vmlframe": true,
+	"template": true, "portal": true,
+}
+
+// xssURLAttrs hold URLs which may use javascript: scheme
+var xssURLAttrs = map[string]bool{
+	"href": true, "src": true, "action": true, "formaction": true, "data": true,
+	"background": true, "dynsrc": true, "lowsrc": true, "poster": true, "codebase": true,
+	"xlink:href": true, "from": true, "to": true, "values": true, "by": true, "srcdoc": true,
+	"xmlns": true, "attributename": true,
+}
+
+// xssEventHandlers are the event handler attributes, such as onerror, other attributes
+// starting with "on" are ignored
+var xssEventHandlers = map[string]bool{}
+
+func init() {
+	events := []string{
+		"abort", "activate", "afterprint", "afterscriptexecute", "afterupdate", "animationcancel",
+		"animationend", "animationiteration", "animationstart", "auxclick", "beforeactivate",
+		"beforecopy", "beforecut", "beforedeactivate", "beforeinput", "beforematch", "beforepaste",
+		"beforeprint", "beforescriptexecute", "beforetoggle", "beforeunload", "beforeupdate", "begin",
+		"blur", "bounce", "cancel", "canplay", "canplaythrough", "change", "click", "close", "command",
+		"contentvisibilityautostatechange", "contextmenu", "copy", "cuechange", "cut", "dblclick",
+		"deactivate", "drag", "dragend", "dragenter", "dragexit", "dragleave", "dragover", "dragstart",
+		"drop", "durationchange", "emptied", "end", "ended", "error", "errorupdate", "filterchange",
+		"finish", "focus", "focusin", "focusout", "formdata", "fullscreenchange", "hashchange", "input",
+		"invalid", "keydown", "keypress", "keyup", "load", "loadeddata", "loadedmetadata", "loadend",
+		"loadstart", "message", "mousedown", "mouseenter", "mouseleave", "mousemove", "mouseout",
+		"mouseover", "mouseup", "mousewheel", "move", "moveend", "movestart", "offline", "online",
+		"pagehide", "pageshow", "paste", "pause", "play", "playing", "pointercancel", "pointerdown",
+		"pointerenter", "pointerleave", "pointermove", "pointerout", "pointerover", "pointerrawupdate",
+		"pointerup", "popstate", "progress", "propertychange", "ratechange", "readystatechange",
+		"repeat", "reset", "resize", "scroll", "scrollend", "search", "securitypolicyviolation",
+		"seeked", "seeking", "select", "selectionchange", "selectstart", "show", "slotchange", "start",
+		"storage", "submit", "suspend", "timeupdate", "toggle", "touchcancel", "touchend", "touchmove",
+		"touchstart", "transitioncancel", "transitionend", "transitionrun", "transitionstart", "unload",
+		"volumechange", "waiting", "webkitanimationend", "webkitanimationiteration",
+		"webkitanimationstart", "webkittransitionend", "wheel",
+	}
+	for _, event := range events {
+		xssEventHandlers["on"+event] = true
+	}
+}
+
+var xssEntities = map[string]string{
+	"colon": ":", "tab": "\t", "newline": "\n", "lpar": "(", "rpar": ")",
+	"lt": "<", "gt": ">", "quot": `"`, "apos": "'", "amp": "&", "sol": "/", "grave": "`",
+}
+
+// IsXSS detects cross-site scripting by tokenizing the value as HTML in data and
+// attribute value contexts, and as a javascript string breakout
+func IsXSS(value string) bool {
+	if len(value) == 0 {
+		return false
+	}
+	states := []htmlState{htmlStateData}
+	if strings.ContainsRune(value, '\'') {
+		states = append(states, htmlStateValueSingleQuote)
+	}
+	if strings.ContainsRune(value, '"') {
+		states = append(states, htmlStateValueDoubleQuote)
+	}
+	if strings.ContainsRune(value, '`') {
+		states = append(states, htmlStateValueBackQuote)
+	}
+	for _, state := range states {
+		if isXSSTokens(htmlTokenize(value, state), false) {
+			return true
+		}
+	}
+	if isScriptURL(value) {
+		return true
+	}
+	return isJSBreakout(value)
+}
+
+// isXSSTokens check the tokens, inSVG is true for the content of an SVG image,
+// in which the svg tags are expected
+func isXSSTokens(tokens []htmlToken, inSVG bool) bool {
+	attrName := ""
+	for _, token := range tokens {
+		switch token.Type {
+		case htmlTokenTagOpen:
+			tagName := strings.ToLower(token.Value)
+			if xssBlackTags[tagName] || strings.HasPrefix(tagName, "xsl") || (!inSVG && strings.HasPrefix(tagName, "svg")) {
+				return true
+			}
+			attrName = ""
+		case htmlTokenAttrName:
+			attrName = strings.ToLower(token.Value)
+		case htmlTokenAttrValue:
+			switch {
+			case xssEventHandlers[attrName]:
+				// event handlers with value: onerror=, onload= ...
+				return true
+			case xssURLAttrs[attrName]:
+				if isScriptURL(token.Value) {
+					return true
+				}
+			case attrName == "style":
+				style := strings.ToLower(decodeHTMLEntities(token.Value))
+				if strings.Contains(style, "expression(") || strings.Contains(style, "javascript:") {
+					return true
+				}
+			}
+			attrName = ""
+		case htmlTokenComment:
+			comment := strings.ToLower(token.Value)
+			// IE conditional comments and backtick tricks
+			if strings.Contains(comment, "[if") || strings.Contains(comment, "`") || strings.Contains(comment, "import") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isScriptURL check javascript: vbscript: data:text/html and data:image/svg with script after normalization
+func isScriptURL(value string) bool {
+	decoded := decodeHTMLEntities(value)
+	var b strings.Builder
+	for _, ch := range decoded {
+		// browsers ignore control characters and whitespace in scheme
+		if ch > 0x20 {
+			b.WriteRune(ch)
+		}
+	}
+	url := strings.ToLower(b.String())
+	if strings.HasPrefix(url, "javascript:") || strings.HasPrefix(url, "vbscript:") {
+		return strings.ContainsAny(url, "(=`")
+	}
+	if strings.HasPrefix(url, "data:image/svg") {
+		return isScriptSVG(decoded)
+	}
+	return strings.HasPrefix(url, "data:text/html")
+}
+
+// isScriptSVG check the content of data:image/svg URL, such as data:image/svg+xml;base64,...
+func isScriptSVG(dataURL string) bool {
+	comma := strings.IndexByte(dataURL, ',')
+	if comma < 0 {
+		return false
+	}
+	mediaType, content := strings.ToLower(dataURL[:comma]), dataURL[comma+1:]
+	if strings.HasSuffix(strings.TrimSpace(mediaType), ";base64") {
+		cleaned := strings.Map(func(ch rune) rune {
+			if ch <= 0x20 {
+				return -1
+			}
+			return ch
+		}, content)
+		decoded, err := base64.StdEncoding.DecodeString(cleaned)
+		if err != nil {
+			decoded, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(cleaned, "="))
+			if err != nil {
+				// undecodable content can not be rendered either
+				return false
+			}
+		}
+		content = string(decoded)
+	} else if unescaped, err := url.PathUnescape(content); err == nil {
+		content = unescaped
+	}
+	return isXSSTokens(htmlTokenize(content, htmlStateData), true)
+}
+
+func decodeHTMLEntities(value string) string {
+	if !strings.Contains(value, "&") {
+		return value
+	}
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '&' {
+			b.WriteByte(value[i])
+			continue
+		}
+		end := i + 1
+		for end < len(value) && end-i < 12 && (isAlphaNum(value[end]) || value[end] == '#') {
+			end++
+		}
+		entity := value[i+1 : end]
+		decoded, ok := decodeHTMLEntity(entity)
+		if !ok {
+			b.WriteByte('&')
+			continue
+		}
+		b.WriteString(decoded)
+		i = end - 1
+		if end < len(value) && value[end] == ';' {
+			i = end
+		}
+	}
+	return b.String()
+}
+
+func decodeHTMLEntity(entity string) (string, bool) {
+	if strings.HasPrefix(entity, "#") {
+		var code int64
+		var err error
+		if len(entity) > 1 && (entity[1] == 'x' || entity[1] == 'X') {
+			code, err = strconv.ParseInt(entity[2:], 16, 32)
+		} else {
+			code, err = strconv.ParseInt(entity[1:], 10, 32)
+		}
+		if err != nil || code <= 0 || code > 0x10FFFF {
+			return "", false
+		}
+		return string(rune(code)), true
+	}
+	decoded, ok := xssEntities[strings.ToLower(entity)]
+	return decoded, ok
+}
+
+func isAlphaNum(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+func isHTMLSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f' || ch == '\v' || ch == 0
+}
+
+// htmlTokenize split value into HTML tokens with a simplified HTML5 tokenizer
+func htmlTokenize(value string, state htmlState) []htmlToken {
+	var tokens []htmlToken
+	n := len(value)
+	pos := 0
+	switch state {
+	case htmlStateValueSingleQuote, htmlStateValueDoubleQuote, htmlStateValueBackQuote:
+		var end int
+		switch state {
+		case htmlStateValueSingleQuote:
+			end = indexByteFrom(value, pos, '\'')
+		case htmlStateValueDoubleQuote:
+			end = indexByteFrom(value, pos, '"')
+		case htmlStateValueBackQuote:
+			end = indexByteFrom(value, pos, '`')
+		}
+		tokens = append(tokens, htmlToken{Type: htmlTokenAttrValue, Value: value[pos:end]})
+		if end < n {
+			end++
+		}
+		// continue within the tag after the attribute value
+		pos, tokens = htmlTokenizeAttrs(value, end, tokens)
+	}
+	for pos < n {
+		lt := indexByteFrom(value, pos, '<')
+		if lt >= n-1 {
+			break
+		}
+		pos = lt + 1
+		ch := value[pos]
+		switch {
+		case ch == '!':
+			if strings.HasPrefix(value[pos:], "!--") {
+				end := strings.Index(value[pos+3:], "-->")
+				if end < 0 {
+					tokens = append(tokens, htmlToken{Type: htmlTokenComment, Value: value[pos+3:]})
+					return tokens
+				}
+				tokens = append(tokens, htmlToken{Type: htmlTokenComment, Value: value[pos+3 : pos+3+end]})
+				pos += 3 + end + 3
+			} else {
+				// <!DOCTYPE or <![CDATA[, treat as a tag
+				end := indexByteFrom(value, pos, '>')
+				tokens = append(tokens, htmlToken{Type: htmlTokenTagOpen, Value: value[pos:end]})
+				pos = end
+			}
+		case ch == '/':
+			end := pos + 1
+			for end < n && !isHTMLSpace(value[end]) && value[end] != '>' {
+				end++
+			}
+			tokens = append(tokens, htmlToken{Type: htmlTokenTagClose, Value: value[pos+1 : end]})
+			pos = indexByteFrom(value, end, '>')
+		case (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch == '?' || ch == '%':
+			end := pos + 1
+			for end < n && !isHTMLSpace(value[end]) && value[end] != '>' && value[end] != '/' {
+				end++
+			}
+			tokens = append(tokens, htmlToken{Type: htmlTokenTagOpen, Value: value[pos:end]})
+			pos, tokens = htmlTokenizeAttrs(value, end, tokens)
+		}
+	}
+	return tokens
+}
+
+// htmlTokenizeAttrs parse attributes until the end of tag
+func htmlTokenizeAttrs(value string, pos int, tokens []htmlToken) (int, []htmlToken) {
+	n := len(value)
+	for pos < n {
+		ch := value[pos]
+		if isHTMLSpace(ch) || ch == '/' {
+			pos++
+			continue
+		}
+		if ch == '>' {
+			return pos + 1, tokens
+		}
+		end := pos + 1
+		for end < n && !isHTMLSpace(value[end]) && value[end] != '=' && value[end] != '>' && value[end] != '/' {
+			end++
+		}
+		tokens = append(tokens, htmlToken{Type: htmlTokenAttrName, Value: value[pos:end]})
+		pos = end
+		for pos < n && isHTMLSpace(value[pos]) {
+			pos++
+		}
+		if pos >= n || value[pos] != '=' {
+			continue
+		}
+		pos++
+		for pos < n && isHTMLSpace(value[pos]) {
+			pos++
+		}
+		if pos >= n {
+			break
+		}
+		quote := value[pos]
+		if quote == '"' || quote == '\'' || quote == '`' {
+			end = indexByteFrom(value, pos+1, quote)
+			tokens = append(tokens, htmlToken{Type: htmlTokenAttrValue, Value: value[pos+1 : end]})
+			pos = end + 1
+		} else {
+			end = pos
+			for end < n && !isHTMLSpace(value[end]) && value[end] != '>' {
+				end++
+			}
+			tokens = append(tokens, htmlToken{Type: htmlTokenAttrValue, Value: value[pos:end]})
+			pos = end
+		}
+	}
+	return pos, tokens
+}
+
+func indexByteFrom(value string, pos int, ch byte) int {
+	if pos >= len(value) {
+		return len(value)
+	}
+	i := strings.IndexByte(value[pos:], ch)
+	if i < 0 {
+		return len(value)
+	}
+	return pos + i
+}
+
+// isJSBreakout detects breaking out of a javascript string and calling a function,
+// such as `';alert(1)//` or `"-confirm(1)-"`
+func isJSBreakout(value string) bool {
+	quotePos := strings.IndexAny(value, "'\"`")
+	if quotePos < 0 {
+		return false
+	}
+	n := len(value)
+	pos := quotePos + 1
+	// separators after the quote: ; + - * / | & , ) }
+	separators := 0
+	for pos < n {
+		ch := value[pos]
+		if isHTMLSpace(ch) {
+			pos++
+			continue
+		}
+		if strings.IndexByte(";+-*/|&,)}^%<>=?:", ch) < 0 {
+			break
+		}
+		separators++
+		pos++
+	}
+	if separators == 0 {
+		return false
+	}
+	// identifier or member expression such as window.alert, top["al"+"ert"]
+	start := pos
+	for pos < n && (isAlphaNum(value[pos]) || value[pos] == '_' || value[pos] == '$' || value[pos] == '.') {
+		pos++
+	}
+	if pos == start {
+		return false
+	}
+	for pos < n && isHTMLSpace(value[pos]) {
+		pos++
+	}
+	if pos < n && (value[pos] == '(' || value[pos] == '`') {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 09:10:24
+ * @Last Modified: U2, 2026-10-20 09:10:24
+ */
+
+package firewall
+
+import "testing"
+
+func TestIsXSS(t *testing.T) {
+	attacks := []string{
+		`<script>alert(1)</script>`,
+		`<ScRiPt src=//evil.com/x.js></script>`,
+		`<img src=x onerror=alert(1)>`,
+		`<img src=x onerror = "alert(1)">`,
+		`"><svg onload=alert(1)>`,
+		`<body onload=alert(1)>`,
+		`<details open ontoggle=alert(1)>`,
+		`<iframe src="https://evil.com"></iframe>`,
+		`<a href="javascript:alert(1)">x</a>`,
+		`<a href="jav&#x61;script:alert(1)">x</a>`,
+		`<a href=" javascript:alert(1)">x</a>`,
+		`<div style="width:expression(alert(1))">`,
+		`<object data="data:text/html,<script>alert(1)</script>">`,
+		`<embed src="data:image/svg+xml,<svg onload=alert(1)>">`,
+		`<img src="data:image/svg+xml;base64,PHN2ZyBvbmxvYWQ9YWxlcnQoMSk+">`,
+		`<!--[if gte IE 4]><script>alert(1)</script><![endif]-->`,
+		`" onmouseover="alert(1)`,
+		`' autofocus onfocus='alert(1)`,
+		`javascript:alert(document.cookie)`,
+		`';alert(1)//`,
+		`"-confirm(1)-"`,
+	}
+	for _, value := range attacks {
+		if !IsXSS(value) {
+			t.Errorf("IsXSS(%q) = false, want true", value)
+		}
+	}
+
+	benign := []string{
+		`I have only one question`,
+		`status online`,
+		`it's 5 o'clock; select one`,
+		`O'Neil & Sons; Update(2024)`,
+		`Don't forget the onion rings`,
+		`one-on-one meeting`,
+		`the onload handler runs after the page loads`,
+		`set onclick = true in the config`,
+		`a < b and c > d`,
+		`I <3 you`,
+		`x<y`,
+		`He said "hello" onstage`,
+		`rock'n'roll`,
+		`javascript tutorial for beginners`,
+		`https://example.com/page?id=1&name=test`,
+		`<b>bold</b> and <i>italic</i>`,
+		`<a href="https://example.com">link</a>`,
+		`<img src="data:image/svg+xml;base64,PHN2ZyB3aWR0aD0iMTAiPjwvc3ZnPg==">`,
+		`data:image/png;base64,iVBORw0KGgo=`,
+		`Price: $5 - $10 (approx.)`,
+		`email@example.com`,
+		`50% off - today only!`,
+	}
+	for _, value := range benign {
+		if IsXSS(value) {
+			t.Errorf("IsXSS(%q) = true, want false", value)
+		}
+	}
+}
@@ -97,6 +97,20 @@ func ApiHandlerFunc(w http.ResponseWriter, r *http.Request) {
 		obj = nil
 		id := int64(param["id"].(float64))
 		err = backend.DeleteApplicationByID(id)
+	case "gettrafficsplits":
+		appID := int64(param["app_id"].(float64))
+		obj, err = backend.GetTrafficSplits(appID)
+	case "gettrafficsplit":
+		id := int64(param["id"].(float64))
+		obj, err = backend.GetTrafficSplitByID(id)
+	case "updatetrafficsplit":
+		obj, err = backend.UpdateTrafficSplit(param)
+	case "deltrafficsplit":
+		id := int64(param["id"].(float64))
+		obj = nil
+		err = backend.DeleteTrafficSplitByID(id)
+	case "getheadertemplates":
+		obj, err = backend.GetHeaderPolicyTemplates()
 	case "getcerts":
 		obj, err = backend.GetCertificates(authUser)
 	case "getcert":
@@ -147,12 +161,97 @@ func ApiHandlerFunc(w http.ResponseWriter, r *http.Request) {
 		id := int64(param["id"].(float64))
 		obj = nil
 		err = firewall.DeleteGroupPolicyByID(id)
+	case "getpolicyexceptions":
+		obj, err = firewall.GetPolicyExceptions()
+	case "getpolicyexception":
+		id := int64(param["id"].(float64))
+		obj, err = firewall.GetPolicyExceptionByID(id)
+	case "updatepolicyexception":
+		obj, err = firewall.UpdatePolicyException(r, authUser.UserID)
+	case "delpolicyexception":
+		id := int64(param["id"].(float64))
+		obj = nil
+		err = firewall.DeletePolicyExceptionByID(id)
+	case "addexceptionfromlog":
+		obj, err = firewall.AddPolicyExceptionFromHitLog(param, authUser.UserID)
+	case "getapischemas":
+		obj, err = firewall.GetAPISchemas()
+	case "getapischema":
+		id := int64(param["id"].(float64))
+		obj, err = firewall.GetAPISchemaByID(id)
+	case "updateapischema":
+		obj, err = firewall.UpdateAPISchema(r, authUser.UserID)
+	case "delapischema":
+		id := int64(param["id"].(float64))
+		obj = nil
+		err = firewall.DeleteAPISchemaByID(id)
+	case "getratelimitrules":
+		obj, err = firewall.GetRateLimitRules()
+	case "getratelimitrule":
+		id := int64(param["id"].(float64))
+		obj, err = firewall.GetRateLimitRuleByID(id)
+	case "updateratelimitrule":
+		obj, err = firewall.UpdateRateLimitRule(r, authUser.UserID)
+	case "delratelimitrule":
+		id := int64(param["id"].(float64))
+		obj = nil
+		err = firewall.DeleteRateLimitRuleByID(id)
+	case "getiplistentries":
+		obj, err = firewall.GetIPListEntries()
+	case "getiplistentry":
+		id := int64(param["id"].(float64))
+		obj, err = firewall.GetIPListEntryByID(id)
+	case "updateiplistentry":
+		obj, err = firewall.UpdateIPListEntry(r, authUser.UserID)
+	case "deliplistentry":
+		id := int64(param["id"].(float64))
+		obj = nil
+		err = firewall.DeleteIPListEntryByID(id)
+	case "importiplist":
+		obj, err = firewall.ImportIPList(r, authUser.UserID)
+	case "addipfromlog":
+		obj, err = firewall.AddIPListEntryFromLog(param, authUser.UserID)
+	case "getcountrypolicies":
+		obj, err = firewall.GetCountryPolicies()
+	case "getcountrypolicy":
+		id := int64(param["id"].(float64))
+		obj, err = firewall.GetCountryPolicyByID(id)
+	case "updatecountrypolicy":
+		obj, err = firewall.UpdateCountryPolicy(r, authUser.UserID)
+	case "delcountrypolicy":
+		id := int64(param["id"].(float64))
+		obj = nil
+		err = firewall.DeleteCountryPolicyByID(id)
+	case "getbotpolicies":
+		obj, err = firewall.GetBotPolicies()
+	case "getbotpolicy":
+		id := int64(param["id"].(float64))
+		obj, err = firewall.GetBotPolicyByID(id)
+	case "updatebotpolicy":
+		obj, err = firewall.UpdateBotPolicy(r, authUser.UserID)
+	case "delbotpolicy":
+		id := int64(param["id"].(float64))
+		obj = nil
+		err = firewall.DeleteBotPolicyByID(id)
+	case "getmaskingrules":
+		obj, err = firewall.GetMaskingRules()
+	case "getmaskingrule":
+		id := int64(param["id"].(float64))
+		obj, err = firewall.GetMaskingRuleByID(id)
+	case "updatemaskingrule":
+		obj, err = firewall.UpdateMaskingRule(r, authUser.UserID)
+	case "delmaskingrule":
+		id := int64(param["id"].(float64))
+		obj = nil
+		err = firewall.DeleteMaskingRuleByID(id)
 	case "testregex":
 		obj, err = firewall.TestRegex(param)
 	case "getvulntypes":
 		obj, err = firewall.GetVulnTypes()
 	case "getsettings":
 		obj, err = settings.GetSettings()
+	case "updatesettings":
+		obj, err = settings.UpdateSettings(param, authUser)
 	case "login":
 		obj, err = usermgmt.Login(w, r, param)
 	case "getoauthconf":
@@ -166,6 +265,12 @@ func ApiHandlerFunc(w http.ResponseWriter, r *http.Request) {
 	case "log_cc":
 		obj = nil
 		err = firewall.LogCCRequestAPI(r)
+	case "log_api_violation":
+		obj = nil
+		err = firewall.LogAPIViolationRequestAPI(r)
+	case "log_masking":
+		obj = nil
+		err = firewall.LogMaskingResponseAPI(r)
 	case "getregexlogscount":
 		obj, err = firewall.GetGroupLogCount(param)
 	case "getcclogscount":
@@ -180,10 +285,23 @@ func ApiHandlerFunc(w http.ResponseWriter, r *http.Request) {
 		obj, err = firewall.GetGroupLogs(param)
 	case "getcclogs":
 		obj, err = firewall.GetCCLogs(param)
+	case "getapiviolationlogscount":
+		obj, err = firewall.GetAPIViolationLogCount(param)
+	case "getapiviolationlog":
+		id := int64(param["id"].(float64))
+		obj, err = firewall.GetAPIViolationLogByID(id)
+	case "getapiviolationlogs":
+		obj, err = firewall.GetAPIViolationLogs(param)
+	case "getmaskinglogscount":
+		obj, err = firewall.GetMaskingLogCount(param)
+	case "getmaskinglogs":
+		obj, err = firewall.GetMaskingLogs(param)
 	case "getvulnstat":
 		obj, err = firewall.GetVulnStat(param)
 	case "getweekstat":
 		obj, err = firewall.GetWeekStat(param)
+	case "getcountrystat":
+		obj, err = firewall.GetCountryStat(param)
 	case "gettotpkey":
 		// used for authenticator launched by slave nodes
 		obj, err = usermgmt.GetOrInsertTOTPItem(param)
@@ -2,35 +2,75 @@
  * @Copyright Reserved By Janusec (https://www.janusec.com/).
  * @Author: U2
  * @Date: 2018-07-14 16:36:54
- * @Last Modified: U2, 2018-07-14 16:36:54
+ * @Last Modified: U2, 2026-10-19 22:05:31
  */
 
 package gateway
 
 import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"net/http"
-	"sync"
+	"net/url"
+	"strings"
 	"text/template"
 	"time"
 
+	"github.com/Janusec/janusec/backend"
 	"github.com/Janusec/janusec/firewall"
 	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
 	"github.com/dchest/captcha"
 )
 
 var (
-	captchaHitInfo sync.Map // (clientID string, *HitInfo)
-	formTemplate   = template.Must(template.New("captcha").Parse(formTemplateSrc))
+	formTemplate = template.Must(template.New("captcha").Parse(formTemplateSrc))
 )
 
 const (
 	// CaptchaEntrance : captcha confirm url
 	CaptchaEntrance = "/captcha/confirm"
+	// captchaExpireSeconds is the time to solve a captcha
+	captchaExpireSeconds = 600
 )
 
+// GenCaptchaURL return the captcha url with signed hit info, so that any node can validate it
+func GenCaptchaURL(r *http.Request, srcIP string, hitInfo *models.HitInfo) string {
+	hitInfoBytes, err := json.Marshal(hitInfo)
+	utils.CheckError("GenCaptchaURL", err)
+	content := base64.RawURLEncoding.EncodeToString(hitInfoBytes)
+	signature := signChallengeData("captcha", srcIP+"\n"+r.UserAgent()+"\n"+content)
+	return CaptchaEntrance + "?id=" + content + "." + signature
+}
+
+// ParseCaptchaID verify the signature and expiry of captcha id, return the hit info
+func ParseCaptchaID(r *http.Request, srcIP string, id string) (*models.HitInfo, error) {
+	parts := strings.Split(id, ".")
+	if len(parts) != 2 {
+		return nil, errors.New("invalid captcha id")
+	}
+	signature := signChallengeData("captcha", srcIP+"\n"+r.UserAgent()+"\n"+parts[0])
+	if !hmac.Equal([]byte(parts[1]), []byte(signature)) {
+		return nil, errors.New("invalid captcha id signature")
+	}
+	hitInfoBytes, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	hitInfo := &models.HitInfo{}
+	if err = json.Unmarshal(hitInfoBytes, hitInfo); err != nil {
+		return nil, err
+	}
+	if time.Now().Unix()-hitInfo.BlockTime > captchaExpireSeconds {
+		return nil, errors.New("captcha id expired")
+	}
+	return hitInfo, nil
+}
+
 // ShowCaptchaHandlerFunc ...
 func ShowCaptchaHandlerFunc(w http.ResponseWriter, r *http.Request) {
-	go ClearExpiredCapthchaHitInfo()
 	id := r.FormValue("id")
 	captchaContext := models.CaptchaContext{CaptchaId: captcha.New(), ClientID: id}
 	if err := formTemplate.Execute(w, &captchaContext); err != nil {
@@ -38,27 +78,35 @@ func ShowCaptchaHandlerFunc(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ValidateCaptchaHandlerFunc ...
+// ValidateCaptchaHandlerFunc set the pass token cookie and redirect to the original url
 func ValidateCaptchaHandlerFunc(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	clientID := r.FormValue("client_id")
 	if !captcha.VerifyString(r.FormValue("captcha_id"), r.FormValue("captcha_solution")) {
-		captchaURL := CaptchaEntrance + "?id=" + clientID
-		http.Redirect(w, r, captchaURL, http.StatusTemporaryRedirect)
-	} else {
-		if mapHitInfo, ok := captchaHitInfo.Load(clientID); ok {
-			hitInfo := mapHitInfo.(*models.HitInfo)
-			captchaHitInfo.Delete(clientID)
-			if hitInfo.TypeID == 1 {
-				firewall.ClearCCStatByClientID(hitInfo.PolicyID, clientID)
-				http.Redirect(w, r, hitInfo.TargetURL, http.StatusMovedPermanently)
-			} else {
-				http.Redirect(w, r, "/", http.StatusMovedPermanently)
-			}
-			return
-		}
-		http.Redirect(w, r, "/", http.StatusMovedPermanently)
+		captchaURL := CaptchaEntrance + "?id=" + url.QueryEscape(clientID)
+		http.Redirect(w, r, captchaURL, http.StatusFound)
+		return
+	}
+	app := backend.GetApplicationByDomain(r.Host)
+	if app == nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	srcIP := GetClientIP(r, app)
+	hitInfo, err := ParseCaptchaID(r, srcIP, clientID)
+	if err != nil {
+		http.Redirect(w, r, "/", http.StatusFound)
+		return
+	}
+	if hitInfo.TypeID == 1 {
+		firewall.ClearCCStatByClientID(hitInfo.PolicyID, hitInfo.ClientID)
 	}
+	SetPassTokenCookie(w, r, srcIP)
+	targetURL := hitInfo.TargetURL
+	if !IsSafeTargetURL(targetURL) {
+		targetURL = "/"
+	}
+	http.Redirect(w, r, targetURL, http.StatusFound)
 }
 
 // ShowCaptchaImage ...
@@ -66,19 +114,6 @@ func ShowCaptchaImage() http.Handler {
 	return captcha.Server(captcha.StdWidth, captcha.StdHeight)
 }
 
-// ClearExpiredCapthchaHitInfo ...
-func ClearExpiredCapthchaHitInfo() {
-	captchaHitInfo.Range(func(key, value interface{}) bool {
-		clientID := key.(string)
-		hitInfo := value.(*models.HitInfo)
-		curTime := time.Now().Unix()
-		if curTime-hitInfo.BlockTime > 600 {
-			captchaHitInfo.Delete(clientID)
-		}
-		return true
-	})
-}
-
 const formTemplateSrc = `<!doctype html>
 <head>
 <title>Captcha Example</title>
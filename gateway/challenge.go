@@ -0,0 +1,290 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 21:40:52
+ * @Last Modified: U2, 2026-10-19 21:40:52
+ */
+
+package gateway
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"html/template"
+	"math/bits"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Janusec/janusec/backend"
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+const (
+	// ChallengeEntrance : JavaScript and proof-of-work challenge verify url
+	ChallengeEntrance = "/challenge/verify"
+	// PassTokenCookieName is the cookie of pass token after solving a challenge
+	PassTokenCookieName = "janusec-pass"
+	// jsChallengeDifficulty is small, just to make sure JavaScript is executed
+	jsChallengeDifficulty = 4
+	// challengeExpireSeconds is the time to solve a challenge
+	challengeExpireSeconds = 300
+)
+
+var (
+	challengeTemplate = template.Must(template.New("challenge").Parse(challengeTemplateSrc))
+)
+
+// ChallengeContext is used by challenge template
+type ChallengeContext struct {
+	Challenge  string
+	Difficulty int64
+	TargetURL  string
+}
+
+// IsChallengeAction return true if the action is CAPTCHA, JavaScript or proof-of-work challenge,
+// which is skipped for the client with valid pass token
+func IsChallengeAction(action models.PolicyAction) bool {
+	return action == models.Action_CAPTCHA_300 || action == models.Action_JSChallenge_500 || action == models.Action_PoWChallenge_600
+}
+
+func signChallengeData(purpose string, content string) string {
+	mac := hmac.New(sha256.New, data.DeriveKey(purpose))
+	mac.Write([]byte(content))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// GenPassToken generate the pass token bound to client IP and User-Agent
+func GenPassToken(r *http.Request, srcIP string, expireTime int64) string {
+	expire := strconv.FormatInt(expireTime, 10)
+	return expire + "." + signChallengeData("pass", srcIP+"\n"+r.UserAgent()+"\n"+expire)
+}
+
+// IsPassTokenValid verify the pass token of request, it is stateless and valid on all nodes
+func IsPassTokenValid(r *http.Request, srcIP string) bool {
+	cookie, err := r.Cookie(PassTokenCookieName)
+	if err != nil {
+		return false
+	}
+	parts := strings.SplitN(cookie.Value, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	expireTime, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || expireTime < time.Now().Unix() {
+		return false
+	}
+	return hmac.Equal([]byte(cookie.Value), []byte(GenPassToken(r, srcIP, expireTime)))
+}
+
+// SetPassTokenCookie issue the pass token after the client solved a CAPTCHA or challenge
+func SetPassTokenCookie(w http.ResponseWriter, r *http.Request, srcIP string) {
+	expireTime := time.Now().Unix() + data.Challenge_Pass_Seconds
+	http.SetCookie(w, &http.Cookie{
+		Name:     PassTokenCookieName,
+		Value:    GenPassToken(r, srcIP, expireTime),
+		Path:     "/",
+		Expires:  time.Unix(expireTime, 0),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode})
+}
+
+// GenerateChallengePage show the challenge page, the page solves the challenge and posts to ChallengeEntrance
+func GenerateChallengePage(w http.ResponseWriter, r *http.Request, srcIP string, action models.PolicyAction, targetURL string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusForbidden)
+	w.Write(GenerateChallengeContent(r, srcIP, action, targetURL))
+}
+
+// GenerateChallengeContent return the challenge page signed for the client IP, User-Agent and target URL
+func GenerateChallengeContent(r *http.Request, srcIP string, action models.PolicyAction, targetURL string) []byte {
+	difficulty := int64(jsChallengeDifficulty)
+	if action == models.Action_PoWChallenge_600 {
+		difficulty = data.Challenge_PoW_Difficulty
+	}
+	salt := make([]byte, 8)
+	rand.Read(salt)
+	expire := strconv.FormatInt(time.Now().Unix()+challengeExpireSeconds, 10)
+	content := strings.Join([]string{expire, strconv.FormatInt(difficulty, 10), hex.EncodeToString(salt)}, ".")
+	signature := signChallengeData("challenge", srcIP+"\n"+r.UserAgent()+"\n"+targetURL+"\n"+content)
+	challengeContext := &ChallengeContext{
+		Challenge:  content + "." + signature,
+		Difficulty: difficulty,
+		TargetURL:  targetURL}
+	var buf bytes.Buffer
+	err := challengeTemplate.Execute(&buf, challengeContext)
+	utils.CheckError("GenerateChallengeContent", err)
+	return buf.Bytes()
+}
+
+// VerifyChallenge check the signature, expiry and proof-of-work of the solution
+func VerifyChallenge(r *http.Request, srcIP string, challenge string, nonce string, targetURL string) bool {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 || len(nonce) == 0 || len(nonce) > 32 {
+		return false
+	}
+	content := strings.Join(parts[:3], ".")
+	signature := signChallengeData("challenge", srcIP+"\n"+r.UserAgent()+"\n"+targetURL+"\n"+content)
+	if !hmac.Equal([]byte(parts[3]), []byte(signature)) {
+		return false
+	}
+	expireTime, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || expireTime < time.Now().Unix() {
+		return false
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return getLeadingZeroBits(sha256.Sum256([]byte(challenge+":"+nonce))) >= difficulty
+}
+
+func getLeadingZeroBits(hash [32]byte) int {
+	count := 0
+	for _, b := range hash {
+		if b != 0 {
+			return count + bits.LeadingZeros8(b)
+		}
+		count += 8
+	}
+	return count
+}
+
+// IsSafeTargetURL only allow local path as redirect target
+func IsSafeTargetURL(targetURL string) bool {
+	return strings.HasPrefix(targetURL, "/") && !strings.HasPrefix(targetURL, "//") && !strings.HasPrefix(targetURL, "/\\")
+}
+
+// ChallengeVerifyHandlerFunc verify the solution and set pass token cookie
+func ChallengeVerifyHandlerFunc(w http.ResponseWriter, r *http.Request) {
+	app := backend.GetApplicationByDomain(r.Host)
+	if app == nil || r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	srcIP := GetClientIP(r, app)
+	targetURL := r.PostFormValue("target")
+	if !IsSafeTargetURL(targetURL) {
+		targetURL = "/"
+	}
+	if !VerifyChallenge(r, srcIP, r.PostFormValue("challenge"), r.PostFormValue("nonce"), targetURL) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+	SetPassTokenCookie(w, r, srcIP)
+	http.Redirect(w, r, targetURL, http.StatusSeeOther)
+}
+
+const challengeTemplateSrc = `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Checking your browser</title>
+<style>
+body {
+	font-family: Arial, Helvetica, sans-serif;
+	text-align: center;
+}
+</style>
+</head>
+<body>
+<p>Checking your browser before accessing, please wait ...</p>
+<noscript><p>Please enable JavaScript to continue.</p></noscript>
+<form id="challenge" action="` + ChallengeEntrance + `" method="POST">
+<input type="hidden" name="challenge" value="{{.Challenge}}">
+<input type="hidden" name="target" value="{{.TargetURL}}">
+<input type="hidden" id="nonce" name="nonce" value="">
+</form>
+<script>
+function sha256(ascii) {
+	function rightRotate(value, amount) {
+		return (value >>> amount) | (value << (32 - amount));
+	}
+	var maxWord = Math.pow(2, 32);
+	var i, j, result = '', words = [], asciiBitLength = ascii.length * 8;
+	var hash = [], k = [], primeCounter = 0, isComposite = {};
+	for (var candidate = 2; primeCounter < 64; candidate++) {
+		if (!isComposite[candidate]) {
+			for (i = 0; i < 313; i += candidate) {
+				isComposite[i] = candidate;
+			}
+			hash[primeCounter] = (Math.pow(candidate, .5) * maxWord) | 0;
+			k[primeCounter++] = (Math.pow(candidate, 1 / 3) * maxWord) | 0;
+		}
+	}
+	ascii += '\x80';
+	while (ascii.length % 64 - 56) ascii += '\x00';
+	for (i = 0; i < ascii.length; i++) {
+		j = ascii.charCodeAt(i);
+		words[i >> 2] |= j << ((3 - i) % 4) * 8;
+	}
+	words[words.length] = ((asciiBitLength / maxWord) | 0);
+	words[words.length] = (asciiBitLength);
+	for (j = 0; j < words.length;) {
+		var w = words.slice(j, j += 16);
+		var oldHash = hash;
+		hash = hash.slice(0, 8);
+		for (i = 0; i < 64; i++) {
+			var w15 = w[i - 15], w2 = w[i - 2];
+			var a = hash[0], e = hash[4];
+			var temp1 = hash[7] + (rightRotate(e, 6) ^ rightRotate(e, 11) ^ rightRotate(e, 25))
+				+ ((e & hash[5]) ^ ((~e) & hash[6])) + k[i]
+				+ (w[i] = (i < 16) ? w[i] : (w[i - 16] + (rightRotate(w15, 7) ^ rightRotate(w15, 18) ^ (w15 >>> 3))
+					+ w[i - 7] + (rightRotate(w2, 17) ^ rightRotate(w2, 19) ^ (w2 >>> 10))) | 0);
+			var temp2 = (rightRotate(a, 2) ^ rightRotate(a, 13) ^ rightRotate(a, 22))
+				+ ((a & hash[1]) ^ (a & hash[2]) ^ (hash[1] & hash[2]));
+			hash = [(temp1 + temp2) | 0].concat(hash);
+			hash[4] = (hash[4] + temp1) | 0;
+		}
+		for (i = 0; i < 8; i++) {
+			hash[i] = (hash[i] + oldHash[i]) | 0;
+		}
+	}
+	for (i = 0; i < 8; i++) {
+		for (j = 3; j + 1; j--) {
+			var b = (hash[i] >> (j * 8)) & 255;
+			result += ((b < 16) ? 0 : '') + b.toString(16);
+		}
+	}
+	return result;
+}
+function leadingZeroBits(hex) {
+	var count = 0;
+	for (var i = 0; i < hex.length; i++) {
+		var v = parseInt(hex[i], 16);
+		if (v != 0) {
+			return count + (v >= 8 ? 0 : v >= 4 ? 1 : v >= 2 ? 2 : 3);
+		}
+		count += 4;
+	}
+	return count;
+}
+(function() {
+	var challenge = "{{.Challenge}}", difficulty = {{.Difficulty}}, nonce = 0;
+	function work() {
+		var deadline = Date.now() + 50;
+		while (Date.now() < deadline) {
+			if (leadingZeroBits(sha256(challenge + ":" + nonce)) >= difficulty) {
+				document.getElementById("nonce").value = nonce;
+				document.getElementById("challenge").submit();
+				return;
+			}
+			nonce++;
+		}
+		setTimeout(work, 0);
+	}
+	work();
+})();
+</script>
+</body>
+</html>
+`
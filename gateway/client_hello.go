@@ -0,0 +1,46 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 22:36:12
+ * @Last Modified: U2, 2026-10-19 22:36:12
+ */
+
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+
+	"github.com/Janusec/janusec/firewall"
+	"github.com/Janusec/janusec/models"
+)
+
+// ClientHelloConnContext attach an empty ClientHello to the connection context,
+// it is filled by SaveClientHello during TLS handshake and inherited by the requests of the connection
+func ClientHelloConnContext(ctx context.Context, conn net.Conn) context.Context {
+	return context.WithValue(ctx, "clientHello", &models.ClientHello{})
+}
+
+// SaveClientHello copy the ClientHello and its JA3/JA4 fingerprints into the connection context, called by GetConfigForClient
+func SaveClientHello(helloInfo *tls.ClientHelloInfo) {
+	clientHello, ok := helloInfo.Context().Value("clientHello").(*models.ClientHello)
+	if !ok {
+		return
+	}
+	clientHello.ServerName = helloInfo.ServerName
+	clientHello.CipherSuites = helloInfo.CipherSuites
+	clientHello.Extensions = helloInfo.Extensions
+	clientHello.SupportedCurves = make([]uint16, len(helloInfo.SupportedCurves))
+	for i, curve := range helloInfo.SupportedCurves {
+		clientHello.SupportedCurves[i] = uint16(curve)
+	}
+	clientHello.SupportedPoints = helloInfo.SupportedPoints
+	clientHello.SignatureSchemes = make([]uint16, len(helloInfo.SignatureSchemes))
+	for i, scheme := range helloInfo.SignatureSchemes {
+		clientHello.SignatureSchemes[i] = uint16(scheme)
+	}
+	clientHello.SupportedProtos = helloInfo.SupportedProtos
+	clientHello.SupportedVersions = helloInfo.SupportedVersions
+	firewall.SetTLSFingerprint(clientHello)
+}
@@ -0,0 +1,139 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 21:02:33
+ * @Last Modified: U2, 2026-10-19 21:02:33
+ */
+
+package gateway
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+var (
+	trustedProxyNets []*net.IPNet
+)
+
+// InitTrustedProxies parse the trusted proxy CIDRs of config
+func InitTrustedProxies() {
+	trustedProxyNets = []*net.IPNet{}
+	for _, cidr := range data.CFG.Proxy.TrustedCIDRs {
+		if !strings.Contains(cidr, "/") {
+			if strings.Contains(cidr, ":") {
+				cidr += "/128"
+			} else {
+				cidr += "/32"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		utils.CheckError("InitTrustedProxies", err)
+		if err == nil {
+			trustedProxyNets = append(trustedProxyNets, ipNet)
+		}
+	}
+}
+
+// IsTrustedProxy return true if the IP is within the trusted proxy CIDRs
+func IsTrustedProxy(ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range trustedProxyNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetClientIP return the client IP resolved by gateway, or resolve it by the method of application,
+// headers are trusted only from trusted proxies if configured
+func GetClientIP(r *http.Request, app *models.Application) (clientIP string) {
+	if clientIP, ok := r.Context().Value("clientIP").(string); ok {
+		return clientIP
+	}
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+	isTrustedPeer := len(trustedProxyNets) == 0 || IsTrustedProxy(remoteIP)
+	if !isTrustedPeer {
+		return remoteIP
+	}
+	switch app.ClientIPMethod {
+	case models.IPMethod_X_FORWARDED_FOR:
+		var hops []string
+		for _, xForwardedFor := range r.Header["X-Forwarded-For"] {
+			for _, hop := range strings.Split(xForwardedFor, ",") {
+				hops = append(hops, strings.TrimSpace(hop))
+			}
+		}
+		clientIP = walkForwardedHops(hops)
+	case models.IPMethod_FORWARDED:
+		var hops []string
+		for _, forwarded := range r.Header["Forwarded"] {
+			hops = append(hops, parseForwardedFor(forwarded)...)
+		}
+		clientIP = walkForwardedHops(hops)
+	case models.IPMethod_X_REAL_IP:
+		clientIP = strings.TrimSpace(r.Header.Get("X-Real-IP"))
+	case models.IPMethod_REAL_IP:
+		clientIP = strings.TrimSpace(r.Header.Get("Real-IP"))
+	}
+	if net.ParseIP(clientIP) == nil {
+		clientIP = remoteIP
+	}
+	return clientIP
+}
+
+// walkForwardedHops walk from right to left and skip trusted proxies, return the first untrusted hop,
+// only the last hop is used if trusted proxies are not configured
+func walkForwardedHops(hops []string) string {
+	clientIP := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		if net.ParseIP(hops[i]) == nil {
+			// obfuscated or invalid hop, the hops on the left are not reliable
+			break
+		}
+		clientIP = hops[i]
+		if len(trustedProxyNets) == 0 || !IsTrustedProxy(clientIP) {
+			break
+		}
+	}
+	return clientIP
+}
+
+// parseForwardedFor return the for= values of Forwarded header, such as
+// Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"
+func parseForwardedFor(forwarded string) (hops []string) {
+	for _, element := range strings.Split(forwarded, ",") {
+		hop := ""
+		for _, pair := range strings.Split(element, ";") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) != 2 || strings.ToLower(kv[0]) != "for" {
+				continue
+			}
+			value := strings.Trim(kv[1], `"`)
+			if strings.HasPrefix(value, "[") {
+				// IPv6 with optional port
+				if end := strings.Index(value, "]"); end > 0 {
+					value = value[1:end]
+				}
+			} else if strings.Count(value, ":") == 1 {
+				// IPv4 with port
+				value = value[:strings.Index(value, ":")]
+			}
+			hop = value
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
@@ -0,0 +1,163 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 00:40:52
+ * @Last Modified: U2, 2026-10-20 00:40:52
+ */
+
+package gateway
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Janusec/janusec/backend"
+	"github.com/Janusec/janusec/data"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// getCookieCipher return AES-GCM with the key derived for cookie, the instance key is only loaded
+// by master, so the key shared by master and slave nodes is used
+func getCookieCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(data.DeriveKey("cookie"))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptCookieValue encrypt and sign the cookie value, the cookie name is authenticated too,
+// so that the value can not be moved to another cookie
+func EncryptCookieValue(name string, value string) (string, error) {
+	aesgcm, err := getCookieCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aesgcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	ciphertext := aesgcm.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// DecryptCookieValue verify and decrypt the cookie value, return error if tampered
+func DecryptCookieValue(name string, value string) (string, error) {
+	aesgcm, err := getCookieCipher()
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < aesgcm.NonceSize() {
+		return "", errors.New("invalid encrypted cookie")
+	}
+	nonce, ciphertext := ciphertext[:aesgcm.NonceSize()], ciphertext[aesgcm.NonceSize():]
+	plaintext, err := aesgcm.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// DecryptRequestCookies decrypt the encrypted cookies before forwarding to backend,
+// the tampered cookies are removed from the request, other cookies are passed as they are
+func DecryptRequestCookies(app *models.Application, r *http.Request) {
+	if !backend.HasEncryptedCookie(app) || len(r.Header.Get("Cookie")) == 0 {
+		return
+	}
+	var headerValues []string
+	for _, headerValue := range r.Header["Cookie"] {
+		if headerValue = decryptCookieHeader(app, r, headerValue); len(headerValue) > 0 {
+			headerValues = append(headerValues, headerValue)
+		}
+	}
+	if len(headerValues) == 0 {
+		r.Header.Del("Cookie")
+		return
+	}
+	r.Header["Cookie"] = headerValues
+}
+
+// decryptCookieHeader replace the value of encrypted cookies in one Cookie header, the other parts are kept
+// byte for byte, including the cookies r.Cookies() would drop as invalid and the quotes around values
+func decryptCookieHeader(app *models.Application, r *http.Request, headerValue string) string {
+	parts := strings.Split(headerValue, ";")
+	keptParts := parts[:0]
+	for _, part := range parts {
+		index := strings.Index(part, "=")
+		if index < 0 {
+			keptParts = append(keptParts, part)
+			continue
+		}
+		name := strings.TrimSpace(part[:index])
+		cookiePolicy := backend.GetCookiePolicy(app, name)
+		value := strings.TrimSpace(part[index+1:])
+		if cookiePolicy == nil || !cookiePolicy.Encrypt || len(value) == 0 || backend.IsGatewayCookie(app, name) {
+			keptParts = append(keptParts, part)
+			continue
+		}
+		plainValue, err := DecryptCookieValue(name, strings.Trim(value, `"`))
+		if err != nil {
+			utils.DebugPrintln("DecryptRequestCookies reject tampered cookie", name, r.Host)
+			continue
+		}
+		prefix := part[:index+1]
+		keptParts = append(keptParts, prefix+plainValue)
+	}
+	return strings.TrimSpace(strings.Join(keptParts, ";"))
+}
+
+// HardenResponseCookies enforce the cookie flags and encrypt the selected cookies set by backend
+func HardenResponseCookies(app *models.Application, resp *http.Response) {
+	setCookies := resp.Header["Set-Cookie"]
+	if len(app.CookiePolicies) == 0 || len(setCookies) == 0 {
+		return
+	}
+	for i, setCookie := range setCookies {
+		cookies := (&http.Response{Header: http.Header{"Set-Cookie": {setCookie}}}).Cookies()
+		if len(cookies) == 0 {
+			continue
+		}
+		cookie := cookies[0]
+		cookiePolicy := backend.GetCookiePolicy(app, cookie.Name)
+		if cookiePolicy == nil {
+			continue
+		}
+		if cookiePolicy.Secure {
+			cookie.Secure = true
+		}
+		if cookiePolicy.HttpOnly {
+			cookie.HttpOnly = true
+		}
+		switch cookiePolicy.SameSite {
+		case models.SameSite_Lax:
+			cookie.SameSite = http.SameSiteLaxMode
+		case models.SameSite_Strict:
+			cookie.SameSite = http.SameSiteStrictMode
+		case models.SameSite_None:
+			cookie.SameSite = http.SameSiteNoneMode
+			cookie.Secure = true
+		}
+		if cookiePolicy.Encrypt && len(cookie.Value) > 0 && !backend.IsGatewayCookie(app, cookie.Name) {
+			value, err := EncryptCookieValue(cookie.Name, cookie.Value)
+			if err != nil {
+				utils.CheckError("HardenResponseCookies", err)
+				continue
+			}
+			cookie.Value = value
+		}
+		if newSetCookie := cookie.String(); len(newSetCookie) > 0 {
+			setCookies[i] = newSetCookie
+		}
+	}
+}
@@ -10,12 +10,10 @@ package gateway
 import (
 	"compress/gzip"
 	"context"
-	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/http/httputil"
 	"strconv"
@@ -32,7 +30,6 @@ import (
 	"github.com/gorilla/sessions"
 	"github.com/patrickmn/go-cache"
 	"github.com/yookoala/gofast"
-	"golang.org/x/net/http2"
 )
 
 var (
@@ -111,8 +108,70 @@ func ReverseHandlerFunc(w http.ResponseWriter, r *http.Request) {
 	}
 	// dynamic
 	srcIP := GetClientIP(r, app)
-	if app.WAFEnabled && !firewall.IsStaticResource(r) {
-		if isCC, ccPolicy, clientID, needLog := firewall.IsCCAttack(r, app.ID, srcIP); isCC == true {
+	// The resolved IP is used by WAF, CC, logs and response inspection
+	r = r.WithContext(context.WithValue(r.Context(), "clientIP", srcIP))
+	// Request ID is used by header rewriting
+	r = r.WithContext(context.WithValue(r.Context(), "requestID", data.GetRandomSaltString()))
+	// X-Auth-User is only set by gateway after OAuth authentication, not by clients
+	r.Header.Del("X-Auth-User")
+	if app.OAuthRequired && data.CFG.MasterNode.OAuth.Enabled {
+		// Authenticated user is used by policy exceptions
+		session, _ := store.Get(r, "janusec-token")
+		if usernameI := session.Values["userid"]; usernameI != nil {
+			r = r.WithContext(context.WithValue(r.Context(), "authUser", usernameI.(string)))
+		}
+	}
+	ipListType := firewall.GetIPListType(app.ID, srcIP)
+	if ipListType == models.IPList_Deny {
+		hitInfo := &models.HitInfo{TypeID: 4, PolicyID: 0, VulnName: "IP Deny List"}
+		GenerateBlockPage(w, hitInfo)
+		return
+	}
+	if ipListType != models.IPList_Allow {
+		if isDenied, countryPolicy := firewall.IsCountryDenied(app.ID, srcIP); isDenied == true {
+			hitInfo := &models.HitInfo{TypeID: 5, PolicyID: countryPolicy.ID, VulnName: "Country Policy"}
+			GenerateBlockPage(w, hitInfo)
+			return
+		}
+	}
+	if app.WAFEnabled && ipListType != models.IPList_Allow && !firewall.IsStaticResource(r) {
+		inspectBody, bodyErr := firewall.PrepareRequestBody(r, app.ID, app.MaxRequestBodySize)
+		isVerifiedCrawler := false
+		// the client with valid pass token is not challenged again
+		if isBot, botPolicy, botName := firewall.IsBotHitPolicy(r, app.ID, srcIP); isBot == true && !(IsChallengeAction(botPolicy.Action) && IsPassTokenValid(r, srcIP)) {
+			targetURL := r.URL.Path
+			if len(r.URL.RawQuery) > 0 {
+				targetURL += "?" + r.URL.RawQuery
+			}
+			hitInfo := &models.HitInfo{TypeID: 6,
+				PolicyID:  botPolicy.ID,
+				VulnName:  "Bot " + botName,
+				Action:    botPolicy.Action,
+				TargetURL: targetURL,
+				BlockTime: time.Now().Unix()}
+			switch botPolicy.Action {
+			case models.Action_Block_100:
+				go firewall.LogBotRequest(r, app.ID, srcIP, botPolicy)
+				GenerateBlockPage(w, hitInfo)
+				return
+			case models.Action_BypassAndLog_200:
+				go firewall.LogBotRequest(r, app.ID, srcIP, botPolicy)
+			case models.Action_CAPTCHA_300:
+				go firewall.LogBotRequest(r, app.ID, srcIP, botPolicy)
+				captchaURL := GenCaptchaURL(r, srcIP, hitInfo)
+				http.Redirect(w, r, captchaURL, http.StatusTemporaryRedirect)
+				return
+			case models.Action_JSChallenge_500, models.Action_PoWChallenge_600:
+				go firewall.LogBotRequest(r, app.ID, srcIP, botPolicy)
+				GenerateChallengePage(w, r, srcIP, botPolicy.Action, targetURL)
+				return
+			default:
+				// models.Action_Pass_400, verified crawlers are not limited by CC and rate limit
+				isVerifiedCrawler = botPolicy.Category == models.Bot_VerifiedCrawler
+			}
+		}
+
+		if isCC, ccPolicy, clientID, needLog := firewall.IsCCAttack(r, app.ID, srcIP); isCC == true && !isVerifiedCrawler && !(IsChallengeAction(ccPolicy.Action) && IsPassTokenValid(r, srcIP)) {
 			targetURL := r.URL.Path
 			if len(r.URL.RawQuery) > 0 {
 				targetURL += "?" + r.URL.RawQuery
@@ -139,14 +198,39 @@ func ReverseHandlerFunc(w http.ResponseWriter, r *http.Request) {
 				if needLog {
 					go firewall.LogCCRequest(r, app.ID, srcIP, ccPolicy)
 				}
-				captchaHitInfo.Store(hitInfo.ClientID, hitInfo)
-				captchaURL := CaptchaEntrance + "?id=" + hitInfo.ClientID
+				captchaURL := GenCaptchaURL(r, srcIP, hitInfo)
 				http.Redirect(w, r, captchaURL, http.StatusTemporaryRedirect)
 				return
+			case models.Action_JSChallenge_500, models.Action_PoWChallenge_600:
+				if needLog {
+					go firewall.LogCCRequest(r, app.ID, srcIP, ccPolicy)
+				}
+				GenerateChallengePage(w, r, srcIP, ccPolicy.Action, targetURL)
+				return
 			}
 		}
 
-		if isHit, policy := firewall.IsRequestHitPolicy(r, app.ID, srcIP); isHit == true {
+		if isLimited, rule, status := firewall.IsRateLimited(r, app.ID, srcIP); status != nil && !isVerifiedCrawler {
+			if rule.Action != models.Action_Pass_400 && status.NeedLog {
+				go firewall.LogRateLimitRequest(r, app.ID, srcIP, rule)
+			}
+			// the rules for monitoring only are invisible to clients
+			if rule.Action != models.Action_Pass_400 && rule.Action != models.Action_BypassAndLog_200 {
+				firewall.SetRateLimitHeaders(w.Header(), status)
+			}
+			if isLimited && rule.Action == models.Action_Block_100 {
+				firewall.SetRetryAfterHeader(w.Header(), status)
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		if bodyErr == firewall.ErrBodyTooLarge && app.OversizeAction == models.Oversize_Reject_413 {
+			http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if isHit, policy := firewall.IsRequestHitPolicy(r, app.ID, srcIP, inspectBody); isHit == true && !(IsChallengeAction(policy.Action) && IsPassTokenValid(r, srcIP)) {
 			switch policy.Action {
 			case models.Action_Block_100:
 				vulnName, _ := firewall.VulnMap.Load(policy.VulnID)
@@ -167,14 +251,33 @@ func ReverseHandlerFunc(w http.ResponseWriter, r *http.Request) {
 					PolicyID: policy.ID, VulnName: "Group Policy Hit",
 					Action: policy.Action, ClientID: clientID,
 					TargetURL: targetURL, BlockTime: time.Now().Unix()}
-				captchaHitInfo.Store(clientID, hitInfo)
-				captchaURL := CaptchaEntrance + "?id=" + clientID
+				captchaURL := GenCaptchaURL(r, srcIP, hitInfo)
 				http.Redirect(w, r, captchaURL, http.StatusTemporaryRedirect)
 				return
+			case models.Action_JSChallenge_500, models.Action_PoWChallenge_600:
+				go firewall.LogGroupHitRequest(r, app.ID, srcIP, policy)
+				targetURL := r.URL.Path
+				if len(r.URL.RawQuery) > 0 {
+					targetURL += "?" + r.URL.RawQuery
+				}
+				GenerateChallengePage(w, r, srcIP, policy.Action, targetURL)
+				return
 			default:
 				// models.Action_Pass_400 do nothing
 			}
 		}
+
+		if isViolated, apiSchema, violation := firewall.IsRequestViolateAPISchema(r, app.ID); isViolated == true {
+			switch apiSchema.Action {
+			case models.Action_Block_100:
+				hitInfo := &models.HitInfo{TypeID: 3, PolicyID: apiSchema.ID, VulnName: "API Schema Violation"}
+				go firewall.LogAPIViolationRequest(r, app.ID, srcIP, apiSchema, violation)
+				GenerateBlockPage(w, hitInfo)
+				return
+			case models.Action_BypassAndLog_200:
+				go firewall.LogAPIViolationRequest(r, app.ID, srcIP, apiSchema, violation)
+			}
+		}
 	}
 
 	// Check OAuth
@@ -247,12 +350,22 @@ func ReverseHandlerFunc(w http.ResponseWriter, r *http.Request) {
 		r.Header.Set("X-Auth-User", usernameI.(string))
 	}
 
-	dest := backend.SelectBackendRoute(app, r)
+	// URL rewrite and redirect
+	if ApplyRewriteRules(w, r, app) {
+		return
+	}
+
+	// The path before route selection is used to select another destination when retrying
+	requestPath := r.URL.Path
+	dest := backend.SelectBackendRoute(w, app, r)
 	if dest == nil {
 		w.Write([]byte("Error: No route found, please check the configuration."))
 		return
 	}
 
+	// Decrypt the cookies encrypted by gateway, after the routing cookies are read
+	DecryptRequestCookies(app, r)
+
 	//fmt.Println("dest", dest, dest.RouteType)
 
 	if dest.RouteType == models.StaticRoute {
@@ -282,36 +395,17 @@ func ReverseHandlerFunc(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Reverse Proxy
-	// var transport http.RoundTripper
-	transport := &http.Transport{
-		TLSHandshakeTimeout:   10 * time.Second,
-		IdleConnTimeout:       30 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			return net.Dial("tcp", dest.Destination)
-		},
-		DialTLS: func(network, addr string) (net.Conn, error) {
-			conn, err := net.Dial("tcp", dest.Destination)
-			if err != nil {
-				return nil, err
-			}
-			cfg := &tls.Config{ServerName: r.Host, NextProtos: []string{"h2", "http/1.1"}}
-			tlsConn := tls.Client(conn, cfg)
-			if err := tlsConn.Handshake(); err != nil {
-				conn.Close()
-				return nil, err
-			}
-			return tlsConn, nil //net.Dial("tcp", dest)
-		},
-	}
-	http2.ConfigureTransport(transport)
+	transport := &upstreamTransport{app: app, r: r, requestPath: requestPath, dest: dest}
 	proxy := &httputil.ReverseProxy{
 		Director: func(req *http.Request) {
 			//req.URL.Scheme = app.InternalScheme
 			//req.URL.Host = r.Host
+			SetForwardedHeaders(req, r)
+			ApplyHeaderPolicies(r, app, models.HeaderDirection_Request, req.Header)
 		},
 		Transport:      transport,
-		ModifyResponse: rewriteResponse}
+		ModifyResponse: rewriteResponse,
+		ErrorHandler:   upstreamErrorHandler}
 	if utils.Debug {
 		dump, err := httputil.DumpRequest(r, true)
 		utils.CheckError("ReverseHandlerFunc DumpRequest", err)
@@ -370,27 +464,6 @@ func GenClientID(r *http.Request, appID int64, srcIP string) string {
 	return clientID
 }
 
-// GetClientIP acquire the client IP address
-func GetClientIP(r *http.Request, app *models.Application) (clientIP string) {
-	switch app.ClientIPMethod {
-	case models.IPMethod_REMOTE_ADDR:
-		clientIP, _, _ = net.SplitHostPort(r.RemoteAddr)
-		return clientIP
-	case models.IPMethod_X_FORWARDED_FOR:
-		xForwardedFor := r.Header.Get("X-Forwarded-For")
-		ips := strings.Split(xForwardedFor, ", ")
-		clientIP = ips[len(ips)-1]
-	case models.IPMethod_X_REAL_IP:
-		clientIP = r.Header.Get("X-Real-IP")
-	case models.IPMethod_REAL_IP:
-		clientIP = r.Header.Get("Real-IP")
-	}
-	if len(clientIP) == 0 {
-		clientIP, _, _ = net.SplitHostPort(r.RemoteAddr)
-	}
-	return clientIP
-}
-
 func OAuthLogout(w http.ResponseWriter, r *http.Request) {
 	session, _ := store.Get(r, "janusec-token")
 	session.Options = &sessions.Options{Path: "/", MaxAge: -1}
@@ -0,0 +1,136 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 01:12:09
+ * @Last Modified: U2, 2026-10-20 01:12:09
+ */
+
+package gateway
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Janusec/janusec/firewall"
+	"github.com/Janusec/janusec/models"
+)
+
+var (
+	headerVariableRegex = regexp.MustCompile(`\$\{([a-z0-9_]+)\}`)
+)
+
+// ApplyHeaderPolicies rewrite the request or response headers by the policies of application in order,
+// r is the request received from client which carries the context
+func ApplyHeaderPolicies(r *http.Request, app *models.Application, direction models.HeaderDirection, header http.Header) {
+	for _, headerPolicy := range app.HeaderPolicies {
+		if headerPolicy.Direction != direction {
+			continue
+		}
+		switch headerPolicy.Action {
+		case models.HeaderAction_Add:
+			if len(header.Get(headerPolicy.Name)) == 0 {
+				header.Set(headerPolicy.Name, expandHeaderVariables(r, app, headerPolicy.Value))
+			}
+		case models.HeaderAction_Override:
+			header.Set(headerPolicy.Name, expandHeaderVariables(r, app, headerPolicy.Value))
+		case models.HeaderAction_Remove:
+			header.Del(headerPolicy.Name)
+		case models.HeaderAction_Append:
+			header.Add(headerPolicy.Name, expandHeaderVariables(r, app, headerPolicy.Value))
+		}
+	}
+}
+
+// expandHeaderVariables replace the variables such as ${client_ip}, unknown variables are kept
+func expandHeaderVariables(r *http.Request, app *models.Application, value string) string {
+	if !strings.Contains(value, "${") {
+		return value
+	}
+	return headerVariableRegex.ReplaceAllStringFunc(value, func(variable string) string {
+		switch variable[2 : len(variable)-1] {
+		case "client_ip":
+			return GetClientIP(r, app)
+		case "scheme":
+			return getRequestScheme(r)
+		case "host":
+			return r.Host
+		case "method":
+			return r.Method
+		case "uri":
+			return r.URL.RequestURI()
+		case "app_id":
+			return strconv.FormatInt(app.ID, 10)
+		case "app_name":
+			return app.Name
+		case "request_id":
+			requestID, _ := r.Context().Value("requestID").(string)
+			return requestID
+		case "auth_user":
+			authUser, _ := r.Context().Value("authUser").(string)
+			return authUser
+		case "tls_version":
+			if r.TLS != nil {
+				return getTLSVersionName(r.TLS.Version)
+			}
+			return ""
+		case "tls_cipher":
+			if r.TLS != nil {
+				return tls.CipherSuiteName(r.TLS.CipherSuite)
+			}
+			return ""
+		case "tls_sni":
+			if r.TLS != nil {
+				return r.TLS.ServerName
+			}
+			return ""
+		case "ja3":
+			ja3, _ := firewall.GetTLSFingerprint(r)
+			return ja3
+		case "ja4":
+			_, ja4 := firewall.GetTLSFingerprint(r)
+			return ja4
+		}
+		return variable
+	})
+}
+
+func getRequestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+func getTLSVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLSv1.0"
+	case tls.VersionTLS11:
+		return "TLSv1.1"
+	case tls.VersionTLS12:
+		return "TLSv1.2"
+	case tls.VersionTLS13:
+		return "TLSv1.3"
+	}
+	return ""
+}
+
+// SetForwardedHeaders set X-Forwarded-Proto and X-Forwarded-Host of the request forwarded to backend,
+// the values sent by trusted proxies are kept
+func SetForwardedHeaders(outReq *http.Request, r *http.Request) {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+	isFromTrustedProxy := len(trustedProxyNets) > 0 && IsTrustedProxy(remoteIP)
+	if !isFromTrustedProxy || len(outReq.Header.Get("X-Forwarded-Proto")) == 0 {
+		outReq.Header.Set("X-Forwarded-Proto", getRequestScheme(r))
+	}
+	if !isFromTrustedProxy || len(outReq.Header.Get("X-Forwarded-Host")) == 0 {
+		outReq.Header.Set("X-Forwarded-Host", r.Host)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-19 21:15:08
+ * @Last Modified: U2, 2026-10-19 21:15:08
+ */
+
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyProtocolV2Signature is the first 12 bytes of PROXY protocol v2 header
+var proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+var errProxyProtocolHeader = errors.New("invalid PROXY protocol header")
+
+const proxyProtocolHeaderTimeout = 5 * time.Second
+
+// ProxyProtocolListener accept PROXY protocol v1/v2 header from trusted proxies,
+// the source address of header is used as RemoteAddr
+type ProxyProtocolListener struct {
+	net.Listener
+}
+
+// NewProxyProtocolListener ...
+func NewProxyProtocolListener(listener net.Listener) net.Listener {
+	return &ProxyProtocolListener{Listener: listener}
+}
+
+// Accept return the connection without reading, the header is parsed in the serving goroutine
+func (listener *ProxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := listener.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyProtocolConn{Conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+type proxyProtocolConn struct {
+	net.Conn
+	reader     *bufio.Reader
+	once       sync.Once
+	remoteAddr net.Addr
+	err        error
+}
+
+func (conn *proxyProtocolConn) Read(b []byte) (int, error) {
+	conn.once.Do(conn.readHeader)
+	if conn.err != nil {
+		return 0, conn.err
+	}
+	return conn.reader.Read(b)
+}
+
+func (conn *proxyProtocolConn) RemoteAddr() net.Addr {
+	conn.once.Do(conn.readHeader)
+	if conn.remoteAddr != nil {
+		return conn.remoteAddr
+	}
+	return conn.Conn.RemoteAddr()
+}
+
+// readHeader parse the header only if the peer is a trusted proxy, otherwise the data is served as is
+func (conn *proxyProtocolConn) readHeader() {
+	peerIP, _, _ := net.SplitHostPort(conn.Conn.RemoteAddr().String())
+	if !IsTrustedProxy(peerIP) {
+		return
+	}
+	conn.Conn.SetReadDeadline(time.Now().Add(proxyProtocolHeaderTimeout))
+	defer conn.Conn.SetReadDeadline(time.Time{})
+	peek, err := conn.reader.Peek(len(proxyProtocolV2Signature))
+	if err != nil && len(peek) < 6 {
+		return
+	}
+	switch {
+	case bytes.Equal(peek, proxyProtocolV2Signature):
+		conn.remoteAddr, conn.err = readProxyProtocolV2(conn.reader)
+	case bytes.HasPrefix(peek, []byte("PROXY ")):
+		conn.remoteAddr, conn.err = readProxyProtocolV1(conn.reader)
+	}
+}
+
+// readProxyProtocolV1 parse the text header like PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n
+func readProxyProtocolV1(reader *bufio.Reader) (net.Addr, error) {
+	// the max length of v1 header is 107 bytes
+	line := make([]byte, 0, 107)
+	for len(line) < 107 {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errProxyProtocolHeader
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errProxyProtocolHeader
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.Atoi(fields[4])
+	if ip == nil || err != nil {
+		return nil, errProxyProtocolHeader
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// readProxyProtocolV2 parse the binary header
+func readProxyProtocolV2(reader *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	version, command := header[12]>>4, header[12]&0x0F
+	family := header[13] >> 4
+	length := int(binary.BigEndian.Uint16(header[14:16]))
+	if version != 2 {
+		return nil, errProxyProtocolHeader
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		return nil, err
+	}
+	if command == 0 {
+		// LOCAL command, such as health check of proxy
+		return nil, nil
+	}
+	switch family {
+	case 1:
+		// AF_INET: src addr, dst addr, src port, dst port
+		if length < 12 {
+			return nil, errProxyProtocolHeader
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:4]), Port: int(binary.BigEndian.Uint16(payload[8:10]))}, nil
+	case 2:
+		// AF_INET6
+		if length < 36 {
+			return nil, errProxyProtocolHeader
+		}
+		return &net.TCPAddr{IP: net.IP(payload[0:16]), Port: int(binary.BigEndian.Uint16(payload[32:34]))}, nil
+	}
+	// AF_UNIX or unspecified
+	return nil, nil
+}
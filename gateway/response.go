@@ -53,7 +53,7 @@ func rewriteResponse(resp *http.Response) (err error) {
 
 	if app.WAFEnabled {
 		srcIP := GetClientIP(r, app)
-		if isHit, policy := firewall.IsResponseHitPolicy(resp, app.ID); isHit {
+		if isHit, policy := firewall.IsResponseHitPolicy(resp, app.ID, app.MaxResponseBodySize); isHit && !(IsChallengeAction(policy.Action) && IsPassTokenValid(r, srcIP)) {
 			switch policy.Action {
 			case models.Action_Block_100:
 				vulnName, _ := firewall.VulnMap.Load(policy.VulnID)
@@ -78,23 +78,50 @@ func rewriteResponse(resp *http.Response) (err error) {
 					PolicyID: policy.ID, VulnName: "Group Policy Hit",
 					Action: policy.Action, ClientID: clientID,
 					TargetURL: targetURL, BlockTime: time.Now().Unix()}
-				captchaHitInfo.Store(clientID, hitInfo)
-				captchaURL := CaptchaEntrance + "?id=" + clientID
+				captchaURL := GenCaptchaURL(r, srcIP, hitInfo)
 				resp.Header.Set("Location", captchaURL)
+				resp.Header.Del("Content-Length")
+				resp.Body = ioutil.NopCloser(bytes.NewReader([]byte{}))
 				resp.ContentLength = 0
+				resp.StatusCode = http.StatusFound
 				//http.Redirect(w, r, captchaURL, http.StatusTemporaryRedirect)
 				return
+			case models.Action_JSChallenge_500, models.Action_PoWChallenge_600:
+				go firewall.LogGroupHitRequest(r, app.ID, srcIP, policy)
+				targetURL := r.URL.Path
+				if len(r.URL.RawQuery) > 0 {
+					targetURL += "?" + r.URL.RawQuery
+				}
+				challengeContent := GenerateChallengeContent(r, srcIP, policy.Action, targetURL)
+				resp.Body = ioutil.NopCloser(bytes.NewReader(challengeContent))
+				resp.ContentLength = int64(len(challengeContent))
+				resp.Header.Del("Content-Length")
+				resp.Header.Set("Content-Type", "text/html; charset=utf-8")
+				resp.Header.Del("Content-Encoding")
+				resp.StatusCode = 403
+				return nil
 			default:
 				// models.Action_Pass_400 do nothing
 			}
 		}
 	}
 
+	// Masking sensitive data of response body
+	if maskedCounts := firewall.MaskResponseBody(resp, app.ID, app.MaxResponseBodySize); len(maskedCounts) > 0 {
+		go firewall.LogMaskingResponse(r, app.ID, GetClientIP(r, app), maskedCounts)
+	}
+
 	// HSTS
 	if (app.HSTSEnabled == true) && (resp.Request.TLS != nil) {
-		resp.Header.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		resp.Header.Set("Strict-Transport-Security", backend.GetHSTSValue(app))
 	}
 
+	// Security response headers and header rewriting
+	ApplyHeaderPolicies(r, app, models.HeaderDirection_Response, resp.Header)
+
+	// Cookie flags and encryption
+	HardenResponseCookies(app, resp)
+
 	//body, err := httputil.DumpResponse(resp, true)
 	//fmt.Println("Dump Response:")
 	//fmt.Println(string(body))
@@ -0,0 +1,105 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 02:03:31
+ * @Last Modified: U2, 2026-10-20 02:03:31
+ */
+
+package gateway
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+)
+
+// ApplyRewriteRules rewrite the URL of request in order, or redirect the client,
+// return true if the client is redirected. Each rule is applied at most once in a single pass,
+// so internal rewrites can not loop, redirect loops are rejected when the rules are saved.
+func ApplyRewriteRules(w http.ResponseWriter, r *http.Request, app *models.Application) bool {
+	for _, rule := range app.RewriteRules {
+		if rule.Regex == nil || !isRewriteConditionMatched(r, rule) {
+			continue
+		}
+		match := rule.Regex.FindStringSubmatchIndex(r.URL.Path)
+		if match == nil {
+			continue
+		}
+		target := string(rule.Regex.ExpandString(nil, rule.Replacement, r.URL.Path, match))
+		targetPath, targetQuery := target, ""
+		if index := strings.Index(target, "?"); index >= 0 {
+			targetPath, targetQuery = target[:index], target[index+1:]
+		}
+		if len(r.URL.RawQuery) > 0 {
+			if len(targetQuery) > 0 {
+				targetQuery += "&"
+			}
+			targetQuery += r.URL.RawQuery
+		}
+		if rule.Action == models.Rewrite_Internal {
+			r.URL.Path = targetPath
+			r.URL.RawPath = ""
+			r.URL.RawQuery = targetQuery
+		} else {
+			location := getRedirectLocation(targetPath, targetQuery)
+			if isRedirectLoop(r, location) {
+				utils.DebugPrintln("ApplyRewriteRules skip redirect loop", r.Host, location)
+				continue
+			}
+			http.Redirect(w, r, location, int(rule.Action))
+			return true
+		}
+		if rule.IsLast {
+			break
+		}
+	}
+	return false
+}
+
+func isRewriteConditionMatched(r *http.Request, rule *models.RewriteRule) bool {
+	if rule.CondHostRegex != nil && !rule.CondHostRegex.MatchString(r.Host) {
+		return false
+	}
+	if len(rule.CondHeaderName) > 0 {
+		value, ok := r.Header[rule.CondHeaderName]
+		if !ok {
+			return false
+		}
+		if rule.CondHeaderRegex != nil && !rule.CondHeaderRegex.MatchString(strings.Join(value, ",")) {
+			return false
+		}
+	}
+	if rule.CondQueryRegex != nil && !rule.CondQueryRegex.MatchString(r.URL.RawQuery) {
+		return false
+	}
+	return true
+}
+
+// getRedirectLocation escape the path, the absolute URL is used as it is
+func getRedirectLocation(targetPath string, targetQuery string) string {
+	location := targetPath
+	if !strings.Contains(targetPath, "://") {
+		location = (&url.URL{Path: targetPath}).EscapedPath()
+	}
+	if len(targetQuery) > 0 {
+		location += "?" + targetQuery
+	}
+	return location
+}
+
+// isRedirectLoop return true if the location is the same as the current URL
+func isRedirectLoop(r *http.Request, location string) bool {
+	locationURL, err := url.Parse(location)
+	if err != nil {
+		return true
+	}
+	if locationURL.IsAbs() {
+		if locationURL.Scheme != getRequestScheme(r) || !strings.EqualFold(locationURL.Host, r.Host) {
+			return false
+		}
+	}
+	return locationURL.Path == r.URL.Path && locationURL.RawQuery == r.URL.RawQuery
+}
@@ -32,6 +32,23 @@ func GenerateBlockConcent(hitInfo *models.HitInfo) []byte {
 	return buf.Bytes()
 }
 
+// UpstreamErrorInfo is used by the error page of upstream failures
+type UpstreamErrorInfo struct {
+	StatusCode int
+	StatusText string
+	RequestID  string
+}
+
+// GenerateUpstreamErrorPage render the 502 or 504 page when the destination failed
+func GenerateUpstreamErrorPage(w http.ResponseWriter, statusCode int, requestID string) {
+	tmpl := template.New("Janusec")
+	tmpl, _ = tmpl.Parse(upstreamErrorHTML)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	tmpl.Execute(w, &UpstreamErrorInfo{StatusCode: statusCode, StatusText: http.StatusText(statusCode), RequestID: requestID})
+}
+
 var blockHTML = `<!DOCTYPE html>
 <html>
 <head>
@@ -71,3 +88,46 @@ Reason: {{.VulnName}}, Policy ID: {{.PolicyID}}
 </body>
 </html>
 `
+
+var upstreamErrorHTML = `<!DOCTYPE html>
+<html>
+<head>
+<title>{{.StatusCode}} {{.StatusText}}</title>
+</head>
+<style>
+body {
+    font-family: Arial, Helvetica, sans-serif;
+    text-align: center;
+}
+
+.text-logo {
+    display: block;
+    width: 260px;
+    font-size: 48px;
+    background-color: #F9F9F9;
+    color: #f5f5f5;
+    text-decoration: none;
+    text-shadow: 2px 2px 4px #000000;
+    box-shadow: 2px 2px 3px #D5D5D5;
+    padding: 15px;
+    margin: auto;
+}
+
+.block_div {
+    padding: 10px;
+    width: 70%;
+    margin: auto;
+}
+
+</style>
+<body>
+<div class="block_div">
+<div class="text-logo">JANUSEC</div>
+<h1>{{.StatusCode}} {{.StatusText}}</h1>
+<p>The website is temporarily unable to respond, please try again later.</p>
+<hr>
+{{if .RequestID}}Request ID: {{.RequestID}}{{end}}
+</div>
+</body>
+</html>
+`
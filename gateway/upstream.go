@@ -0,0 +1,160 @@
+/*
+ * @Copyright Reserved By Janusec (https://www.janusec.com/).
+ * @Author: U2
+ * @Date: 2026-10-20 04:31:06
+ * @Last Modified: U2, 2026-10-20 04:31:06
+ */
+
+package gateway
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Janusec/janusec/backend"
+	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
+	"golang.org/x/net/http2"
+)
+
+const (
+	// defaultConnectTimeout is used when the connect timeout of application is 0
+	defaultConnectTimeout = 10 * time.Second
+)
+
+// upstreamTransport forward the request to the selected destination, the idempotent request
+// is retried on another destination of the same route if the destination failed
+type upstreamTransport struct {
+	app *models.Application
+	// r is the request received from client, requestPath is the path before route selection
+	r           *http.Request
+	requestPath string
+	dest        *models.Destination
+}
+
+// RoundTrip implements http.RoundTripper, the result is reported to the circuit of destination
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dest := t.dest
+	var triedDests []*models.Destination
+	for {
+		resp, err := getDestinationTransport(t.app, dest).RoundTrip(req)
+		if err == nil {
+			if isGatewayErrorStatus(resp.StatusCode) {
+				backend.MarkDestinationFailed(dest)
+			} else {
+				backend.MarkDestinationAvailable(dest)
+			}
+			return resp, nil
+		}
+		if req.Context().Err() == context.Canceled {
+			// canceled by client, not a failure of destination
+			return nil, err
+		}
+		backend.MarkDestinationFailed(dest)
+		triedDests = append(triedDests, dest)
+		if int64(len(triedDests)) > t.app.RetryTimes || !isIdempotentRequest(req) {
+			return nil, err
+		}
+		routeReq := t.r.Clone(t.r.Context())
+		routeReq.URL.Path = t.requestPath
+		nextDest := backend.SelectRetryDestination(t.app, routeReq, triedDests)
+		if nextDest == nil {
+			return nil, err
+		}
+		utils.DebugPrintln("upstreamTransport retry", req.Host, dest.Destination, "->", nextDest.Destination, err)
+		req = req.Clone(req.Context())
+		req.URL.Path = routeReq.URL.Path
+		dest = nextDest
+	}
+}
+
+// destTransports sync.Map, map[destination and timeouts]*http.Transport, the idle connections
+// are reused by the requests to the same destination
+var destTransports sync.Map
+
+// getDestinationTransport return the cached transport of destination with the timeouts of application,
+// a new one is created when the destination address or the timeouts are changed
+func getDestinationTransport(app *models.Application, dest *models.Destination) *http.Transport {
+	connectTimeout := defaultConnectTimeout
+	if app.ConnectTimeout > 0 {
+		connectTimeout = time.Duration(app.ConnectTimeout) * time.Second
+	}
+	responseTimeout := time.Duration(app.ResponseTimeout) * time.Second
+	key := dest.Destination + "|" + connectTimeout.String() + "|" + responseTimeout.String()
+	if transport, ok := destTransports.Load(key); ok {
+		return transport.(*http.Transport)
+	}
+	transport, _ := destTransports.LoadOrStore(key, newDestinationTransport(dest.Destination, connectTimeout, responseTimeout))
+	return transport.(*http.Transport)
+}
+
+// newDestinationTransport return the transport which connects to the destination address with the timeouts,
+// addr of the dial functions is the host of request, it is the TLS server name
+func newDestinationTransport(destination string, connectTimeout time.Duration, responseTimeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{Timeout: connectTimeout}
+	transport := &http.Transport{
+		TLSHandshakeTimeout:   10 * time.Second,
+		IdleConnTimeout:       30 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		ResponseHeaderTimeout: responseTimeout,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", destination)
+		},
+		DialTLS: func(network, addr string) (net.Conn, error) {
+			serverName, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				serverName = addr
+			}
+			conn, err := dialer.Dial("tcp", destination)
+			if err != nil {
+				return nil, err
+			}
+			cfg := &tls.Config{ServerName: serverName, NextProtos: []string{"h2", "http/1.1"}}
+			tlsConn := tls.Client(conn, cfg)
+			conn.SetDeadline(time.Now().Add(connectTimeout))
+			if err := tlsConn.Handshake(); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			conn.SetDeadline(time.Time{})
+			return tlsConn, nil
+		},
+	}
+	http2.ConfigureTransport(transport)
+	return transport
+}
+
+// isIdempotentRequest return true if the request can be sent again, only the request without body is retried
+func isIdempotentRequest(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody {
+		return false
+	}
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+func isGatewayErrorStatus(statusCode int) bool {
+	return statusCode == http.StatusBadGateway || statusCode == http.StatusServiceUnavailable || statusCode == http.StatusGatewayTimeout
+}
+
+// upstreamErrorHandler is the ErrorHandler of reverse proxy, render 504 for timeout and 502 for other errors
+func upstreamErrorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	if r.Context().Err() == context.Canceled {
+		// the client is gone
+		return
+	}
+	utils.DebugPrintln("upstreamErrorHandler", r.Host, r.URL.Path, err)
+	statusCode := http.StatusBadGateway
+	if netErr, ok := err.(net.Error); (ok && netErr.Timeout()) || err == context.DeadlineExceeded {
+		statusCode = http.StatusGatewayTimeout
+	}
+	requestID, _ := r.Context().Value("requestID").(string)
+	GenerateUpstreamErrorPage(w, statusCode, requestID)
+}
@@ -61,6 +61,11 @@ func main() {
 	settings.LoadSettings()
 
 	tlsconfig := &tls.Config{
+		// GetConfigForClient is called for every ClientHello, GetCertificate is skipped on TLS 1.3 resumption
+		GetConfigForClient: func(helloInfo *tls.ClientHelloInfo) (*tls.Config, error) {
+			gateway.SaveClientHello(helloInfo)
+			return nil, nil
+		},
 		GetCertificate: func(helloInfo *tls.ClientHelloInfo) (*tls.Certificate, error) {
 			cert, err := backend.GetCertificateByDomain(helloInfo.ServerName)
 			return cert, err
@@ -129,17 +134,27 @@ func main() {
 	gateMux.HandleFunc("/captcha/confirm", gateway.ShowCaptchaHandlerFunc)
 	gateMux.HandleFunc("/captcha/validate", gateway.ValidateCaptchaHandlerFunc)
 	gateMux.Handle("/captcha/png/", gateway.ShowCaptchaImage())
+	gateMux.HandleFunc(gateway.ChallengeEntrance, gateway.ChallengeVerifyHandlerFunc)
 
 	// Reverse Proxy
 	gateMux.HandleFunc("/", gateway.ReverseHandlerFunc)
 	ctxGateMux := AddContextHandler(gateMux)
+	gateway.InitTrustedProxies()
 	go func() {
 		listen, _ := net.Listen("tcp", ":80")
+		if data.CFG.Proxy.ProxyProtocol {
+			listen = gateway.NewProxyProtocolListener(listen)
+		}
 		utils.CheckError("Listen 80 Failed", http.Serve(listen, ctxGateMux))
 	}()
 	//go func() {
-	listen, _ := tls.Listen("tcp", ":443", tlsconfig)
-	utils.CheckError("Listen 443 Failed", http.Serve(listen, ctxGateMux))
+	listen, err := net.Listen("tcp", ":443")
+	utils.CheckError("Listen 443 Failed", err)
+	if data.CFG.Proxy.ProxyProtocol {
+		listen = gateway.NewProxyProtocolListener(listen)
+	}
+	server := &http.Server{Handler: ctxGateMux, ConnContext: gateway.ClientHelloConnContext}
+	utils.CheckError("Listen 443 Failed", server.Serve(tls.NewListener(listen, tlsconfig)))
 	//}()
 }
 
@@ -10,7 +10,8 @@ package models
 import (
 	"crypto/tls"
 	"database/sql"
-	"sync"
+	"regexp"
+	"sync/atomic"
 )
 
 type Application struct {
@@ -19,9 +20,9 @@ type Application struct {
 	InternalScheme string         `json:"internal_scheme"` // http, https
 	Destinations   []*Destination `json:"destinations"`
 
-	// Route: map[string][]*Destination
-	// {"/abc/": ["192.168.1.1:8800", "192.168.1.2:8800"], ".php": [...], "/": [...]}
-	Route sync.Map `json:"-"`
+	// RouteTable holds []*RouteEntry compiled from destinations by LoadRoute, ordered by priority and specificity,
+	// the destinations with the same route are in one RouteEntry, it is replaced as a whole while requests are routed
+	RouteTable atomic.Value `json:"-"`
 
 	Domains        []*Domain `json:"domains"`
 	RedirectHTTPS  bool      `json:"redirect_https"`
@@ -32,6 +33,42 @@ type Application struct {
 	OAuthRequired  bool      `json:"oauth_required"`
 	SessionSeconds int64     `json:"session_seconds"`
 	Owner          string    `json:"owner"`
+
+	// MaxRequestBodySize and MaxResponseBodySize in bytes, 0 for default
+	MaxRequestBodySize  int64          `json:"max_req_body_size"`
+	MaxResponseBodySize int64          `json:"max_resp_body_size"`
+	OversizeAction      OversizeAction `json:"oversize_action"`
+
+	// HSTSMaxAge in seconds, 0 for default 31536000
+	HSTSMaxAge  int64 `json:"hsts_max_age"`
+	HSTSPreload bool  `json:"hsts_preload"`
+
+	// StickyMode keeps the client on the same destination, for stateful backends
+	StickyMode StickyMode `json:"sticky_mode"`
+
+	// ConnectTimeout and ResponseTimeout in seconds, 0 for default, the response timeout is
+	// the time waiting for the response headers after the request is sent
+	ConnectTimeout  int64 `json:"connect_timeout"`
+	ResponseTimeout int64 `json:"response_timeout"`
+
+	// RetryTimes is the max times of retrying the idempotent request on another destination
+	RetryTimes int64 `json:"retry_times"`
+
+	// HeaderPolicies are applied to the request and response headers in order
+	HeaderPolicies []*HeaderPolicy `json:"header_policies"`
+
+	// CookiePolicies enforce the flags of backend cookies, and encrypt the selected cookies
+	CookiePolicies []*CookiePolicy `json:"cookie_policies"`
+
+	// RewriteRules rewrite or redirect the request URL in order
+	RewriteRules []*RewriteRule `json:"rewrite_rules"`
+
+	// TrafficSplits distribute the traffic of routes to backend groups
+	TrafficSplits []*TrafficSplit `json:"traffic_splits"`
+
+	// SplitTable holds the []*TrafficSplit read by requests, the slice and its splits are not modified
+	// after published, an update stores a new slice
+	SplitTable atomic.Value `json:"-"`
 }
 
 type DBApplication struct {
@@ -46,8 +83,31 @@ type DBApplication struct {
 	OAuthRequired  bool     `json:"oauth_required"`
 	SessionSeconds int64    `json:"session_seconds"`
 	Owner          string   `json:"owner"`
+
+	MaxRequestBodySize  int64          `json:"max_req_body_size"`
+	MaxResponseBodySize int64          `json:"max_resp_body_size"`
+	OversizeAction      OversizeAction `json:"oversize_action"`
+
+	HSTSMaxAge  int64      `json:"hsts_max_age"`
+	HSTSPreload bool       `json:"hsts_preload"`
+	StickyMode  StickyMode `json:"sticky_mode"`
+
+	ConnectTimeout  int64 `json:"connect_timeout"`
+	ResponseTimeout int64 `json:"response_timeout"`
+	RetryTimes      int64 `json:"retry_times"`
 }
 
+// StickyMode is the session affinity of application
+type StickyMode int64
+
+const (
+	Sticky_None StickyMode = 0
+	// Sticky_Cookie pins the client by the cookie issued by gateway
+	Sticky_Cookie StickyMode = 1
+	// Sticky_IPHash pins the client by the hash of client IP
+	Sticky_IPHash StickyMode = 2
+)
+
 type DomainRelation struct {
 	App      *Application
 	Cert     *CertItem
@@ -107,6 +167,167 @@ type Destination struct {
 
 	AppID  int64 `json:"app_id"`
 	NodeID int64 `json:"node_id"`
+
+	// MatchMode of RequestRoute, prefix or regular expression
+	MatchMode RouteMatchMode `json:"match_mode"`
+	// Priority, the higher is matched first
+	Priority int64 `json:"priority"`
+	// Host, Methods and Header are optional conditions, Host supports wildcard such as *.janusec.com,
+	// Methods such as GET,POST, HeaderValue is a regular expression
+	Host        string `json:"host"`
+	Methods     string `json:"methods"`
+	HeaderName  string `json:"header_name"`
+	HeaderValue string `json:"header_value"`
+
+	// Group is the name of backend group such as blue, green or canary, used by traffic split
+	Group string `json:"group"`
+}
+
+// RouteMatchMode is the way of matching RequestRoute
+type RouteMatchMode int64
+
+const (
+	// RouteMatch_Prefix matches the longest path prefix such as /api/v2/, file extension such as .php, or /
+	RouteMatch_Prefix RouteMatchMode = 0
+	// RouteMatch_Regex matches the path by regular expression such as ^/api/v\d+/
+	RouteMatch_Regex RouteMatchMode = 1
+)
+
+// TrafficSplit distribute the traffic of route to backend groups by percentage
+type TrafficSplit struct {
+	ID    int64 `json:"id"`
+	AppID int64 `json:"app_id"`
+	// RequestRoute is the same as the RequestRoute of destinations, empty for all routes
+	RequestRoute string `json:"request_route"`
+	// Weights such as blue:90,green:10
+	Weights string `json:"weights"`
+	// Sticky keeps the client in the assigned group by cookie
+	Sticky bool `json:"sticky"`
+	// OverrideHeader and OverrideCookie are used by testers to choose the group, such as X-Canary: green
+	OverrideHeader string `json:"override_header"`
+	OverrideCookie string `json:"override_cookie"`
+	IsEnabled      bool   `json:"is_enabled"`
+	UpdateTime     int64  `json:"update_time"`
+
+	GroupWeights []*GroupWeight `json:"-"`
+}
+
+// GroupWeight is the percentage of backend group
+type GroupWeight struct {
+	Group  string
+	Weight int64
+}
+
+// RouteEntry is the compiled route of destinations
+type RouteEntry struct {
+	MatchMode    RouteMatchMode
+	Priority     int64
+	RequestRoute string
+	Regex        *regexp.Regexp
+	Host         string
+	Methods      []string
+	HeaderName   string
+	HeaderRegex  *regexp.Regexp
+	Destinations []*Destination
+}
+
+// HeaderAction is the operation of header policy
+type HeaderAction int64
+
+const (
+	// HeaderAction_Add adds the header only if it is absent
+	HeaderAction_Add HeaderAction = 1
+	// HeaderAction_Override sets the header and replaces the existing value
+	HeaderAction_Override HeaderAction = 2
+	// HeaderAction_Remove deletes the header, such as Server
+	HeaderAction_Remove HeaderAction = 3
+	// HeaderAction_Append appends a value to the existing values
+	HeaderAction_Append HeaderAction = 4
+)
+
+// HeaderDirection is the request forwarded to backend or the response sent to client
+type HeaderDirection int64
+
+const (
+	HeaderDirection_Request  HeaderDirection = 1
+	HeaderDirection_Response HeaderDirection = 2
+)
+
+// HeaderPolicy is used to rewrite the request or response header of application
+type HeaderPolicy struct {
+	ID        int64           `json:"id"`
+	AppID     int64           `json:"app_id"`
+	Direction HeaderDirection `json:"direction"`
+	Action    HeaderAction    `json:"action"`
+	Name      string          `json:"name"`
+	// Value is not used for HeaderAction_Remove, variables such as ${client_ip} are supported
+	Value       string `json:"value"`
+	Description string `json:"description"`
+}
+
+// CookieSameSite is the SameSite attribute enforced by cookie policy
+type CookieSameSite int64
+
+const (
+	// SameSite_Keep keeps the SameSite attribute of backend
+	SameSite_Keep   CookieSameSite = 0
+	SameSite_Lax    CookieSameSite = 1
+	SameSite_Strict CookieSameSite = 2
+	// SameSite_None also requires Secure
+	SameSite_None CookieSameSite = 3
+)
+
+// CookiePolicy is used to harden the cookies set by backend
+type CookiePolicy struct {
+	ID    int64 `json:"id"`
+	AppID int64 `json:"app_id"`
+	// Name of cookie, * for all cookies without a policy of its own name
+	Name     string         `json:"name"`
+	Secure   bool           `json:"secure"`
+	HttpOnly bool           `json:"http_only"`
+	SameSite CookieSameSite `json:"same_site"`
+	// Encrypt the value by gateway, the backend always see the plaintext, not available for *
+	Encrypt     bool   `json:"encrypt"`
+	Description string `json:"description"`
+}
+
+// RewriteAction is internal rewrite or the status code of redirect
+type RewriteAction int64
+
+const (
+	// Rewrite_Internal changes the URL forwarded to backend, the client is not aware of it
+	Rewrite_Internal RewriteAction = 1
+	Redirect_301     RewriteAction = 301
+	Redirect_302     RewriteAction = 302
+	Redirect_307     RewriteAction = 307
+	Redirect_308     RewriteAction = 308
+)
+
+// RewriteRule rewrite or redirect the URL path matched by Pattern
+type RewriteRule struct {
+	ID    int64 `json:"id"`
+	AppID int64 `json:"app_id"`
+	// Pattern is the regular expression of URL path, such as ^/old/(.*)$
+	Pattern string `json:"pattern"`
+	// Replacement is the new URL with capture groups, such as /new/$1?from=old,
+	// the query of original URL is appended, absolute URL is only used by redirect
+	Replacement string        `json:"replacement"`
+	Action      RewriteAction `json:"action"`
+
+	// Conditions are regular expressions, empty for any
+	CondHost        string `json:"cond_host"`
+	CondHeaderName  string `json:"cond_header_name"`
+	CondHeaderValue string `json:"cond_header_value"`
+	CondQuery       string `json:"cond_query"`
+
+	// IsLast stops processing the following rules after this rule is applied
+	IsLast      bool   `json:"is_last"`
+	Description string `json:"description"`
+
+	Regex           *regexp.Regexp `json:"-"`
+	CondHostRegex   *regexp.Regexp `json:"-"`
+	CondHeaderRegex *regexp.Regexp `json:"-"`
+	CondQueryRegex  *regexp.Regexp `json:"-"`
 }
 
 type CertItem struct {
@@ -182,4 +403,15 @@ const (
 	IPMethod_X_FORWARDED_FOR IPMethod = 1 << 1
 	IPMethod_X_REAL_IP       IPMethod = 1 << 2
 	IPMethod_REAL_IP         IPMethod = 1 << 3
+	IPMethod_FORWARDED       IPMethod = 1 << 4 // RFC 7239 Forwarded header
+)
+
+// OversizeAction is used when the body exceeds the max inspected size
+type OversizeAction int64
+
+const (
+	// Oversize_Pass pass the body to backend without inspection
+	Oversize_Pass OversizeAction = 1
+	// Oversize_Reject_413 reject the request with 413 Request Entity Too Large
+	Oversize_Reject_413 OversizeAction = 1 << 1
 )
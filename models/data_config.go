@@ -11,6 +11,11 @@ type Config struct {
 	NodeRole   string           `json:"node_role"`
 	MasterNode MasterNodeConfig `json:"master_node"`
 	SlaveNode  SlaveNodeConfig  `json:"slave_node"`
+	ClamAV     ClamAVConfig     `json:"clamav"`
+	CCStore    CCStoreConfig    `json:"cc_store"`
+	GeoIP      GeoIPConfig      `json:"geoip"`
+	Proxy      ProxyConfig      `json:"proxy"`
+	Bot        BotConfig        `json:"bot"`
 }
 
 type OAuthConfig struct {
@@ -54,6 +59,52 @@ type EncryptedConfig struct {
 	NodeRole   string           `json:"node_role"`
 	MasterNode MasterNodeConfig `json:"master_node"`
 	SlaveNode  SlaveNodeConfig  `json:"slave_node"`
+	ClamAV     ClamAVConfig     `json:"clamav"`
+	CCStore    CCStoreConfig    `json:"cc_store"`
+	GeoIP      GeoIPConfig      `json:"geoip"`
+	Proxy      ProxyConfig      `json:"proxy"`
+	Bot        BotConfig        `json:"bot"`
+}
+
+// ClamAVConfig is the local clamd used for upload scanning on each node
+type ClamAVConfig struct {
+	Enabled bool `json:"enabled"`
+	// Address like 127.0.0.1:3310 or unix:/var/run/clamav/clamd.ctl
+	Address        string `json:"address"`
+	TimeoutSeconds int64  `json:"timeout_seconds"`
+}
+
+// CCStoreConfig is the shared counter (Redis protocol) for CC statistics among nodes
+type CCStoreConfig struct {
+	Enabled bool `json:"enabled"`
+	// Address like 127.0.0.1:6379
+	Address             string `json:"address"`
+	Password            string `json:"password"`
+	DB                  int64  `json:"db"`
+	TimeoutMilliseconds int64  `json:"timeout_milliseconds"`
+}
+
+// GeoIPConfig is the offline MaxMind DB (mmdb) used for country lookup
+type GeoIPConfig struct {
+	Enabled bool `json:"enabled"`
+	// Database like ./GeoLite2-Country.mmdb
+	Database string `json:"database"`
+}
+
+// ProxyConfig is the trusted proxies (load balancers or CDN) in front of gateway
+type ProxyConfig struct {
+	// TrustedCIDRs like 10.0.0.0/8, headers of client IP are trusted only from them if not empty
+	TrustedCIDRs []string `json:"trusted_cidrs"`
+	// ProxyProtocol accept PROXY protocol v1/v2 header from trusted proxies on gateway listeners
+	ProxyProtocol bool `json:"proxy_protocol"`
+}
+
+// BotConfig is used by bot management
+type BotConfig struct {
+	// DNSServer like 127.0.0.1:53 is used to verify search engine crawlers, empty for system resolver
+	DNSServer string `json:"dns_server"`
+	// DNSTimeoutMilliseconds is the timeout of reverse and forward lookup
+	DNSTimeoutMilliseconds int64 `json:"dns_timeout_milliseconds"`
 }
 
 type WxworkConfig struct {
@@ -18,18 +18,23 @@ const (
 	Action_BypassAndLog_200 PolicyAction = 200
 	Action_CAPTCHA_300      PolicyAction = 300
 	Action_Pass_400         PolicyAction = 400
+	// Action_JSChallenge_500 transparent JavaScript challenge
+	Action_JSChallenge_500 PolicyAction = 500
+	// Action_PoWChallenge_600 proof-of-work challenge, difficulty is set by challenge_pow_difficulty
+	Action_PoWChallenge_600 PolicyAction = 600
 )
 
 type CCPolicy struct {
-	AppID           int64         `json:"app_id"` // Global Policy set app_id=0
-	IntervalSeconds time.Duration `json:"interval_seconds"`
-	MaxCount        int64         `json:"max_count"`
-	BlockSeconds    time.Duration `json:"block_seconds"`
-	Action          PolicyAction  `json:"action"`
-	StatByURL       bool          `json:"stat_by_url"`
-	StatByUserAgent bool          `json:"stat_by_ua"`
-	StatByCookie    bool          `json:"stat_by_cookie"`
-	IsEnabled       bool          `json:"is_enabled"`
+	AppID                int64         `json:"app_id"` // Global Policy set app_id=0
+	IntervalSeconds      time.Duration `json:"interval_seconds"`
+	MaxCount             int64         `json:"max_count"`
+	BlockSeconds         time.Duration `json:"block_seconds"`
+	Action               PolicyAction  `json:"action"`
+	StatByURL            bool          `json:"stat_by_url"`
+	StatByUserAgent      bool          `json:"stat_by_ua"`
+	StatByCookie         bool          `json:"stat_by_cookie"`
+	StatByTLSFingerprint bool          `json:"stat_by_tls_fingerprint"` // count by JA4 fingerprint of TLS ClientHello
+	IsEnabled            bool          `json:"is_enabled"`
 }
 
 type ChkPoint int64
@@ -40,10 +45,12 @@ const (
 	ChkPointMethod              ChkPoint = 1 << 2
 	ChkPointURLPath             ChkPoint = 1 << 3
 	ChkPointURLQuery            ChkPoint = 1 << 4
+	ChkPointUploadFileType      ChkPoint = 1 << 5 // MIME type sniffed from content of uploaded file
 	ChkPointValueLength         ChkPoint = 1 << 6
 	ChkPointGetPostKey          ChkPoint = 1 << 7
 	ChkPointGetPostValue        ChkPoint = 1 << 8
 	ChkPointUploadFileExt       ChkPoint = 1 << 9
+	ChkPointUploadTypeMismatch  ChkPoint = 1 << 10 // declared:detected type, only if they are mismatched
 	ChkPointCookieKey           ChkPoint = 1 << 11
 	ChkPointCookieValue         ChkPoint = 1 << 12
 	ChkPointUserAgent           ChkPoint = 1 << 13
@@ -51,11 +58,23 @@ const (
 	ChkPointHeaderKey           ChkPoint = 1 << 15
 	ChkPointHeaderValue         ChkPoint = 1 << 16
 	ChkPointProto               ChkPoint = 1 << 17
+	ChkPointXMLDoctype          ChkPoint = 1 << 18 // DOCTYPE declaration of XML/SOAP body
+	ChkPointXMLEntityExpansion  ChkPoint = 1 << 19 // max expanded size of internal entities
+	ChkPointGraphQLDepth        ChkPoint = 1 << 20 // max nesting depth of GraphQL fields
+	ChkPointGraphQLComplexity   ChkPoint = 1 << 21 // count of GraphQL fields, fragments and lists expanded
+	ChkPointGraphQLField        ChkPoint = 1 << 22 // name of each GraphQL field, such as __schema
+	ChkPointJSONDepth           ChkPoint = 1 << 23 // max nesting depth of JSON body
+	ChkPointJSONElementCount    ChkPoint = 1 << 24 // count of all objects, arrays and values in JSON body
 	ChkPointResponseStatusCode  ChkPoint = 1 << 25
 	ChkPointResponseHeaderKey   ChkPoint = 1 << 26
 	ChkPointResponseHeaderValue ChkPoint = 1 << 27
 	ChkPointResponseBodyLength  ChkPoint = 1 << 28
 	ChkPointResponseBody        ChkPoint = 1 << 29
+	ChkPointJSONSize            ChkPoint = 1 << 30 // size of JSON body in bytes
+	ChkPointUploadContent       ChkPoint = 1 << 31 // content of uploaded file and archive entries
+	ChkPointUploadVirus         ChkPoint = 1 << 32 // virus name reported by clamd
+	ChkPointCountry             ChkPoint = 1 << 33 // ISO country code of client IP by GeoIP
+	ChkPointTLSFingerprint      ChkPoint = 1 << 34 // JA3 and JA4 fingerprints of TLS ClientHello
 )
 
 type GroupPolicy struct {
@@ -93,13 +112,17 @@ const (
 	OperationEqualsStringCaseInSensitive Operation = 1 << 1
 	OperationGreaterThanInteger          Operation = 1 << 2
 	OperationEqualsInteger               Operation = 1 << 3
+	// OperationSQLInjection uses lexical SQL injection detector, regex_policy is ignored
+	OperationSQLInjection Operation = 1 << 4
+	// OperationXSS uses lexical cross-site scripting detector, regex_policy is ignored
+	OperationXSS Operation = 1 << 5
 )
 
 type CheckItem struct {
 	ID            int64        `json:"id"`
 	CheckPoint    ChkPoint     `json:"check_point"`
 	Operation     Operation    `json:"operation"`
-	KeyName       string       `json:"key_name"`
+	KeyName       string       `json:"key_name"` // header name, or JSON path like $.items[*].id or key name of JSON body
 	RegexPolicy   string       `json:"regex_policy"`
 	GroupPolicyID int64        `json:"group_policy_id"`
 	GroupPolicy   *GroupPolicy `json:"-"`
@@ -147,6 +170,8 @@ type CCLog struct {
 	RawRequest  string       `json:"raw_request"`
 	Action      PolicyAction `json:"action"`
 	AppID       int64        `json:"app_id"`
+	JA3         string       `json:"ja3"`
+	JA4         string       `json:"ja4"`
 }
 
 type SimpleCCLog struct {
@@ -176,6 +201,8 @@ type GroupHitLog struct {
 	PolicyID    int64        `json:"policy_id"`
 	VulnID      int64        `json:"vuln_id"`
 	AppID       int64        `json:"app_id"`
+	JA3         string       `json:"ja3"`
+	JA4         string       `json:"ja4"`
 }
 
 type SimpleGroupHitLog struct {
@@ -201,3 +228,243 @@ type VulnStat struct {
 	VulnID int64 `json:"vuln_id"`
 	Count  int64 `json:"count"`
 }
+
+// PolicyException skip designated group policy or vulnerability type within the scope
+type PolicyException struct {
+	ID          int64  `json:"id"`
+	Description string `json:"description"`
+	// AppID 0 for all applications
+	AppID int64 `json:"app_id"`
+	// PolicyID 0 for any group policy
+	PolicyID int64 `json:"policy_id"`
+	// VulnID 0 for any vulnerability type
+	VulnID int64 `json:"vuln_id"`
+	// URLPath is a regex pattern, empty for any path
+	URLPath string `json:"url_path"`
+	// ParamName is the name of GET/POST/JSON parameter, cookie or header, empty for any
+	ParamName string `json:"param_name"`
+	// Username is the authenticated user, empty for any user
+	Username   string `json:"username"`
+	IsEnabled  bool   `json:"is_enabled"`
+	UserID     int64  `json:"user_id"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+// APISchema is the OpenAPI 3 document (JSON format) of an application, for positive security model
+type APISchema struct {
+	ID          int64  `json:"id"`
+	AppID       int64  `json:"app_id"`
+	Description string `json:"description"`
+	Content     string `json:"content"`
+	// Action for non-conforming requests, Action_Block_100 or Action_BypassAndLog_200
+	Action     PolicyAction `json:"action"`
+	IsEnabled  bool         `json:"is_enabled"`
+	UserID     int64        `json:"user_id"`
+	UpdateTime int64        `json:"update_time"`
+}
+
+type APIViolationLog struct {
+	ID          int64        `json:"id"`
+	RequestTime int64        `json:"request_time"`
+	ClientIP    string       `json:"client_ip"`
+	Host        string       `json:"host"`
+	Method      string       `json:"method"`
+	UrlPath     string       `json:"url_path"`
+	UrlQuery    string       `json:"url_query"`
+	ContentType string       `json:"content_type"`
+	UserAgent   string       `json:"user_agent"`
+	Cookies     string       `json:"cookies"`
+	RawRequest  string       `json:"raw_request"`
+	Action      PolicyAction `json:"action"`
+	SchemaID    int64        `json:"schema_id"`
+	Violation   string       `json:"violation"`
+	AppID       int64        `json:"app_id"`
+}
+
+type SimpleAPIViolationLog struct {
+	ID          int64        `json:"id"`
+	RequestTime int64        `json:"request_time"`
+	ClientIP    string       `json:"client_ip"`
+	Host        string       `json:"host"`
+	Method      string       `json:"method"`
+	UrlPath     string       `json:"url_path"`
+	Action      PolicyAction `json:"action"`
+	SchemaID    int64        `json:"schema_id"`
+	Violation   string       `json:"violation"`
+	AppID       int64        `json:"app_id"`
+}
+
+type RateLimitAlgorithm int64
+
+const (
+	RateLimit_TokenBucket   RateLimitAlgorithm = 1
+	RateLimit_SlidingWindow RateLimitAlgorithm = 1 << 1
+)
+
+// RateLimitKey is the sum of request attributes composing the rate limit key
+type RateLimitKey int64
+
+const (
+	RateLimitKey_IP     RateLimitKey = 1
+	RateLimitKey_CIDR   RateLimitKey = 1 << 1 // network of client IP with CIDRPrefix
+	RateLimitKey_Header RateLimitKey = 1 << 2 // header designated by KeyName
+	RateLimitKey_Cookie RateLimitKey = 1 << 3 // cookie designated by KeyName
+	RateLimitKey_User   RateLimitKey = 1 << 4 // authenticated user
+	RateLimitKey_APIKey RateLimitKey = 1 << 5 // X-API-Key, Authorization or api_key parameter
+	RateLimitKey_Path   RateLimitKey = 1 << 6 // URL path
+)
+
+// RateLimitRule limit the request rate of each key within the scope of AppID and URLPath
+type RateLimitRule struct {
+	ID          int64  `json:"id"`
+	Description string `json:"description"`
+	// AppID 0 for all applications
+	AppID int64 `json:"app_id"`
+	// URLPath is a regex pattern, empty for any path
+	URLPath string       `json:"url_path"`
+	KeyType RateLimitKey `json:"key_type"`
+	KeyName string       `json:"key_name"`
+	// CIDRPrefix is the prefix length of IPv4 network, IPv6 network uses /64
+	CIDRPrefix int64              `json:"cidr_prefix"`
+	Algorithm  RateLimitAlgorithm `json:"algorithm"`
+	// Limit requests within PeriodSeconds
+	Limit         int64 `json:"limit"`
+	PeriodSeconds int64 `json:"period_seconds"`
+	// Burst is the capacity of token bucket, 0 means equal to Limit
+	Burst int64 `json:"burst"`
+	// Action Action_Block_100 (429), Action_BypassAndLog_200 or Action_Pass_400 (headers only)
+	Action     PolicyAction `json:"action"`
+	IsEnabled  bool         `json:"is_enabled"`
+	UserID     int64        `json:"user_id"`
+	UpdateTime int64        `json:"update_time"`
+}
+
+type IPListType int64
+
+const (
+	IPList_Allow IPListType = 1
+	IPList_Deny  IPListType = 1 << 1
+)
+
+// IPListEntry is an IP or CIDR in allow list or deny list
+type IPListEntry struct {
+	ID int64 `json:"id"`
+	// AppID 0 for all applications
+	AppID int64 `json:"app_id"`
+	// IPNet like 192.168.1.10, 10.0.0.0/8 or 2001:db8::/32
+	IPNet    string     `json:"ip_net"`
+	ListType IPListType `json:"list_type"`
+	Comment  string     `json:"comment"`
+	// ExpireTime is unix time, 0 for never
+	ExpireTime int64 `json:"expire_time"`
+	UserID     int64 `json:"user_id"`
+	UpdateTime int64 `json:"update_time"`
+}
+
+// IPListImport is the request of bulk import, IPs separated by lines, commas or spaces
+type IPListImport struct {
+	AppID      int64      `json:"app_id"`
+	ListType   IPListType `json:"list_type"`
+	Comment    string     `json:"comment"`
+	ExpireTime int64      `json:"expire_time"`
+	IPs        string     `json:"ips"`
+}
+
+// CountryPolicy allow or deny the countries (ISO codes separated by commas) by GeoIP
+type CountryPolicy struct {
+	ID int64 `json:"id"`
+	// AppID 0 for all applications
+	AppID int64 `json:"app_id"`
+	// ListType IPList_Allow denies other countries, IPList_Deny denies the countries
+	ListType   IPListType `json:"list_type"`
+	Countries  string     `json:"countries"`
+	IsEnabled  bool       `json:"is_enabled"`
+	UserID     int64      `json:"user_id"`
+	UpdateTime int64      `json:"update_time"`
+}
+
+// BotCategory is the classification of bot
+type BotCategory int64
+
+const (
+	// Bot_None is not classified as bot
+	Bot_None BotCategory = 0
+	// Bot_VerifiedCrawler is search engine crawler verified by forward-confirmed reverse DNS
+	Bot_VerifiedCrawler BotCategory = 1
+	// Bot_FakeCrawler claims to be search engine crawler but failed the verification
+	Bot_FakeCrawler BotCategory = 2
+	// Bot_Automation is headless browser, automation framework or HTTP library
+	Bot_Automation BotCategory = 3
+	// Bot_Scanner is known vulnerability scanner
+	Bot_Scanner BotCategory = 4
+)
+
+// BotPolicy is the action for a bot category
+type BotPolicy struct {
+	ID int64 `json:"id"`
+	// AppID 0 for all applications, the policy of application takes precedence over the global one
+	AppID    int64       `json:"app_id"`
+	Category BotCategory `json:"category"`
+	// Action Block, BypassAndLog, CAPTCHA, JSChallenge, PoWChallenge or Pass,
+	// Pass for Bot_VerifiedCrawler also skips CC and rate limit check
+	Action     PolicyAction `json:"action"`
+	IsEnabled  bool         `json:"is_enabled"`
+	UserID     int64        `json:"user_id"`
+	UpdateTime int64        `json:"update_time"`
+}
+
+// MaskType is the type of sensitive data in response body
+type MaskType int64
+
+const (
+	Mask_Phone    MaskType = 1
+	Mask_IDNumber MaskType = 2
+	Mask_BankCard MaskType = 3
+	Mask_Email    MaskType = 4
+	Mask_Custom   MaskType = 5
+)
+
+// MaskingRule rewrite the sensitive data of response body, such as 13800138000 to 138****8000
+type MaskingRule struct {
+	ID int64 `json:"id"`
+	// AppID 0 for all applications
+	AppID int64 `json:"app_id"`
+	// URLPath is a regex pattern, empty for any path
+	URLPath  string   `json:"url_path"`
+	MaskType MaskType `json:"mask_type"`
+	// Pattern is the regular expression of Mask_Custom
+	Pattern string `json:"pattern"`
+	// KeepPrefix and KeepSuffix are the count of characters not masked, only the local part of email is masked
+	KeepPrefix int64  `json:"keep_prefix"`
+	KeepSuffix int64  `json:"keep_suffix"`
+	MaskChar   string `json:"mask_char"`
+	// Replacement like $1****, used instead of mask characters if not empty
+	Replacement string `json:"replacement"`
+	IsEnabled   bool   `json:"is_enabled"`
+	UserID      int64  `json:"user_id"`
+	UpdateTime  int64  `json:"update_time"`
+}
+
+// MaskingLog record the count of masked data in a response
+type MaskingLog struct {
+	ID          int64  `json:"id"`
+	RequestTime int64  `json:"request_time"`
+	ClientIP    string `json:"client_ip"`
+	Host        string `json:"host"`
+	Method      string `json:"method"`
+	UrlPath     string `json:"url_path"`
+	MaskedCount int64  `json:"masked_count"`
+	// Detail is the masked count of each rule, such as 1:3,4:1
+	Detail string `json:"detail"`
+	AppID  int64  `json:"app_id"`
+}
+
+type IPStat struct {
+	ClientIP string `json:"client_ip"`
+	Count    int64  `json:"count"`
+}
+
+type CountryStat struct {
+	Country string `json:"country"`
+	Count   int64  `json:"count"`
+}
@@ -8,7 +8,7 @@
 package models
 
 type HitInfo struct {
-	TypeID    int64 // 1: CCPolicy  2:GroupPolicy
+	TypeID    int64 // 1: CCPolicy  2:GroupPolicy  3:APISchema  4:IPList  5:CountryPolicy  6:BotPolicy
 	PolicyID  int64
 	VulnName  string
 	Action    PolicyAction
@@ -17,6 +17,20 @@ type HitInfo struct {
 	BlockTime int64
 }
 
+// ClientHello is the TLS ClientHello of the connection, attached to request context as "clientHello"
+type ClientHello struct {
+	ServerName        string
+	CipherSuites      []uint16
+	Extensions        []uint16
+	SupportedCurves   []uint16
+	SupportedPoints   []uint8
+	SignatureSchemes  []uint16
+	SupportedProtos   []string
+	SupportedVersions []uint16
+	JA3               string
+	JA4               string
+}
+
 type CaptchaContext struct {
 	CaptchaId string
 	ClientID  string
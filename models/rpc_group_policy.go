@@ -11,3 +11,43 @@ type RPCSetGroupPolicy struct {
 	Action string       `json:"action"`
 	Object *GroupPolicy `json:"object"`
 }
+
+type RPCSetPolicyException struct {
+	Action string           `json:"action"`
+	Object *PolicyException `json:"object"`
+}
+
+type RPCSetAPISchema struct {
+	Action string     `json:"action"`
+	Object *APISchema `json:"object"`
+}
+
+type RPCSetRateLimitRule struct {
+	Action string         `json:"action"`
+	Object *RateLimitRule `json:"object"`
+}
+
+type RPCSetIPListEntry struct {
+	Action string       `json:"action"`
+	Object *IPListEntry `json:"object"`
+}
+
+type RPCImportIPList struct {
+	Action string        `json:"action"`
+	Object *IPListImport `json:"object"`
+}
+
+type RPCSetCountryPolicy struct {
+	Action string         `json:"action"`
+	Object *CountryPolicy `json:"object"`
+}
+
+type RPCSetBotPolicy struct {
+	Action string     `json:"action"`
+	Object *BotPolicy `json:"object"`
+}
+
+type RPCSetMaskingRule struct {
+	Action string       `json:"action"`
+	Object *MaskingRule `json:"object"`
+}
@@ -36,6 +36,22 @@ type RPCCCLogRequest struct {
 	Object   *CCLog `json:"object"`
 }
 
+type RPCAPIViolationLogRequest struct {
+	Action   string           `json:"action"`
+	ObjectID int64            `json:"id"`
+	NodeID   int64            `json:"node_id"`
+	AuthKey  string           `json:"auth_key"`
+	Object   *APIViolationLog `json:"object"`
+}
+
+type RPCMaskingLogRequest struct {
+	Action   string      `json:"action"`
+	ObjectID int64       `json:"id"`
+	NodeID   int64       `json:"node_id"`
+	AuthKey  string      `json:"auth_key"`
+	Object   *MaskingLog `json:"object"`
+}
+
 type RPCCertItems struct {
 	Error  *string     `json:"err"`
 	Object []*CertItem `json:"object"`
@@ -80,3 +96,38 @@ type RPCTOTP struct {
 	Error  *string `json:"err"`
 	Object *TOTP   `json:"object"`
 }
+
+type RPCPolicyExceptions struct {
+	Error  *string            `json:"err"`
+	Object []*PolicyException `json:"object"`
+}
+
+type RPCAPISchemas struct {
+	Error  *string      `json:"err"`
+	Object []*APISchema `json:"object"`
+}
+
+type RPCRateLimitRules struct {
+	Error  *string          `json:"err"`
+	Object []*RateLimitRule `json:"object"`
+}
+
+type RPCIPListEntries struct {
+	Error  *string        `json:"err"`
+	Object []*IPListEntry `json:"object"`
+}
+
+type RPCCountryPolicies struct {
+	Error  *string          `json:"err"`
+	Object []*CountryPolicy `json:"object"`
+}
+
+type RPCBotPolicies struct {
+	Error  *string      `json:"err"`
+	Object []*BotPolicy `json:"object"`
+}
+
+type RPCMaskingRules struct {
+	Error  *string        `json:"err"`
+	Object []*MaskingRule `json:"object"`
+}
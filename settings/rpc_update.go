@@ -45,6 +45,10 @@ func UpdateTimeTick() {
 					updateTicker.Stop()
 					updateTicker = time.NewTicker(data.Sync_Seconds * time.Second)
 				}
+			case "Challenge_PoW_Difficulty":
+				setChallengePoWDifficulty(int64(settingItem.Value.(float64)))
+			case "Challenge_Pass_Seconds":
+				setChallengePassSeconds(int64(settingItem.Value.(float64)))
 			}
 		}
 	}
@@ -8,10 +8,12 @@
 package settings
 
 import (
+	"errors"
 	"time"
 
 	"github.com/Janusec/janusec/data"
 	"github.com/Janusec/janusec/models"
+	"github.com/Janusec/janusec/utils"
 )
 
 func InitDefaultSettings() {
@@ -29,6 +31,12 @@ func InitDefaultSettings() {
 	if data.DAL.ExistsSetting("Log_Expire_Seconds") == false {
 		data.DAL.SaveIntSetting("Log_Expire_Seconds", 7*86400)
 	}
+	if data.DAL.ExistsSetting("Challenge_PoW_Difficulty") == false {
+		data.DAL.SaveIntSetting("Challenge_PoW_Difficulty", 18)
+	}
+	if data.DAL.ExistsSetting("Challenge_Pass_Seconds") == false {
+		data.DAL.SaveIntSetting("Challenge_Pass_Seconds", 1800)
+	}
 }
 
 func LoadSettings() {
@@ -37,9 +45,15 @@ func LoadSettings() {
 		data.Firewall_Last_Modified, _ = data.DAL.SelectIntSetting("Firewall_Last_Modified")
 		Sync_Seconds_int64, _ := data.DAL.SelectIntSetting("Sync_Seconds")
 		data.Sync_Seconds = time.Duration(Sync_Seconds_int64)
+		powDifficulty, _ := data.DAL.SelectIntSetting("Challenge_PoW_Difficulty")
+		setChallengePoWDifficulty(powDifficulty)
+		passSeconds, _ := data.DAL.SelectIntSetting("Challenge_Pass_Seconds")
+		setChallengePassSeconds(passSeconds)
 		data.Settings = append(data.Settings, &models.Setting{Name: "Backend_Last_Modified", Value: data.Backend_Last_Modified})
 		data.Settings = append(data.Settings, &models.Setting{Name: "Firewall_Last_Modified", Value: data.Firewall_Last_Modified})
 		data.Settings = append(data.Settings, &models.Setting{Name: "Sync_Seconds", Value: data.Sync_Seconds})
+		data.Settings = append(data.Settings, &models.Setting{Name: "Challenge_PoW_Difficulty", Value: data.Challenge_PoW_Difficulty})
+		data.Settings = append(data.Settings, &models.Setting{Name: "Challenge_Pass_Seconds", Value: data.Challenge_Pass_Seconds})
 	} else {
 		// Load OAuth Config
 		data.CFG.MasterNode.OAuth = *(data.RPCGetOAuthConfig())
@@ -53,6 +67,10 @@ func LoadSettings() {
 				data.Firewall_Last_Modified = int64(setting_item.Value.(float64))
 			case "Sync_Seconds":
 				data.Sync_Seconds = time.Duration(setting_item.Value.(float64))
+			case "Challenge_PoW_Difficulty":
+				setChallengePoWDifficulty(int64(setting_item.Value.(float64)))
+			case "Challenge_Pass_Seconds":
+				setChallengePassSeconds(int64(setting_item.Value.(float64)))
 			}
 		}
 		go UpdateTimeTick()
@@ -62,3 +80,64 @@ func LoadSettings() {
 func GetSettings() ([]*models.Setting, error) {
 	return data.Settings, nil
 }
+
+// UpdateSettings update the challenge settings, only super administrators are allowed
+func UpdateSettings(param map[string]interface{}, authUser *models.AuthUser) ([]*models.Setting, error) {
+	if authUser == nil || !authUser.IsSuperAdmin {
+		return nil, errors.New("Only super administrators can update settings")
+	}
+	settingsMap, ok := param["object"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("UpdateSettings parse body null")
+	}
+	// Check all values before saving any of them
+	values := map[string]int64{}
+	for name, valueI := range settingsMap {
+		value, ok := valueI.(float64)
+		if !ok {
+			return nil, errors.New(name + " should be a number")
+		}
+		values[name] = int64(value)
+		var err error
+		switch name {
+		case "Challenge_PoW_Difficulty":
+			err = data.CheckChallengePoWDifficulty(values[name])
+		case "Challenge_Pass_Seconds":
+			err = data.CheckChallengePassSeconds(values[name])
+		default:
+			err = errors.New(name + " cannot be updated")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if powDifficulty, ok := values["Challenge_PoW_Difficulty"]; ok {
+		if err := data.UpdateChallengePoWDifficulty(powDifficulty); err != nil {
+			return nil, err
+		}
+	}
+	if passSeconds, ok := values["Challenge_Pass_Seconds"]; ok {
+		if err := data.UpdateChallengePassSeconds(passSeconds); err != nil {
+			return nil, err
+		}
+	}
+	return data.Settings, nil
+}
+
+// setChallengePoWDifficulty apply the difficulty loaded from database or master, invalid value is ignored
+func setChallengePoWDifficulty(difficulty int64) {
+	err := data.CheckChallengePoWDifficulty(difficulty)
+	utils.CheckError("setChallengePoWDifficulty", err)
+	if err == nil {
+		data.Challenge_PoW_Difficulty = difficulty
+	}
+}
+
+// setChallengePassSeconds apply the validity of pass token loaded from database or master, invalid value is ignored
+func setChallengePassSeconds(seconds int64) {
+	err := data.CheckChallengePassSeconds(seconds)
+	utils.CheckError("setChallengePassSeconds", err)
+	if err == nil {
+		data.Challenge_Pass_Seconds = seconds
+	}
+}